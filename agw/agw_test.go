@@ -0,0 +1,442 @@
+package agw
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aprs_agent/audio"
+	"aprs_agent/ax25"
+	"aprs_agent/modem"
+)
+
+// sentFrame 记录发送的帧
+type sentFrame struct {
+	channel int
+	data    []byte
+}
+
+// fakeModem 记录发送的帧
+type fakeModem struct {
+	mu       sync.Mutex
+	channels int
+	handlers []func(modem.Frame)
+	sent     []sentFrame
+	params   audio.TxParams
+}
+
+func (m *fakeModem) AddFrameHandler(handler func(modem.Frame)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+func (m *fakeModem) Transmit(channel int, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentFrame{channel: channel, data: data})
+	return nil
+}
+
+func (m *fakeModem) ChannelCount() int {
+	return m.channels
+}
+
+func (m *fakeModem) ChannelDeviceName(channel int) string {
+	return []string{"USB Audio", "Line In"}[channel]
+}
+
+func (m *fakeModem) GetTxParams(channel int) (audio.TxParams, error) {
+	return m.params, nil
+}
+
+func (m *fakeModem) GetTxQueueSize(channel int) int {
+	return 3
+}
+
+func (m *fakeModem) receive(frame modem.Frame) {
+	m.mu.Lock()
+	handlers := m.handlers
+	m.mu.Unlock()
+	for _, h := range handlers {
+		h(frame)
+	}
+}
+
+func (m *fakeModem) sentFrames() []sentFrame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]sentFrame(nil), m.sent...)
+}
+
+// waitFor 轮询等待条件满足
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// testClient AGW测试客户端
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (c *testClient) send(h Header, data []byte) {
+	c.t.Helper()
+	if _, err := c.conn.Write(Encode(h, data)); err != nil {
+		c.t.Fatalf("写入失败: %v", err)
+	}
+}
+
+func (c *testClient) read() (Header, []byte) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	h, data, err := ReadFrame(c.conn)
+	if err != nil {
+		c.t.Fatalf("读取失败: %v", err)
+	}
+	return h, data
+}
+
+func TestEncodeReadFrame(t *testing.T) {
+	h := Header{Port: 1, Kind: 'M', PID: 0xF0, CallFrom: "N0CALL-9", CallTo: "APRS"}
+	encoded := Encode(h, []byte("hello"))
+	if len(encoded) != HeaderLen+5 {
+		t.Fatalf("长度错误: %d", len(encoded))
+	}
+
+	got, data, err := ReadFrame(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	h.DataLen = 5
+	if got != h || string(data) != "hello" {
+		t.Errorf("解码错误: %+v %q", got, data)
+	}
+
+	// 数据长度过大
+	bad := Encode(Header{Kind: 'K'}, nil)
+	binary.LittleEndian.PutUint32(bad[28:], MaxDataLen+1)
+	if _, _, err := ReadFrame(bytes.NewReader(bad)); err == nil {
+		t.Error("期望数据长度错误")
+	}
+}
+
+func TestServer(t *testing.T) {
+	m := &fakeModem{channels: 2, params: audio.TxParams{
+		TxDelay:     300 * time.Millisecond,
+		TxTail:      50 * time.Millisecond,
+		Persistence: 63,
+		SlotTime:    100 * time.Millisecond,
+	}}
+	server := NewServer(m, "127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+	c := &testClient{t: t, conn: conn}
+
+	// 版本号
+	c.send(Header{Kind: 'R'}, nil)
+	h, data := c.read()
+	if h.Kind != 'R' || len(data) != 8 || binary.LittleEndian.Uint32(data) != versionMajor {
+		t.Errorf("'R' 应答错误: %+v % X", h, data)
+	}
+
+	// 端口信息
+	c.send(Header{Kind: 'G'}, nil)
+	_, data = c.read()
+	if got := strings.TrimRight(string(data), "\x00"); got != "2;Port1 USB Audio;Port2 Line In;" {
+		t.Errorf("'G' 应答错误: %q", got)
+	}
+
+	// 端口参数
+	c.send(Header{Port: 1, Kind: 'g'}, nil)
+	_, data = c.read()
+	if want := []byte{0, 0xFF, 30, 5, 63, 10, 7, 0, 0, 0, 0, 0}; !bytes.Equal(data, want) {
+		t.Errorf("'g' 应答错误: % X", data)
+	}
+
+	// 注册呼号
+	c.send(Header{Kind: 'X', CallFrom: "N0CALL"}, nil)
+	if h, data := c.read(); h.Kind != 'X' || h.CallFrom != "N0CALL" || !bytes.Equal(data, []byte{1}) {
+		t.Errorf("'X' 应答错误: %+v % X", h, data)
+	}
+	c.send(Header{Kind: 'X', CallFrom: "bad call!"}, nil)
+	if _, data := c.read(); !bytes.Equal(data, []byte{0}) {
+		t.Errorf("无效呼号应注册失败: % X", data)
+	}
+
+	// 发送队列
+	c.send(Header{Port: 0, Kind: 'y'}, nil)
+	if h, data := c.read(); h.Kind != 'y' || binary.LittleEndian.Uint32(data) != 3 {
+		t.Errorf("'y' 应答错误: %+v % X", h, data)
+	}
+
+	// 开启监听和原始帧
+	c.send(Header{Kind: 'm'}, nil)
+	c.send(Header{Kind: 'k'}, nil)
+
+	// UI帧
+	c.send(Header{Port: 1, Kind: 'M', PID: 0xF0, CallFrom: "N0CALL", CallTo: "APRS"}, []byte(">test"))
+	h, data = c.read()
+	if h.Kind != 'T' || h.Port != 1 || !strings.HasPrefix(string(data), " 2:Fm N0CALL To APRS <UI pid=F0 Len=5 >[") {
+		t.Errorf("'T' 监听帧错误: %+v %q", h, data)
+	}
+
+	// 带中继路径的UI帧
+	via := make([]byte, 1+2*callLen)
+	via[0] = 2
+	putCall(via[1:], "WIDE1-1")
+	putCall(via[1+callLen:], "WIDE2-1")
+	c.send(Header{Port: 0, Kind: 'V', PID: 0xF0, CallFrom: "N0CALL", CallTo: "APRS"}, append(via, ">via"...))
+	c.read()
+
+	// 原始帧
+	raw, _ := ax25.NewUIFrame(ax25.MustParseAddress("N0CALL-1"), ax25.MustParseAddress("APRS"), nil, []byte("raw")).Encode()
+	c.send(Header{Port: 0, Kind: 'K'}, append([]byte{0}, raw...))
+	c.read()
+
+	waitFor(t, "发送3帧", func() bool { return len(m.sentFrames()) == 3 })
+	sent := m.sentFrames()
+	wantTNC2 := []string{"N0CALL>APRS:>test", "N0CALL>APRS,WIDE1-1,WIDE2-1:>via", "N0CALL-1>APRS:raw"}
+	wantChannel := []int{1, 0, 0}
+	for i, s := range sent {
+		frame, err := ax25.Decode(s.data)
+		if err != nil {
+			t.Fatalf("帧 %d 解码失败: %v", i, err)
+		}
+		if frame.String() != wantTNC2[i] || s.channel != wantChannel[i] {
+			t.Errorf("帧 %d = %q 通道 %d, want %q 通道 %d", i, frame.String(), s.channel, wantTNC2[i], wantChannel[i])
+		}
+	}
+
+	// 接收的帧：先收到原始帧，再收到监听帧
+	rx, _ := ax25.NewUIFrame(ax25.MustParseAddress("BG0ABC-7"), ax25.MustParseAddress("APDR16"),
+		[]ax25.Address{{Call: "WIDE1", H: true}}, []byte("!3000.00N/12000.00E>")).Encode()
+	m.receive(modem.Frame{Channel: 0, Data: rx})
+
+	h, data = c.read()
+	if h.Kind != 'K' || !bytes.Equal(data, append([]byte{0}, rx...)) {
+		t.Errorf("'K' 原始帧错误: %+v % X", h, data)
+	}
+	h, data = c.read()
+	if h.Kind != 'U' || h.CallFrom != "BG0ABC-7" || h.CallTo != "APDR16" ||
+		!strings.HasPrefix(string(data), " 1:Fm BG0ABC-7 To APDR16 Via WIDE1* <UI pid=F0 Len=20 >[") ||
+		!strings.HasSuffix(string(data), "]\r!3000.00N/12000.00E>\r\x00") {
+		t.Errorf("'U' 监听帧错误: %+v %q", h, data)
+	}
+
+	// 收听列表
+	c.send(Header{Port: 0, Kind: 'H'}, nil)
+	if h, data := c.read(); h.Kind != 'H' || h.CallFrom != "BG0ABC-7" || !strings.HasPrefix(string(data), "BG0ABC-7") {
+		t.Errorf("'H' 应答错误: %+v %q", h, data)
+	}
+	c.send(Header{Port: 1, Kind: 'H'}, nil)
+	if h, data := c.read(); h.Kind != 'H' || len(data) != 0 {
+		t.Errorf("空收听列表应答错误: %+v %q", h, data)
+	}
+
+	// 接收字节统计
+	c.send(Header{Port: 0, Kind: 'g'}, nil)
+	if _, data := c.read(); binary.LittleEndian.Uint32(data[8:]) != uint32(len(rx)) {
+		t.Errorf("接收字节数错误: % X", data)
+	}
+
+	// 断开的客户端被移除，注册的呼号被释放
+	conn.Close()
+	waitFor(t, "客户端断开", func() bool { return server.ClientCount() == 0 })
+	server.mu.Lock()
+	registered := len(server.registered)
+	server.mu.Unlock()
+	if registered != 0 {
+		t.Errorf("断开后应释放注册的呼号: %d", registered)
+	}
+}
+
+// startServer 启动服务并连接一个客户端
+func startServer(t *testing.T, m *fakeModem) (*Server, *testClient) {
+	t.Helper()
+	server := NewServer(m, "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return server, &testClient{t: t, conn: conn}
+}
+
+// waitSent 等待发送第n帧并解码
+func waitSent(t *testing.T, m *fakeModem, n int) *ax25.Frame {
+	t.Helper()
+	waitFor(t, "发送帧", func() bool { return len(m.sentFrames()) >= n })
+	frame, err := ax25.Decode(m.sentFrames()[n-1].data)
+	if err != nil {
+		t.Fatalf("帧 %d 解码失败: %v", n, err)
+	}
+	return frame
+}
+
+// receiveLink 模拟收到对方发来的连接模式帧
+func receiveLink(t *testing.T, m *fakeModem, src, dest string, control byte, command bool, info []byte) {
+	t.Helper()
+	frame := &ax25.Frame{Dest: ax25.MustParseAddress(dest), Src: ax25.MustParseAddress(src), Control: control, Info: info}
+	frame.Dest.H, frame.Src.H = command, !command
+	if frame.HasPID() {
+		frame.PID = ax25.PIDNoLayer3
+	}
+	data, err := frame.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	m.receive(modem.Frame{Channel: 0, Data: data})
+}
+
+func TestConnectedMode(t *testing.T) {
+	m := &fakeModem{channels: 1}
+	server, c := startServer(t, m)
+
+	c.send(Header{Kind: 'X', CallFrom: "N0CALL"}, nil)
+	c.read()
+
+	// 发起连接：发送SABM，收到UA后报告已连接
+	c.send(Header{Port: 0, Kind: 'C', CallFrom: "N0CALL", CallTo: "BG0ABC"}, nil)
+	if f := waitSent(t, m, 1); f.ControlName() != "SABM" || !f.PF() || !f.IsCommand() || f.Dest.Call != "BG0ABC" {
+		t.Fatalf("应发送SABM: %s %+v", f.ControlName(), f)
+	}
+	receiveLink(t, m, "BG0ABC", "N0CALL", ax25.ControlUA|ax25.PFBit, false, nil)
+	h, data := c.read()
+	if h.Kind != 'C' || h.CallFrom != "BG0ABC" || h.CallTo != "N0CALL" ||
+		!strings.HasPrefix(string(data), "*** CONNECTED With Station BG0ABC") {
+		t.Errorf("'C' 应答错误: %+v %q", h, data)
+	}
+	if got := server.linkCount(0); got != 1 {
+		t.Errorf("连接数 = %d, want 1", got)
+	}
+
+	// 发送数据：超过pacLen时分段
+	c.send(Header{Port: 0, Kind: 'D', PID: 0xF0, CallFrom: "N0CALL", CallTo: "BG0ABC"}, bytes.Repeat([]byte("a"), pacLen+10))
+	first, second := waitSent(t, m, 2), waitSent(t, m, 3)
+	if first.ControlName() != "I00" || len(first.Info) != pacLen || second.ControlName() != "I01" || len(second.Info) != 10 {
+		t.Errorf("I帧错误: %s %d, %s %d", first.ControlName(), len(first.Info), second.ControlName(), len(second.Info))
+	}
+	c.send(Header{Port: 0, Kind: 'Y', CallFrom: "N0CALL", CallTo: "BG0ABC"}, nil)
+	if h, data := c.read(); h.Kind != 'Y' || binary.LittleEndian.Uint32(data) != 2 {
+		t.Errorf("'Y' 应答错误: %+v % X", h, data)
+	}
+
+	// 对方确认
+	receiveLink(t, m, "BG0ABC", "N0CALL", 2<<5|ax25.ControlRR, false, nil)
+	c.send(Header{Port: 0, Kind: 'Y', CallFrom: "N0CALL", CallTo: "BG0ABC"}, nil)
+	if _, data := c.read(); binary.LittleEndian.Uint32(data) != 0 {
+		t.Errorf("确认后待发送帧数应为0: % X", data)
+	}
+
+	// 接收数据：交给客户端并回复RR
+	receiveLink(t, m, "BG0ABC", "N0CALL", 2<<5|0<<1, true, []byte("hello"))
+	h, data = c.read()
+	if h.Kind != 'D' || h.CallFrom != "BG0ABC" || h.CallTo != "N0CALL" || string(data) != "hello" {
+		t.Errorf("'D' 数据错误: %+v %q", h, data)
+	}
+	if f := waitSent(t, m, 4); f.ControlName() != "RR1" || f.IsCommand() {
+		t.Errorf("应回复RR1: %s", f.ControlName())
+	}
+
+	// 序号不连续时回复REJ
+	receiveLink(t, m, "BG0ABC", "N0CALL", 2<<5|2<<1, true, []byte("lost"))
+	if f := waitSent(t, m, 5); f.ControlName() != "REJ1" {
+		t.Errorf("应回复REJ1: %s", f.ControlName())
+	}
+
+	// 断开连接：发送DISC，收到UA后报告已断开
+	c.send(Header{Port: 0, Kind: 'd', CallFrom: "N0CALL", CallTo: "BG0ABC"}, nil)
+	if f := waitSent(t, m, 6); f.ControlName() != "DISC" {
+		t.Errorf("应发送DISC: %s", f.ControlName())
+	}
+	receiveLink(t, m, "BG0ABC", "N0CALL", ax25.ControlUA|ax25.PFBit, false, nil)
+	h, data = c.read()
+	if h.Kind != 'd' || !strings.HasPrefix(string(data), "*** DISCONNECTED From Station BG0ABC") {
+		t.Errorf("'d' 应答错误: %+v %q", h, data)
+	}
+	if got := server.linkCount(0); got != 0 {
+		t.Errorf("断开后连接数 = %d, want 0", got)
+	}
+}
+
+func TestIncomingConnection(t *testing.T) {
+	m := &fakeModem{channels: 1}
+	_, c := startServer(t, m)
+
+	// 未注册的呼号不应答
+	receiveLink(t, m, "BG0ABC", "N0CALL", ax25.ControlSABM|ax25.PFBit, true, nil)
+
+	c.send(Header{Kind: 'X', CallFrom: "N0CALL"}, nil)
+	c.read()
+
+	receiveLink(t, m, "BG0ABC", "N0CALL", ax25.ControlSABM|ax25.PFBit, true, nil)
+	h, data := c.read()
+	if h.Kind != 'C' || !strings.HasPrefix(string(data), "*** CONNECTED To Station BG0ABC") {
+		t.Errorf("'C' 应答错误: %+v %q", h, data)
+	}
+	sent := m.sentFrames()
+	if len(sent) != 1 {
+		t.Fatalf("应只回复一个UA: %d", len(sent))
+	}
+	if f := waitSent(t, m, 1); f.ControlName() != "UA" || !f.PF() || f.IsCommand() {
+		t.Errorf("应回复UA: %s", f.ControlName())
+	}
+
+	// 对方断开
+	receiveLink(t, m, "BG0ABC", "N0CALL", ax25.ControlDISC|ax25.PFBit, true, nil)
+	if h, _ := c.read(); h.Kind != 'd' {
+		t.Errorf("应报告已断开: %+v", h)
+	}
+	if f := waitSent(t, m, 2); f.ControlName() != "UA" {
+		t.Errorf("应回复UA: %s", f.ControlName())
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+	old := frack
+	frack = 5 * time.Millisecond
+	defer func() { frack = old }()
+
+	m := &fakeModem{channels: 1}
+	_, c := startServer(t, m)
+
+	c.send(Header{Port: 0, Kind: 'C', CallFrom: "N0CALL", CallTo: "BG0ABC"}, nil)
+	h, data := c.read()
+	if h.Kind != 'd' || !strings.HasPrefix(string(data), "*** DISCONNECTED RETRYOUT With BG0ABC") {
+		t.Errorf("'d' 应答错误: %+v %q", h, data)
+	}
+	if got := len(m.sentFrames()); got != maxRetries+1 {
+		t.Errorf("SABM发送次数 = %d, want %d", got, maxRetries+1)
+	}
+}
@@ -0,0 +1,482 @@
+package agw
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"aprs_agent/ax25"
+)
+
+// AX.25 v2.0 连接模式参数 (模8)
+const (
+	maxFrame   = 7   // 窗口大小，未确认的I帧最多7个
+	pacLen     = 256 // I帧信息字段的最大长度，客户端数据按此分段
+	maxRetries = 10  // N2: T1超时重发的最多次数
+)
+
+// frack T1基本超时时间，经过中继时按 (2*中继数+1) 倍计算，测试时可替换
+var frack = 4 * time.Second
+
+// linkState 连接状态
+type linkState int
+
+const (
+	linkConnecting    linkState = iota // 已发送SABM，等待UA
+	linkConnected                      // 已建立连接
+	linkDisconnecting                  // 已发送DISC，等待UA
+)
+
+// linkKey 连接标识：端口、本地呼号和对方呼号
+type linkKey struct {
+	port   int
+	local  string
+	remote string
+}
+
+// link AX.25连接，由Server.linkMu保护
+type link struct {
+	key    linkKey
+	owner  *client
+	local  ax25.Address
+	remote ax25.Address
+	path   []ax25.Address // 发往对方的中继路径
+	pid    byte           // 发送I帧使用的PID
+	state  linkState
+
+	vs, va, vr int      // 发送状态变量、确认状态变量、接收状态变量
+	sent       [][]byte // 已发送未确认的I帧信息，sent[i]的序号为 va+i
+	queue      [][]byte // 等待发送的数据
+	rejected   bool     // 已发送REJ，等待对方重发
+	peerBusy   bool     // 对方发送了RNR
+
+	retries int
+	timer   *time.Timer
+	timerID int // 每次启动T1递增，用于忽略已失效的超时回调
+}
+
+// outstanding 等待发送和未确认的I帧数
+func (l *link) outstanding() int {
+	return len(l.sent) + len(l.queue)
+}
+
+// t1 T1超时时间
+func (l *link) t1() time.Duration {
+	return frack * time.Duration(2*len(l.path)+1)
+}
+
+// connect 处理客户端的 'C'/'v'/'c' 请求，向对方发送SABM
+func (s *Server) connect(c *client, h Header, via []string) {
+	local, err := ax25.ParseAddress(h.CallFrom)
+	if err != nil {
+		log.Printf("AGW客户端 %s 源呼号无效: %v", c.name, err)
+		return
+	}
+	remote, err := ax25.ParseAddress(h.CallTo)
+	if err != nil {
+		log.Printf("AGW客户端 %s 目的呼号无效: %v", c.name, err)
+		return
+	}
+	path := make([]ax25.Address, 0, len(via))
+	for _, v := range via {
+		addr, err := ax25.ParseAddress(v)
+		if err != nil {
+			log.Printf("AGW客户端 %s 中继呼号无效: %v", c.name, err)
+			return
+		}
+		addr.H = false
+		path = append(path, addr)
+	}
+
+	pid := byte(ax25.PIDNoLayer3)
+	if h.Kind == 'c' && h.PID != 0 {
+		pid = h.PID
+	}
+
+	key := linkKey{port: h.Port, local: local.String(), remote: remote.String()}
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+
+	if l, ok := s.links[key]; ok {
+		// 已有连接时AGWPE返回当前状态
+		if l.owner == c && l.state == linkConnected {
+			s.notifyConnected(l, false)
+		}
+		return
+	}
+
+	l := &link{key: key, owner: c, local: local, remote: remote, path: path, pid: pid, state: linkConnecting}
+	s.links[key] = l
+	log.Printf("AGW端口 %d: %s 正在连接 %s", h.Port, local, remote)
+	s.sendControl(l, ax25.ControlSABM|ax25.PFBit, true)
+	s.startT1(l)
+}
+
+// disconnect 处理客户端的 'd' 请求，向对方发送DISC
+func (s *Server) disconnect(c *client, h Header) {
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+
+	l := s.clientLink(c, h)
+	if l == nil {
+		return
+	}
+	if l.state == linkConnecting {
+		// 尚未建立连接，直接放弃
+		s.closeLink(l, fmt.Sprintf("*** DISCONNECTED From Station %s\r", l.remote))
+		return
+	}
+	l.state = linkDisconnecting
+	l.queue, l.sent = nil, nil
+	l.retries = 0
+	s.sendControl(l, ax25.ControlDISC|ax25.PFBit, true)
+	s.startT1(l)
+}
+
+// sendData 处理客户端的 'D' 请求，数据按pacLen分段后排队发送
+func (s *Server) sendData(c *client, h Header, data []byte) {
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+
+	l := s.clientLink(c, h)
+	if l == nil || l.state != linkConnected {
+		log.Printf("AGW客户端 %s 端口 %d: 与 %s 没有连接，丢弃数据", c.name, h.Port, h.CallTo)
+		return
+	}
+	for len(data) > 0 {
+		n := min(len(data), pacLen)
+		l.queue = append(l.queue, append([]byte(nil), data[:n]...))
+		data = data[n:]
+	}
+	s.pushFrames(l)
+}
+
+// linkOutstanding 'Y' 应答：连接上等待发送和未确认的帧数
+func (s *Server) linkOutstanding(c *client, h Header) int {
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+	if l := s.clientLink(c, h); l != nil {
+		return l.outstanding()
+	}
+	return 0
+}
+
+// linkCount 端口上的连接数
+func (s *Server) linkCount(port int) int {
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+	n := 0
+	for key := range s.links {
+		if key.port == port {
+			n++
+		}
+	}
+	return n
+}
+
+// closeClientLinks 客户端断开时断开其所有连接，调用时不能持有s.mu
+func (s *Server) closeClientLinks(c *client) {
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+	for _, l := range s.links {
+		if l.owner != c {
+			continue
+		}
+		if l.state == linkConnected {
+			s.sendControl(l, ax25.ControlDISC|ax25.PFBit, true)
+		}
+		s.removeLink(l)
+	}
+}
+
+// clientLink 查找客户端请求对应的连接，调用时需持有s.linkMu
+func (s *Server) clientLink(c *client, h Header) *link {
+	l := s.links[linkKey{port: h.Port, local: h.CallFrom, remote: h.CallTo}]
+	if l == nil || l.owner != c {
+		return nil
+	}
+	return l
+}
+
+// onLinkFrame 处理接收到的非UI帧，调用时不能持有s.mu
+func (s *Server) onLinkFrame(port int, frame *ax25.Frame) {
+	// 经过中继的帧在所有中继转发后才是发给本站的
+	for _, digi := range frame.Path {
+		if !digi.H {
+			return
+		}
+	}
+
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+
+	key := linkKey{port: port, local: frame.Dest.String(), remote: frame.Src.String()}
+	l := s.links[key]
+	control := frame.Control &^ ax25.PFBit
+
+	if l == nil {
+		s.mu.Lock()
+		owner := s.registered[key.local]
+		s.mu.Unlock()
+		if owner == nil {
+			return
+		}
+		switch {
+		case frame.Type() == ax25.FrameU && control == ax25.ControlSABM:
+			// 对方发起连接
+			l = &link{key: key, owner: owner, local: frame.Dest, remote: frame.Src,
+				path: replyPath(frame.Path), pid: ax25.PIDNoLayer3, state: linkConnected}
+			l.local.H, l.remote.H = false, false
+			s.links[key] = l
+			s.sendControl(l, ax25.ControlUA|pfBit(frame.PF()), false)
+			s.notifyConnected(l, true)
+		case frame.IsCommand() && frame.PF() && !(frame.Type() == ax25.FrameU && control == ax25.ControlDM):
+			// 没有连接时对轮询的命令回复DM
+			l = &link{key: key, owner: owner, local: frame.Dest, remote: frame.Src, path: replyPath(frame.Path)}
+			l.local.H, l.remote.H = false, false
+			s.sendControl(l, ax25.ControlDM|ax25.PFBit, false)
+		}
+		return
+	}
+
+	switch frame.Type() {
+	case ax25.FrameU:
+		s.onLinkU(l, frame, control)
+	case ax25.FrameS:
+		if l.state != linkConnected {
+			return
+		}
+		if !s.ackTo(l, frame.NR()) {
+			return
+		}
+		switch frame.Control & 0x0F {
+		case ax25.ControlRR:
+			l.peerBusy = false
+		case ax25.ControlRNR:
+			l.peerBusy = true
+		case ax25.ControlREJ:
+			// 从N(R)开始重发所有未确认的帧
+			l.peerBusy = false
+			s.resend(l)
+		}
+		if frame.IsCommand() && frame.PF() {
+			s.sendControl(l, byte(l.vr<<5)|ax25.ControlRR|ax25.PFBit, false)
+		}
+		s.pushFrames(l)
+	case ax25.FrameI:
+		if l.state != linkConnected {
+			return
+		}
+		if !s.ackTo(l, frame.NR()) {
+			return
+		}
+		if frame.NS() == l.vr {
+			l.vr = (l.vr + 1) % 8
+			l.rejected = false
+			l.owner.send(Encode(Header{Port: port, Kind: 'D', PID: frame.PID,
+				CallFrom: l.remote.String(), CallTo: l.local.String()}, frame.Info))
+			s.sendControl(l, byte(l.vr<<5)|ax25.ControlRR|pfBit(frame.PF()), false)
+		} else if !l.rejected || frame.PF() {
+			// 序号不连续，请求对方从V(R)开始重发
+			l.rejected = true
+			s.sendControl(l, byte(l.vr<<5)|ax25.ControlREJ|pfBit(frame.PF()), false)
+		}
+		s.pushFrames(l)
+	}
+}
+
+// onLinkU 处理已有连接上收到的无编号帧
+func (s *Server) onLinkU(l *link, frame *ax25.Frame, control byte) {
+	switch control {
+	case ax25.ControlSABM:
+		// 对方重新建立连接，复位序号
+		l.vs, l.va, l.vr = 0, 0, 0
+		l.sent, l.rejected, l.peerBusy = nil, false, false
+		s.sendControl(l, ax25.ControlUA|pfBit(frame.PF()), false)
+		if l.state == linkConnecting {
+			l.state = linkConnected
+			s.stopT1(l)
+			s.notifyConnected(l, false)
+		}
+	case ax25.ControlUA:
+		switch l.state {
+		case linkConnecting:
+			l.state = linkConnected
+			s.stopT1(l)
+			s.notifyConnected(l, false)
+			s.pushFrames(l)
+		case linkDisconnecting:
+			s.closeLink(l, fmt.Sprintf("*** DISCONNECTED From Station %s\r", l.remote))
+		}
+	case ax25.ControlDISC:
+		s.sendControl(l, ax25.ControlUA|pfBit(frame.PF()), false)
+		s.closeLink(l, fmt.Sprintf("*** DISCONNECTED From Station %s\r", l.remote))
+	case ax25.ControlDM:
+		if l.state == linkConnecting {
+			s.closeLink(l, fmt.Sprintf("*** %s station is busy, connection refused\r", l.remote))
+			return
+		}
+		s.closeLink(l, fmt.Sprintf("*** DISCONNECTED From Station %s\r", l.remote))
+	case ax25.ControlFRMR:
+		s.closeLink(l, fmt.Sprintf("*** DISCONNECTED From Station %s\r", l.remote))
+	}
+}
+
+// ackTo 确认N(R)之前的所有I帧，N(R)无效时返回false
+func (s *Server) ackTo(l *link, nr int) bool {
+	acked := (nr - l.va + 8) % 8
+	if acked > len(l.sent) {
+		log.Printf("AGW端口 %d: %s 发来的N(R)=%d无效", l.key.port, l.remote, nr)
+		return false
+	}
+	if acked == 0 {
+		return true
+	}
+	l.sent = l.sent[acked:]
+	l.va = nr
+	l.retries = 0
+	if len(l.sent) == 0 {
+		s.stopT1(l)
+	} else {
+		s.startT1(l)
+	}
+	return true
+}
+
+// pushFrames 在窗口允许时发送排队的数据
+func (s *Server) pushFrames(l *link) {
+	if l.state != linkConnected || l.peerBusy {
+		return
+	}
+	sent := false
+	for len(l.queue) > 0 && len(l.sent) < maxFrame {
+		info := l.queue[0]
+		l.queue = l.queue[1:]
+		s.sendI(l, l.vs, info)
+		l.sent = append(l.sent, info)
+		l.vs = (l.vs + 1) % 8
+		sent = true
+	}
+	if sent && l.timer == nil {
+		s.startT1(l)
+	}
+}
+
+// resend 从V(A)开始重发所有未确认的I帧
+func (s *Server) resend(l *link) {
+	for i, info := range l.sent {
+		s.sendI(l, (l.va+i)%8, info)
+	}
+	if len(l.sent) > 0 {
+		s.startT1(l)
+	}
+}
+
+// sendI 发送I帧
+func (s *Server) sendI(l *link, ns int, info []byte) {
+	frame := s.linkFrame(l, byte(l.vr<<5|ns<<1), true)
+	frame.PID = l.pid
+	frame.Info = info
+	s.transmit(l.owner, l.key.port, frame)
+}
+
+// sendControl 发送S帧或U帧，command为false时为响应帧
+func (s *Server) sendControl(l *link, control byte, command bool) {
+	s.transmit(l.owner, l.key.port, s.linkFrame(l, control, command))
+}
+
+// linkFrame 创建发往对方的帧，命令帧目的地址C位为1，响应帧源地址C位为1
+func (s *Server) linkFrame(l *link, control byte, command bool) *ax25.Frame {
+	dest, src := l.remote, l.local
+	dest.H, src.H = command, !command
+	return &ax25.Frame{Dest: dest, Src: src, Path: l.path, Control: control}
+}
+
+// startT1 启动或重启T1
+func (s *Server) startT1(l *link) {
+	s.stopT1(l)
+	l.timerID++
+	id := l.timerID
+	l.timer = time.AfterFunc(l.t1(), func() { s.onT1(l, id) })
+}
+
+// stopT1 停止T1
+func (s *Server) stopT1(l *link) {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+}
+
+// onT1 T1超时：重发SABM、DISC或未确认的I帧，超过maxRetries次后断开
+func (s *Server) onT1(l *link, id int) {
+	s.linkMu.Lock()
+	defer s.linkMu.Unlock()
+
+	if s.links[l.key] != l || l.timerID != id {
+		return
+	}
+	l.timer = nil
+	l.retries++
+	if l.retries > maxRetries {
+		log.Printf("AGW端口 %d: %s 重试%d次无应答，断开连接", l.key.port, l.remote, maxRetries)
+		s.closeLink(l, fmt.Sprintf("*** DISCONNECTED RETRYOUT With %s\r", l.remote))
+		return
+	}
+
+	switch l.state {
+	case linkConnecting:
+		s.sendControl(l, ax25.ControlSABM|ax25.PFBit, true)
+		s.startT1(l)
+	case linkDisconnecting:
+		s.sendControl(l, ax25.ControlDISC|ax25.PFBit, true)
+		s.startT1(l)
+	case linkConnected:
+		s.resend(l)
+	}
+}
+
+// notifyConnected 向客户端报告连接已建立
+func (s *Server) notifyConnected(l *link, incoming bool) {
+	text := fmt.Sprintf("*** CONNECTED With Station %s\r", l.remote)
+	if incoming {
+		text = fmt.Sprintf("*** CONNECTED To Station %s\r", l.remote)
+	}
+	log.Printf("AGW端口 %d: %s 已连接 %s", l.key.port, l.local, l.remote)
+	l.owner.send(Encode(Header{Port: l.key.port, Kind: 'C', CallFrom: l.remote.String(), CallTo: l.local.String()},
+		append([]byte(text), 0)))
+}
+
+// closeLink 移除连接并向客户端报告已断开
+func (s *Server) closeLink(l *link, text string) {
+	s.removeLink(l)
+	log.Printf("AGW端口 %d: %s 与 %s 的连接已断开", l.key.port, l.local, l.remote)
+	l.owner.send(Encode(Header{Port: l.key.port, Kind: 'd', CallFrom: l.remote.String(), CallTo: l.local.String()},
+		append([]byte(text), 0)))
+}
+
+// removeLink 移除连接并停止T1
+func (s *Server) removeLink(l *link) {
+	s.stopT1(l)
+	if s.links[l.key] == l {
+		delete(s.links, l.key)
+	}
+}
+
+// replyPath 将收到的中继路径反转为回复路径
+func replyPath(path []ax25.Address) []ax25.Address {
+	reply := make([]ax25.Address, len(path))
+	for i, digi := range path {
+		digi.H = false
+		reply[len(path)-1-i] = digi
+	}
+	return reply
+}
+
+// pfBit 返回P/F位
+func pfBit(set bool) byte {
+	if set {
+		return ax25.PFBit
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package agw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// HeaderLen AGWPE帧头长度
+const HeaderLen = 36
+
+// MaxDataLen 接受的最大数据长度
+const MaxDataLen = 64 * 1024
+
+// callLen 帧头中呼号字段的长度（以0结尾）
+const callLen = 10
+
+// Header AGWPE帧头
+//
+//	偏移  长度  字段
+//	0     1     端口号 (从0开始)，后跟3字节保留
+//	4     1     数据类型，后跟1字节保留
+//	6     1     PID，后跟1字节保留
+//	8     10    源呼号
+//	18    10    目的呼号
+//	28    4     数据长度 (小端)
+//	32    4     保留
+type Header struct {
+	Port     int
+	Kind     byte
+	PID      byte
+	CallFrom string
+	CallTo   string
+	DataLen  uint32
+}
+
+// Encode 编码帧头和数据
+func Encode(h Header, data []byte) []byte {
+	out := make([]byte, HeaderLen+len(data))
+	out[0] = byte(h.Port)
+	out[4] = h.Kind
+	out[6] = h.PID
+	putCall(out[8:18], h.CallFrom)
+	putCall(out[18:28], h.CallTo)
+	binary.LittleEndian.PutUint32(out[28:32], uint32(len(data)))
+	copy(out[HeaderLen:], data)
+	return out
+}
+
+// ReadFrame 从流中读取一个完整的帧
+func ReadFrame(r io.Reader) (Header, []byte, error) {
+	var buf [HeaderLen]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return Header{}, nil, err
+	}
+
+	h := Header{
+		Port:     int(buf[0]),
+		Kind:     buf[4],
+		PID:      buf[6],
+		CallFrom: getCall(buf[8:18]),
+		CallTo:   getCall(buf[18:28]),
+		DataLen:  binary.LittleEndian.Uint32(buf[28:32]),
+	}
+	if h.DataLen > MaxDataLen {
+		return h, nil, fmt.Errorf("AGW数据长度过大: %d", h.DataLen)
+	}
+
+	data := make([]byte, h.DataLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return h, nil, err
+	}
+	return h, data, nil
+}
+
+// putCall 写入以0结尾的呼号
+func putCall(dst []byte, call string) {
+	if len(call) > callLen-1 {
+		call = call[:callLen-1]
+	}
+	copy(dst, call)
+}
+
+// getCall 读取以0结尾的呼号
+func getCall(src []byte) string {
+	if i := strings.IndexByte(string(src), 0); i >= 0 {
+		src = src[:i]
+	}
+	return strings.TrimSpace(string(src))
+}
@@ -0,0 +1,642 @@
+package agw
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aprs_agent/audio"
+	"aprs_agent/ax25"
+	"aprs_agent/modem"
+)
+
+// DefaultPort AGWPE默认端口
+const DefaultPort = 8000
+
+// 'R' 返回的版本号，与Dire Wolf报告的一致
+const (
+	versionMajor = 2005
+	versionMinor = 127
+)
+
+// statsWindow 'g' 报告接收字节数的统计窗口
+const statsWindow = 2 * time.Minute
+
+// sendQueueSize 每个客户端的发送队列长度
+const sendQueueSize = 64
+
+// maxHeard 'H' 返回的最多站点数
+const maxHeard = 20
+
+// Modem AGWPE服务使用的调制解调器接口，由audio.Manager实现
+type Modem interface {
+	AddFrameHandler(handler func(modem.Frame))
+	Transmit(channel int, data []byte) error
+	ChannelCount() int
+	ChannelDeviceName(channel int) string
+	GetTxParams(channel int) (audio.TxParams, error)
+	GetTxQueueSize(channel int) int
+}
+
+// heardStation 收听到的站点
+type heardStation struct {
+	call  string
+	first time.Time
+	last  time.Time
+}
+
+// rxRecord 接收字节记录
+type rxRecord struct {
+	at    time.Time
+	bytes int
+}
+
+// client AGWPE客户端连接
+type client struct {
+	name      string
+	conn      net.Conn
+	out       chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// 由Server.mu保护
+	monitor bool // 接收监听帧 ('m')
+	raw     bool // 接收原始AX.25帧 ('k')
+}
+
+// send 将帧放入发送队列，队列满时丢弃
+func (c *client) send(data []byte) {
+	select {
+	case c.out <- data:
+	case <-c.done:
+	default:
+		log.Printf("AGW客户端 %s 发送队列已满，丢弃帧", c.name)
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *client) writeLoop() {
+	for {
+		select {
+		case data := <-c.out:
+			if _, err := c.conn.Write(data); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Server AGWPE兼容的TCP服务
+// 音频管理器的每个通道对应一个AGW端口，支持UI帧收发和AX.25 v2.0连接模式
+type Server struct {
+	modem    Modem
+	addr     string
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+	clients  map[*client]struct{}
+	// 已注册的呼号
+	registered map[string]*client
+	heard      map[int]map[string]*heardStation
+	received   map[int][]rxRecord
+	wg         sync.WaitGroup
+
+	// AX.25连接，需要同时持有时先锁linkMu再锁mu
+	linkMu sync.Mutex
+	links  map[linkKey]*link
+}
+
+// NewServer 创建AGWPE服务
+func NewServer(m Modem, addr string) *Server {
+	s := &Server{
+		modem:      m,
+		addr:       addr,
+		clients:    make(map[*client]struct{}),
+		registered: make(map[string]*client),
+		heard:      make(map[int]map[string]*heardStation),
+		received:   make(map[int][]rxRecord),
+		links:      make(map[linkKey]*link),
+	}
+	m.AddFrameHandler(s.onFrame)
+	return s
+}
+
+// Start 开始监听，ctx取消时自动停止
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return fmt.Errorf("AGW服务已在运行")
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("AGW服务监听失败: %w", err)
+	}
+	s.listener = listener
+	s.closed = false
+
+	s.wg.Add(1)
+	go s.acceptLoop(listener)
+
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+
+	log.Printf("AGWPE服务已启动: %s", listener.Addr())
+	return nil
+}
+
+// Stop 停止监听并断开所有客户端
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	listener := s.listener
+	s.listener = nil
+	s.closed = true
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+
+	err := listener.Close()
+	for _, c := range clients {
+		c.close()
+	}
+	s.wg.Wait()
+
+	log.Println("AGWPE服务已停止")
+	return err
+}
+
+// Addr 获取监听地址，未启动时返回nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// ClientCount 获取已连接的客户端数
+func (s *Server) ClientCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) acceptLoop(listener net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("AGW服务接受连接失败: %v", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+// serve 处理一个客户端连接
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+
+	c := &client{
+		name: conn.RemoteAddr().String(),
+		conn: conn,
+		out:  make(chan []byte, sendQueueSize),
+		done: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go c.writeLoop()
+	log.Printf("AGW客户端已连接: %s", c.name)
+
+	for {
+		h, data, err := ReadFrame(conn)
+		if err != nil {
+			break
+		}
+		s.handle(c, h, data)
+	}
+
+	c.close()
+	s.mu.Lock()
+	delete(s.clients, c)
+	for call, owner := range s.registered {
+		if owner == c {
+			delete(s.registered, call)
+		}
+	}
+	s.mu.Unlock()
+	s.closeClientLinks(c)
+	log.Printf("AGW客户端已断开: %s", c.name)
+}
+
+// handle 处理客户端发来的帧
+func (s *Server) handle(c *client, h Header, data []byte) {
+	switch h.Kind {
+	case 'R':
+		// 版本号
+		reply := make([]byte, 8)
+		binary.LittleEndian.PutUint32(reply[0:], versionMajor)
+		binary.LittleEndian.PutUint32(reply[4:], versionMinor)
+		c.send(Encode(Header{Kind: 'R'}, reply))
+	case 'G':
+		c.send(Encode(Header{Kind: 'G'}, s.portInfo()))
+	case 'g':
+		if !s.validPort(c, h) {
+			return
+		}
+		c.send(Encode(Header{Port: h.Port, Kind: 'g'}, s.portCapabilities(h.Port)))
+	case 'X':
+		c.send(Encode(Header{Port: h.Port, Kind: 'X', CallFrom: h.CallFrom}, []byte{s.register(c, h.CallFrom)}))
+	case 'x':
+		s.unregister(c, h.CallFrom)
+	case 'm':
+		s.mu.Lock()
+		c.monitor = !c.monitor
+		s.mu.Unlock()
+	case 'k':
+		s.mu.Lock()
+		c.raw = !c.raw
+		s.mu.Unlock()
+	case 'K':
+		// 首字节为KISS端口和命令，其后为不含FCS的AX.25帧
+		if !s.validPort(c, h) || len(data) < 2 {
+			return
+		}
+		frame, err := ax25.Decode(data[1:])
+		if err != nil {
+			log.Printf("AGW客户端 %s 发送的原始帧无效: %v", c.name, err)
+			return
+		}
+		s.transmit(c, h.Port, frame)
+	case 'M':
+		if !s.validPort(c, h) {
+			return
+		}
+		s.transmitUnproto(c, h, nil, data)
+	case 'V':
+		if !s.validPort(c, h) {
+			return
+		}
+		via, info, ok := parseVia(data)
+		if !ok {
+			log.Printf("AGW客户端 %s 'V' 帧长度错误", c.name)
+			return
+		}
+		s.transmitUnproto(c, h, via, info)
+	case 'H':
+		if !s.validPort(c, h) {
+			return
+		}
+		for _, frame := range s.heardList(h.Port) {
+			c.send(frame)
+		}
+	case 'y':
+		reply := make([]byte, 4)
+		binary.LittleEndian.PutUint32(reply, uint32(s.modem.GetTxQueueSize(h.Port)))
+		c.send(Encode(Header{Port: h.Port, Kind: 'y'}, reply))
+	case 'Y':
+		reply := make([]byte, 4)
+		binary.LittleEndian.PutUint32(reply, uint32(s.linkOutstanding(c, h)))
+		c.send(Encode(Header{Port: h.Port, Kind: 'Y', CallFrom: h.CallFrom, CallTo: h.CallTo}, reply))
+	case 'C', 'c':
+		if !s.validPort(c, h) {
+			return
+		}
+		s.connect(c, h, nil)
+	case 'v':
+		if !s.validPort(c, h) {
+			return
+		}
+		via, _, ok := parseVia(data)
+		if !ok {
+			log.Printf("AGW客户端 %s 'v' 帧长度错误", c.name)
+			return
+		}
+		s.connect(c, h, via)
+	case 'D':
+		s.sendData(c, h, data)
+	case 'd':
+		s.disconnect(c, h)
+	default:
+		log.Printf("AGW客户端 %s 不支持的帧类型: %q", c.name, h.Kind)
+	}
+}
+
+// validPort 检查端口号是否有效
+func (s *Server) validPort(c *client, h Header) bool {
+	if h.Port >= s.modem.ChannelCount() {
+		log.Printf("AGW客户端 %s 端口 %d 不存在 (帧类型 %q)", c.name, h.Port, h.Kind)
+		return false
+	}
+	return true
+}
+
+// parseVia 解析 'V'/'v' 帧数据：中继数、每个中继10字节呼号，其后为信息字段
+func parseVia(data []byte) ([]string, []byte, bool) {
+	if len(data) < 1 || len(data) < 1+int(data[0])*callLen {
+		return nil, nil, false
+	}
+	n := int(data[0])
+	via := make([]string, n)
+	for i := 0; i < n; i++ {
+		via[i] = getCall(data[1+i*callLen : 1+(i+1)*callLen])
+	}
+	return via, data[1+n*callLen:], true
+}
+
+// portInfo 生成 'G' 应答: "端口数;Port1 描述;Port2 描述;"
+func (s *Server) portInfo() []byte {
+	n := s.modem.ChannelCount()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d;", n)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "Port%d %s;", i+1, s.modem.ChannelDeviceName(i))
+	}
+	sb.WriteByte(0)
+	return []byte(sb.String())
+}
+
+// portCapabilities 生成 'g' 应答的12字节端口参数
+func (s *Server) portCapabilities(port int) []byte {
+	params, _ := s.modem.GetTxParams(port)
+
+	reply := make([]byte, 12)
+	reply[0] = 0    // 空中波特率: 0表示1200
+	reply[1] = 0xFF // 流量等级: 未启用自动更新
+	reply[2] = clampByte(int(params.TxDelay / (10 * time.Millisecond)))
+	reply[3] = clampByte(int(params.TxTail / (10 * time.Millisecond)))
+	reply[4] = clampByte(params.Persistence)
+	reply[5] = clampByte(int(params.SlotTime / (10 * time.Millisecond)))
+	reply[6] = maxFrame
+	reply[7] = clampByte(s.linkCount(port)) // 活动连接数
+	binary.LittleEndian.PutUint32(reply[8:], uint32(s.receivedBytes(port)))
+	return reply
+}
+
+// register 注册呼号，成功返回1
+func (s *Server) register(c *client, call string) byte {
+	if _, err := ax25.ParseAddress(call); err != nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if owner, ok := s.registered[call]; ok && owner != c {
+		return 0
+	}
+	s.registered[call] = c
+	return 1
+}
+
+// unregister 注销呼号
+func (s *Server) unregister(c *client, call string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.registered[call] == c {
+		delete(s.registered, call)
+	}
+}
+
+// transmitUnproto 发送 'M'/'V' 请求的UI帧
+func (s *Server) transmitUnproto(c *client, h Header, via []string, info []byte) {
+	src, err := ax25.ParseAddress(h.CallFrom)
+	if err != nil {
+		log.Printf("AGW客户端 %s 源呼号无效: %v", c.name, err)
+		return
+	}
+	dest, err := ax25.ParseAddress(h.CallTo)
+	if err != nil {
+		log.Printf("AGW客户端 %s 目的呼号无效: %v", c.name, err)
+		return
+	}
+
+	path := make([]ax25.Address, 0, len(via))
+	for _, v := range via {
+		addr, err := ax25.ParseAddress(v)
+		if err != nil {
+			log.Printf("AGW客户端 %s 中继呼号无效: %v", c.name, err)
+			return
+		}
+		path = append(path, addr)
+	}
+
+	frame := ax25.NewUIFrame(src, dest, path, info)
+	if h.PID != 0 {
+		frame.PID = h.PID
+	}
+	s.transmit(c, h.Port, frame)
+}
+
+// transmit 发送帧，成功后向监听客户端报告 'T' 帧
+func (s *Server) transmit(c *client, port int, frame *ax25.Frame) {
+	data, err := frame.Encode()
+	if err != nil {
+		log.Printf("AGW客户端 %s 编码帧失败: %v", c.name, err)
+		return
+	}
+	if err := s.modem.Transmit(port, data); err != nil {
+		log.Printf("AGW客户端 %s 端口 %d 发送失败: %v", c.name, port, err)
+		return
+	}
+
+	_, text := monitorText(port, frame, time.Now())
+	monitor := Encode(Header{Port: port, Kind: 'T', PID: frame.PID, CallFrom: frame.Src.String(), CallTo: frame.Dest.String()}, text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for cl := range s.clients {
+		if cl.monitor {
+			cl.send(monitor)
+		}
+	}
+}
+
+// onFrame 处理接收到的帧：记录收听站点，向客户端发送原始帧和监听帧，连接模式的帧交给对应的连接
+func (s *Server) onFrame(f modem.Frame) {
+	now := time.Now()
+	frame, err := ax25.Decode(f.Data)
+
+	s.mu.Lock()
+	s.received[f.Channel] = append(pruneRecords(s.received[f.Channel], now), rxRecord{at: now, bytes: len(f.Data)})
+
+	var monitor []byte
+	if err == nil {
+		s.recordHeard(f.Channel, frame.Src.String(), now)
+		kind, text := monitorText(f.Channel, frame, now)
+		monitor = Encode(Header{Port: f.Channel, Kind: kind, PID: frame.PID, CallFrom: frame.Src.String(), CallTo: frame.Dest.String()}, text)
+	}
+
+	var raw []byte
+	for c := range s.clients {
+		if c.raw {
+			if raw == nil {
+				// 原始帧首字节为KISS端口和命令
+				payload := append([]byte{byte(f.Channel << 4)}, f.Data...)
+				raw = Encode(Header{Port: f.Channel, Kind: 'K'}, payload)
+			}
+			c.send(raw)
+		}
+		if c.monitor && monitor != nil {
+			c.send(monitor)
+		}
+	}
+	s.mu.Unlock()
+
+	if err == nil && !frame.IsUI() {
+		s.onLinkFrame(f.Channel, frame)
+	}
+}
+
+// recordHeard 记录收听到的站点，调用时需持有s.mu
+func (s *Server) recordHeard(port int, call string, now time.Time) {
+	stations := s.heard[port]
+	if stations == nil {
+		stations = make(map[string]*heardStation)
+		s.heard[port] = stations
+	}
+	if st, ok := stations[call]; ok {
+		st.last = now
+		return
+	}
+	stations[call] = &heardStation{call: call, first: now, last: now}
+}
+
+// heardList 生成 'H' 应答，每个站点一帧，按最后收听时间倒序
+// 数据为 "呼号 首次收听时间 最后收听时间"，没有站点时返回一个空帧
+func (s *Server) heardList(port int) [][]byte {
+	s.mu.Lock()
+	stations := make([]heardStation, 0, len(s.heard[port]))
+	for _, st := range s.heard[port] {
+		stations = append(stations, *st)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].last.After(stations[j].last)
+	})
+	if len(stations) > maxHeard {
+		stations = stations[:maxHeard]
+	}
+
+	if len(stations) == 0 {
+		return [][]byte{Encode(Header{Port: port, Kind: 'H'}, nil)}
+	}
+
+	frames := make([][]byte, 0, len(stations))
+	for _, st := range stations {
+		text := fmt.Sprintf("%-9s %s %s\x00", st.call,
+			st.first.Format("2006-01-02 15:04:05"), st.last.Format("2006-01-02 15:04:05"))
+		frames = append(frames, Encode(Header{Port: port, Kind: 'H', CallFrom: st.call}, []byte(text)))
+	}
+	return frames
+}
+
+// receivedBytes 统计窗口内接收的字节数
+func (s *Server) receivedBytes(port int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received[port] = pruneRecords(s.received[port], time.Now())
+	total := 0
+	for _, r := range s.received[port] {
+		total += r.bytes
+	}
+	return total
+}
+
+// pruneRecords 删除统计窗口之外的记录
+func pruneRecords(records []rxRecord, now time.Time) []rxRecord {
+	i := 0
+	for i < len(records) && now.Sub(records[i].at) > statsWindow {
+		i++
+	}
+	return records[i:]
+}
+
+// monitorText 生成监听帧的类型和文本，格式与AGWPE一致:
+// " 1:Fm N0CALL To APRS Via WIDE1-1 <UI pid=F0 Len=5 >[12:00:00]\r信息\r"
+func monitorText(port int, frame *ax25.Frame, t time.Time) (byte, []byte) {
+	via := ""
+	if len(frame.Path) > 0 {
+		digis := make([]string, len(frame.Path))
+		for i, digi := range frame.Path {
+			digis[i] = digi.String()
+			if digi.H {
+				digis[i] += "*"
+			}
+		}
+		via = " Via " + strings.Join(digis, ",")
+	}
+
+	var kind byte
+	var control string
+	switch frame.Type() {
+	case ax25.FrameI:
+		kind = 'I'
+		control = fmt.Sprintf("<I R%d S%d pid=%02X Len=%d >", frame.NR(), frame.NS(), frame.PID, len(frame.Info))
+	default:
+		if frame.IsUI() {
+			kind = 'U'
+			control = fmt.Sprintf("<UI pid=%02X Len=%d >", frame.PID, len(frame.Info))
+		} else {
+			kind = 'S'
+			control = fmt.Sprintf("<%s >", frame.ControlName())
+		}
+	}
+
+	text := fmt.Sprintf(" %d:Fm %s To %s%s %s[%s]\r", port+1, frame.Src, frame.Dest, via, control, t.Format("15:04:05"))
+	if len(frame.Info) > 0 {
+		text += string(frame.Info) + "\r"
+	}
+	return kind, append([]byte(text), 0)
+}
+
+func clampByte(v int) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
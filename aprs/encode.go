@@ -0,0 +1,586 @@
+package aprs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 压缩位置的压缩类型字节 (T)
+// 0x20 当前定位，0x18/0x10 NMEA来源为RMC/GGA，0x02 来源为软件
+const (
+	compressionTypeRMC = 0x3A
+	compressionTypeGGA = 0x32
+)
+
+// 消息和名称长度限制
+const (
+	MaxAddresseeLen   = 9
+	MaxMessageTextLen = 67
+	MaxMessageIDLen   = 5
+	MaxObjectNameLen  = 9
+	MinItemNameLen    = 3
+	MaxItemNameLen    = 9
+)
+
+// EncodePosition 编码位置报文信息字段
+// timestamp为零值时不带时间戳 (! 或 =)，否则使用DDHHMMz格式 (/ 或 @)
+// pos.Compressed 为true时使用Base91压缩格式
+func EncodePosition(pos *Position, timestamp time.Time, messaging bool, comment string) (string, error) {
+	data, err := encodePositionData(pos, comment)
+	if err != nil {
+		return "", err
+	}
+
+	var dataType byte
+	switch {
+	case timestamp.IsZero() && !messaging:
+		dataType = '!'
+	case timestamp.IsZero():
+		dataType = '='
+	case !messaging:
+		dataType = '/'
+	default:
+		dataType = '@'
+	}
+
+	if timestamp.IsZero() {
+		return string(dataType) + data, nil
+	}
+	return string(dataType) + encodeTimestamp(timestamp) + data, nil
+}
+
+// EncodeObject 编码对象报文，obj.Live为false时生成删除对象的报文
+// timestamp为零值时使用当前时间
+func EncodeObject(obj *Object, pos *Position, timestamp time.Time, comment string) (string, error) {
+	if obj.Name == "" || len(obj.Name) > MaxObjectNameLen {
+		return "", fmt.Errorf("对象名称长度必须为1-%d个字符: %q", MaxObjectNameLen, obj.Name)
+	}
+	if !isPrintable(obj.Name) {
+		return "", fmt.Errorf("对象名称包含非打印字符: %q", obj.Name)
+	}
+
+	data, err := encodePositionData(pos, comment)
+	if err != nil {
+		return "", err
+	}
+
+	if timestamp.IsZero() {
+		timestamp = nowFunc()
+	}
+
+	state := byte('*')
+	if !obj.Live {
+		state = '_'
+	}
+	return fmt.Sprintf(";%-9s%c%s%s", obj.Name, state, encodeTimestamp(timestamp), data), nil
+}
+
+// EncodeItem 编码条目报文，item.Live为false时生成删除条目的报文
+func EncodeItem(item *Item, pos *Position, comment string) (string, error) {
+	if len(item.Name) < MinItemNameLen || len(item.Name) > MaxItemNameLen {
+		return "", fmt.Errorf("条目名称长度必须为%d-%d个字符: %q", MinItemNameLen, MaxItemNameLen, item.Name)
+	}
+	if !isPrintable(item.Name) || strings.ContainsAny(item.Name, "!_") {
+		return "", fmt.Errorf("条目名称包含无效字符: %q", item.Name)
+	}
+
+	data, err := encodePositionData(pos, comment)
+	if err != nil {
+		return "", err
+	}
+
+	state := byte('!')
+	if !item.Live {
+		state = '_'
+	}
+	return fmt.Sprintf(")%s%c%s", item.Name, state, data), nil
+}
+
+// EncodeMessage 编码消息、确认或拒绝报文
+// msg.ID非空时生成带编号的消息，msg.ReplyAck非空时使用 {MM}AA 回复确认格式
+func EncodeMessage(msg *Message) (string, error) {
+	if err := validateAddressee(msg.Addressee); err != nil {
+		return "", err
+	}
+
+	header := fmt.Sprintf(":%-9s:", msg.Addressee)
+
+	switch msg.Type {
+	case MessageAck, MessageReject:
+		if err := validateMessageID(msg.ID); err != nil {
+			return "", err
+		}
+		if msg.Type == MessageAck {
+			return header + "ack" + msg.ID, nil
+		}
+		return header + "rej" + msg.ID, nil
+	}
+
+	if len(msg.Text) > MaxMessageTextLen {
+		return "", fmt.Errorf("消息文本超过%d个字符", MaxMessageTextLen)
+	}
+	if !isPrintable(msg.Text) || strings.ContainsAny(msg.Text, "|~{") {
+		return "", fmt.Errorf("消息文本包含无效字符")
+	}
+
+	info := header + msg.Text
+	if msg.ID != "" {
+		if err := validateMessageID(msg.ID); err != nil {
+			return "", err
+		}
+		info += "{" + msg.ID
+		if msg.ReplyAck != "" {
+			info += "}" + msg.ReplyAck
+		}
+	}
+	return info, nil
+}
+
+// EncodeTelemetry 编码 T#seq,a1,...,a5,bbbbbbbb 格式的遥测报文
+func EncodeTelemetry(t *Telemetry, comment string) (string, error) {
+	seq := t.Sequence
+	if n, err := strconv.Atoi(seq); err == nil {
+		if n < 0 || n > 999 {
+			return "", fmt.Errorf("遥测序号超出范围: %d", n)
+		}
+		seq = fmt.Sprintf("%03d", n)
+	}
+	if seq == "" || strings.ContainsAny(seq, ",") {
+		return "", fmt.Errorf("遥测序号无效: %q", t.Sequence)
+	}
+	if len(t.Analog) > 5 {
+		return "", fmt.Errorf("遥测模拟通道最多5个")
+	}
+
+	fields := []string{"T#" + seq}
+	for i := 0; i < 5; i++ {
+		value := 0.0
+		if i < len(t.Analog) {
+			value = t.Analog[i]
+		}
+		fields = append(fields, formatTelemetryValue(value))
+	}
+
+	digital := t.Digital
+	if digital == "" {
+		digital = "00000000"
+	}
+	if len(digital) != 8 || strings.Trim(digital, "01") != "" {
+		return "", fmt.Errorf("遥测数字通道必须为8个0或1: %q", digital)
+	}
+	fields = append(fields, digital+comment)
+
+	return strings.Join(fields, ","), nil
+}
+
+// EncodeTelemetryDefinition 编码 PARM/UNIT/EQNS/BITS 遥测参数定义消息
+// 消息发往def.Station，即遥测数据的发送站
+func EncodeTelemetryDefinition(def *TelemetryDefinition) (string, error) {
+	if err := validateAddressee(def.Station); err != nil {
+		return "", err
+	}
+
+	var body string
+	switch def.Kind {
+	case "PARM", "UNIT":
+		if len(def.Names) > 13 {
+			return "", fmt.Errorf("%s 最多13个字段", def.Kind)
+		}
+		for _, name := range def.Names {
+			if strings.ContainsAny(name, ",") || !isPrintable(name) {
+				return "", fmt.Errorf("%s 字段包含无效字符: %q", def.Kind, name)
+			}
+		}
+		body = strings.Join(def.Names, ",")
+	case "EQNS":
+		if len(def.Coefficients) > 5 {
+			return "", fmt.Errorf("EQNS 最多5组系数")
+		}
+		values := make([]string, 0, len(def.Coefficients)*3)
+		for _, coef := range def.Coefficients {
+			for _, v := range coef {
+				values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		body = strings.Join(values, ",")
+	case "BITS":
+		if len(def.Bits) != 8 || strings.Trim(def.Bits, "01") != "" {
+			return "", fmt.Errorf("BITS 必须为8个0或1: %q", def.Bits)
+		}
+		body = def.Bits
+		if def.ProjectTitle != "" {
+			body += "," + def.ProjectTitle
+		}
+	default:
+		return "", fmt.Errorf("未知的遥测定义类型: %q", def.Kind)
+	}
+
+	info := fmt.Sprintf(":%-9s:%s.%s", def.Station, def.Kind, body)
+	if len(info)-11 > MaxMessageTextLen {
+		return "", fmt.Errorf("遥测定义超过%d个字符", MaxMessageTextLen)
+	}
+	return info, nil
+}
+
+// EncodeMicE 编码Mic-E报文，返回目的地址和信息字段
+// mice为nil时使用 "Off Duty" 消息
+func EncodeMicE(pos *Position, mice *MicE, comment string) (string, string, error) {
+	if err := validatePosition(pos); err != nil {
+		return "", "", err
+	}
+
+	code := 7
+	custom := false
+	if mice != nil {
+		code, custom = mice.Code, mice.Custom
+	}
+	if code < 0 || code > 7 || (custom && code == 0) {
+		return "", "", fmt.Errorf("无效的Mic-E消息代码: %d", code)
+	}
+
+	// 纬度数字 DDMMHH，模糊位置从末尾替换为空格
+	latHundredths := int(math.Round(math.Abs(pos.Latitude) * 6000))
+	latDigits := []byte(fmt.Sprintf("%02d%02d%02d", latHundredths/6000, latHundredths%6000/100, latHundredths%100))
+	for i := 0; i < pos.Ambiguity && i < 4; i++ {
+		latDigits[5-i] = ' '
+	}
+
+	lonHundredths := int(math.Round(math.Abs(pos.Longitude) * 6000))
+	lonDeg := lonHundredths / 6000
+	lonMin := lonHundredths % 6000 / 100
+	lonHun := lonHundredths % 100
+	if lonDeg > 179 {
+		return "", "", fmt.Errorf("经度超出Mic-E范围: %f", pos.Longitude)
+	}
+
+	flags := [6]bool{
+		code&4 != 0,
+		code&2 != 0,
+		code&1 != 0,
+		pos.Latitude >= 0,
+		lonDeg < 10 || lonDeg >= 100,
+		pos.Longitude < 0,
+	}
+
+	dest := make([]byte, 6)
+	for i, d := range latDigits {
+		switch {
+		case !flags[i] && d == ' ':
+			dest[i] = 'L'
+		case !flags[i]:
+			dest[i] = d
+		case i < 3 && custom && d == ' ':
+			dest[i] = 'K'
+		case i < 3 && custom:
+			dest[i] = 'A' + d - '0'
+		case d == ' ':
+			dest[i] = 'Z'
+		default:
+			dest[i] = 'P' + d - '0'
+		}
+	}
+
+	// 经度度数编码，见APRS规范第10章
+	var degChar int
+	switch {
+	case lonDeg < 10:
+		degChar = lonDeg + 90
+	case lonDeg < 100:
+		degChar = lonDeg
+	case lonDeg < 110:
+		degChar = lonDeg - 20
+	default:
+		degChar = lonDeg - 100
+	}
+	minChar := lonMin
+	if minChar < 10 {
+		minChar += 60
+	}
+
+	// 速度 (节) 和航向
+	speed, course := 0, 0
+	if pos.HasCourseSpeed {
+		speed = int(math.Round(pos.Speed / knotsToKmh))
+		course = pos.Course % 360
+	}
+	if speed > 799 {
+		speed = 799
+	}
+	sp := speed / 10
+	if sp < 4 {
+		sp += 80
+	}
+	dc := speed%10*10 + course/100
+	if dc < 4 {
+		dc += 4
+	}
+	se := course % 100
+
+	if !isSymbolTable(pos.SymbolTable) {
+		return "", "", fmt.Errorf("无效的符号表标识: %q", pos.SymbolTable)
+	}
+
+	info := []byte{'`',
+		byte(degChar + 28), byte(minChar + 28), byte(lonHun + 28),
+		byte(sp + 28), byte(dc + 28), byte(se + 28),
+		pos.SymbolCode, pos.SymbolTable,
+	}
+
+	if pos.HasAltitude {
+		alt := int(math.Round(pos.Altitude)) + 10000
+		if alt < 0 || alt >= 91*91*91 {
+			return "", "", fmt.Errorf("海拔超出Mic-E范围: %.0f", pos.Altitude)
+		}
+		info = append(info, encodeBase91(alt, 3)...)
+		info = append(info, '}')
+	}
+	info = append(info, comment...)
+
+	return string(dest), string(info), nil
+}
+
+// encodePositionData 编码位置（含数据扩展、海拔）和注释
+func encodePositionData(pos *Position, comment string) (string, error) {
+	if err := validatePosition(pos); err != nil {
+		return "", err
+	}
+	if pos.Compressed {
+		return encodeCompressedPosition(pos, comment)
+	}
+	return encodeUncompressedPosition(pos, comment)
+}
+
+// encodeUncompressedPosition 编码 DDMM.HHN/DDDMM.HHW$ 格式
+// 数据扩展按航向速度、PHG、RNG的顺序取第一个
+func encodeUncompressedPosition(pos *Position, comment string) (string, error) {
+	if !isSymbolTable(pos.SymbolTable) {
+		return "", fmt.Errorf("无效的符号表标识: %q", pos.SymbolTable)
+	}
+
+	lat := []byte(formatCoordinate(pos.Latitude, 2, 'N', 'S'))
+	lon := []byte(formatCoordinate(pos.Longitude, 3, 'E', 'W'))
+	applyAmbiguity(lat, pos.Ambiguity)
+	applyAmbiguity(lon, pos.Ambiguity)
+
+	var sb strings.Builder
+	sb.Write(lat)
+	sb.WriteByte(pos.SymbolTable)
+	sb.Write(lon)
+	sb.WriteByte(pos.SymbolCode)
+
+	switch {
+	case pos.HasCourseSpeed:
+		speed := int(math.Round(pos.Speed / knotsToKmh))
+		if speed > 999 {
+			speed = 999
+		}
+		fmt.Fprintf(&sb, "%03d/%03d", pos.Course%361, speed)
+	case pos.PHG != nil:
+		sb.WriteString("PHG" + encodePHG(pos.PHG))
+	case pos.Range > 0:
+		miles := int(math.Round(pos.Range / milesToKm))
+		if miles > 9999 {
+			miles = 9999
+		}
+		fmt.Fprintf(&sb, "RNG%04d", miles)
+	}
+
+	sb.WriteString(comment)
+	if pos.HasAltitude {
+		sb.WriteString(encodeAltitude(pos.Altitude))
+	}
+	return sb.String(), nil
+}
+
+// encodeCompressedPosition 编码Base91压缩位置
+// cs字节按航向速度、海拔、范围的顺序取第一个，海拔未能放入cs时追加到注释
+func encodeCompressedPosition(pos *Position, comment string) (string, error) {
+	table := pos.SymbolTable
+	if !isSymbolTable(table) {
+		return "", fmt.Errorf("无效的符号表标识: %q", table)
+	}
+	// 压缩格式中的叠加数字用 a-j 表示
+	if isDigit(table) {
+		table = 'a' + (table - '0')
+	}
+
+	y := int(380926 * (90 - pos.Latitude))
+	x := int(190463 * (180 + pos.Longitude))
+
+	var sb strings.Builder
+	sb.WriteByte(table)
+	sb.Write(encodeBase91(y, 4))
+	sb.Write(encodeBase91(x, 4))
+	sb.WriteByte(pos.SymbolCode)
+
+	altitudeInComment := pos.HasAltitude
+	switch {
+	case pos.HasCourseSpeed:
+		knots := pos.Speed / knotsToKmh
+		s := int(math.Round(math.Log(knots+1) / math.Log(1.08)))
+		if s > 89 {
+			s = 89
+		}
+		sb.WriteByte(byte(pos.Course%360/4) + 33)
+		sb.WriteByte(byte(s) + 33)
+		sb.WriteByte(compressionTypeRMC + 33)
+	case pos.HasAltitude && pos.Altitude/feetToMeter >= 1:
+		v := int(math.Round(math.Log(pos.Altitude/feetToMeter) / math.Log(1.002)))
+		sb.Write(encodeBase91(v, 2))
+		sb.WriteByte(compressionTypeGGA + 33)
+		altitudeInComment = false
+	case pos.Range > 0:
+		miles := pos.Range / milesToKm
+		s := int(math.Round(math.Log(miles/2) / math.Log(1.08)))
+		if s < 0 {
+			s = 0
+		} else if s > 89 {
+			s = 89
+		}
+		sb.WriteByte('{')
+		sb.WriteByte(byte(s) + 33)
+		sb.WriteByte(compressionTypeRMC + 33)
+	default:
+		sb.WriteString(" sT")
+	}
+
+	sb.WriteString(comment)
+	if altitudeInComment {
+		sb.WriteString(encodeAltitude(pos.Altitude))
+	}
+	return sb.String(), nil
+}
+
+// formatCoordinate 格式化为 DDMM.HHN 或 DDDMM.HHW
+func formatCoordinate(value float64, degDigits int, positive, negative byte) string {
+	hemi := positive
+	if value < 0 {
+		hemi = negative
+	}
+	// 先换算为百分之一分再取整，避免出现 60.00 分
+	hundredths := int(math.Round(math.Abs(value) * 6000))
+	return fmt.Sprintf("%0*d%02d.%02d%c", degDigits, hundredths/6000, hundredths%6000/100, hundredths%100, hemi)
+}
+
+// applyAmbiguity 将坐标末尾的数字替换为空格
+func applyAmbiguity(coord []byte, ambiguity int) {
+	// 坐标最后3个字节为 "HH" + 半球标识，点号前为分
+	n := len(coord)
+	positions := []int{n - 2, n - 3, n - 5, n - 6}
+	for i := 0; i < ambiguity && i < len(positions); i++ {
+		coord[positions[i]] = ' '
+	}
+}
+
+// encodePHG 编码PHG扩展的4个数字
+func encodePHG(phg *PHG) string {
+	p := int(math.Round(math.Sqrt(float64(phg.Power))))
+	h := 0
+	if phg.Height > 10 {
+		h = int(math.Round(math.Log2(float64(phg.Height) / 10)))
+	}
+	d := phg.Directivity / 45 % 9
+	return fmt.Sprintf("%d%d%d%d", clampDigit(p), clampDigit(h), clampDigit(phg.Gain), clampDigit(d))
+}
+
+// encodeAltitude 编码 /A=aaaaaa 海拔 (英尺)
+func encodeAltitude(meters float64) string {
+	feet := int(math.Round(meters / feetToMeter))
+	if feet > 999999 {
+		feet = 999999
+	} else if feet < -99999 {
+		feet = -99999
+	}
+	return fmt.Sprintf("/A=%06d", feet)
+}
+
+// encodeTimestamp 编码 DDHHMMz 格式的UTC时间戳
+func encodeTimestamp(t time.Time) string {
+	return t.UTC().Format("021504") + "z"
+}
+
+// encodeBase91 将数值编码为指定长度的Base91字符
+func encodeBase91(value, length int) []byte {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = byte(value%91) + 33
+		value /= 91
+	}
+	return out
+}
+
+// formatTelemetryValue 格式化遥测模拟量，整数值不带小数
+func formatTelemetryValue(v float64) string {
+	if v == math.Trunc(v) && v >= 0 && v <= 999 {
+		return fmt.Sprintf("%03d", int(v))
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// validatePosition 检查位置是否有效
+func validatePosition(pos *Position) error {
+	if pos == nil {
+		return fmt.Errorf("缺少位置数据")
+	}
+	if math.IsNaN(pos.Latitude) || pos.Latitude < -90 || pos.Latitude > 90 {
+		return fmt.Errorf("纬度超出范围: %f", pos.Latitude)
+	}
+	if math.IsNaN(pos.Longitude) || pos.Longitude < -180 || pos.Longitude > 180 {
+		return fmt.Errorf("经度超出范围: %f", pos.Longitude)
+	}
+	if pos.Ambiguity < 0 || pos.Ambiguity > 4 {
+		return fmt.Errorf("模糊级别超出范围: %d", pos.Ambiguity)
+	}
+	if pos.SymbolCode < '!' || pos.SymbolCode > '~' {
+		return fmt.Errorf("无效的符号代码: %q", pos.SymbolCode)
+	}
+	return nil
+}
+
+// validateAddressee 检查消息收件人
+func validateAddressee(addressee string) error {
+	if addressee == "" || len(addressee) > MaxAddresseeLen {
+		return fmt.Errorf("消息收件人长度必须为1-%d个字符: %q", MaxAddresseeLen, addressee)
+	}
+	if !isPrintable(addressee) || strings.ContainsAny(addressee, ":") {
+		return fmt.Errorf("消息收件人包含无效字符: %q", addressee)
+	}
+	return nil
+}
+
+// validateMessageID 检查消息编号 (1-5个字母数字)
+func validateMessageID(id string) error {
+	if id == "" || len(id) > MaxMessageIDLen {
+		return fmt.Errorf("消息编号长度必须为1-%d个字符: %q", MaxMessageIDLen, id)
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !isDigit(c) && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') {
+			return fmt.Errorf("消息编号只能包含字母和数字: %q", id)
+		}
+	}
+	return nil
+}
+
+// isPrintable 检查是否只包含可打印ASCII字符
+func isPrintable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func clampDigit(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 9 {
+		return 9
+	}
+	return v
+}
@@ -0,0 +1,247 @@
+package aprs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodePosition(t *testing.T) {
+	pos := &Position{
+		Latitude:    49.058333,
+		Longitude:   -72.029167,
+		SymbolTable: '/',
+		SymbolCode:  '-',
+	}
+
+	tests := []struct {
+		name      string
+		mutate    func(p *Position)
+		timestamp time.Time
+		messaging bool
+		comment   string
+		want      string
+	}{
+		{"基本位置", nil, time.Time{}, false, "Test", "!4903.50N/07201.75W-Test"},
+		{"支持消息", nil, time.Time{}, true, "", "=4903.50N/07201.75W-"},
+		{"时间戳", nil, time.Date(2026, 10, 9, 23, 45, 0, 0, time.UTC), true, "", "@092345z4903.50N/07201.75W-"},
+		{"航向速度和海拔", func(p *Position) {
+			p.HasCourseSpeed, p.Course, p.Speed = true, 88, 36*knotsToKmh
+			p.HasAltitude, p.Altitude = true, 1234*feetToMeter
+		}, time.Time{}, false, "hi", "!4903.50N/07201.75W-088/036hi/A=001234"},
+		{"PHG", func(p *Position) {
+			p.PHG = &PHG{Power: 25, Height: 20, Gain: 3, Directivity: 90}
+		}, time.Time{}, false, "", "!4903.50N/07201.75W-PHG5132"},
+		{"模糊位置", func(p *Position) { p.Ambiguity = 2 }, time.Time{}, false, "", "!4903.  N/07201.  W-"},
+		{"压缩", func(p *Position) {
+			p.Latitude, p.Longitude, p.SymbolCode, p.Compressed = 49.5, -72.75, '>', true
+			p.HasCourseSpeed, p.Course, p.Speed = true, 88, 36.2*knotsToKmh
+		}, time.Time{}, true, "", "=/5L!!<*e7>7P["},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := *pos
+			if tt.mutate != nil {
+				tt.mutate(&p)
+			}
+			got, err := EncodePosition(&p, tt.timestamp, tt.messaging, tt.comment)
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("EncodePosition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodePositionRoundTrip(t *testing.T) {
+	positions := []*Position{
+		{Latitude: 35.5966, Longitude: 139.752, SymbolTable: '/', SymbolCode: '>', HasCourseSpeed: true, Course: 251, Speed: 50},
+		{Latitude: -33.86, Longitude: 151.21, SymbolTable: '\\', SymbolCode: 'k', Compressed: true, HasAltitude: true, Altitude: 500},
+		{Latitude: 0.5, Longitude: -0.5, SymbolTable: '2', SymbolCode: '#', Compressed: true, Range: 20},
+		{Latitude: 51.5, Longitude: -0.12, SymbolTable: '/', SymbolCode: '-', Compressed: true, HasCourseSpeed: true, Course: 92, Speed: 100, HasAltitude: true, Altitude: 120},
+	}
+
+	for _, pos := range positions {
+		info, err := EncodePosition(pos, time.Time{}, false, "rt")
+		if err != nil {
+			t.Fatalf("编码失败: %v", err)
+		}
+		packet, err := ParseInfo("N0CALL", "APRS", nil, info)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", info, err)
+		}
+
+		got := packet.Position
+		if !approx(got.Latitude, pos.Latitude, 1e-4) || !approx(got.Longitude, pos.Longitude, 1e-4) {
+			t.Errorf("%q 位置 = %.5f,%.5f, want %.5f,%.5f", info, got.Latitude, got.Longitude, pos.Latitude, pos.Longitude)
+		}
+		if got.SymbolTable != pos.SymbolTable || got.SymbolCode != pos.SymbolCode {
+			t.Errorf("%q 符号 = %c%c", info, got.SymbolTable, got.SymbolCode)
+		}
+		if pos.HasCourseSpeed && (got.Course != pos.Course || !approx(got.Speed, pos.Speed, pos.Speed*0.05)) {
+			t.Errorf("%q 航向速度 = %d %.1f", info, got.Course, got.Speed)
+		}
+		if pos.HasAltitude && !approx(got.Altitude, pos.Altitude, pos.Altitude*0.01) {
+			t.Errorf("%q 海拔 = %.1f", info, got.Altitude)
+		}
+		if pos.Range > 0 && !approx(got.Range, pos.Range, pos.Range*0.05) {
+			t.Errorf("%q 范围 = %.1f", info, got.Range)
+		}
+		if packet.Comment != "rt" {
+			t.Errorf("%q 注释 = %q", info, packet.Comment)
+		}
+	}
+}
+
+func TestEncodeMicE(t *testing.T) {
+	pos := &Position{
+		Latitude:       35.596667,
+		Longitude:      139.752,
+		SymbolTable:    '/',
+		SymbolCode:     '>',
+		HasCourseSpeed: true,
+		Course:         251,
+		Speed:          20 * knotsToKmh,
+		HasAltitude:    true,
+		Altitude:       61,
+	}
+
+	dest, info, err := EncodeMicE(pos, &MicE{Code: 6}, "test")
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if dest != "SU3UX0" || info != "`CI(n\"O>/\"4T}test" {
+		t.Errorf("EncodeMicE() = %q %q", dest, info)
+	}
+
+	// 各经度区间和速度航向的编解码
+	cases := []struct {
+		lat, lon      float64
+		course, speed int
+		mice          MicE
+	}{
+		{10.01, 5.05, 0, 0, MicE{Code: 0}},
+		{-45.5, -105.25, 360, 5, MicE{Code: 7}},
+		{60.123, 175.999, 99, 120, MicE{Code: 2, Custom: true}},
+		{1.5, -99.9, 359, 799, MicE{Code: 5}},
+	}
+	for _, c := range cases {
+		p := &Position{Latitude: c.lat, Longitude: c.lon, SymbolTable: '/', SymbolCode: '[',
+			HasCourseSpeed: true, Course: c.course, Speed: float64(c.speed) * knotsToKmh}
+		dest, info, err := EncodeMicE(p, &c.mice, "")
+		if err != nil {
+			t.Fatalf("编码失败: %v", err)
+		}
+		packet, err := ParseInfo("N0CALL", dest, nil, info)
+		if err != nil {
+			t.Fatalf("解析 %s %q 失败: %v", dest, info, err)
+		}
+		got := packet.Position
+		if !approx(got.Latitude, c.lat, 1e-3) || !approx(got.Longitude, c.lon, 1e-3) {
+			t.Errorf("%s 位置 = %.4f,%.4f, want %.4f,%.4f", dest, got.Latitude, got.Longitude, c.lat, c.lon)
+		}
+		if got.Course != c.course%360 || !approx(got.Speed, float64(c.speed)*knotsToKmh, 1e-6) {
+			t.Errorf("%s 航向速度 = %d %.1f", dest, got.Course, got.Speed)
+		}
+		if packet.MicE.Code != c.mice.Code || packet.MicE.Custom != c.mice.Custom {
+			t.Errorf("%s 消息 = %+v, want %+v", dest, packet.MicE, c.mice)
+		}
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	tests := []struct {
+		msg     Message
+		want    string
+		wantErr bool
+	}{
+		{Message{Addressee: "WU2Z", Text: "Testing", ID: "003"}, ":WU2Z     :Testing{003", false},
+		{Message{Addressee: "WU2Z", Text: "No id"}, ":WU2Z     :No id", false},
+		{Message{Addressee: "KB2ICI-14", ID: "003", Type: MessageAck}, ":KB2ICI-14:ack003", false},
+		{Message{Addressee: "KB2ICI-14", ID: "003", Type: MessageReject}, ":KB2ICI-14:rej003", false},
+		{Message{Addressee: "N0CALL", Text: "hi", ID: "MM", ReplyAck: "AA"}, ":N0CALL   :hi{MM}AA", false},
+		{Message{Addressee: "TOOLONGCALL", Text: "x"}, "", true},
+		{Message{Addressee: "N0CALL", Text: "bad{brace"}, "", true},
+		{Message{Addressee: "N0CALL", Text: "x", ID: "123456"}, "", true},
+		{Message{Addressee: "N0CALL", Type: MessageAck}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := EncodeMessage(&tt.msg)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("EncodeMessage(%+v) error = %v, wantErr %v", tt.msg, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("EncodeMessage(%+v) = %q, want %q", tt.msg, got, tt.want)
+		}
+		if err == nil {
+			packet, err := ParseInfo("N0CALL", "APRS", nil, got)
+			if err != nil || *packet.Message != tt.msg {
+				t.Errorf("往返解析不一致: %v %+v", err, packet)
+			}
+		}
+	}
+}
+
+func TestEncodeObjectItem(t *testing.T) {
+	pos := &Position{Latitude: 49.058333, Longitude: -72.029167, SymbolTable: '/', SymbolCode: '>'}
+	ts := time.Date(2026, 10, 9, 23, 45, 0, 0, time.UTC)
+
+	got, err := EncodeObject(&Object{Name: "LEADER", Live: true}, pos, ts, "x")
+	if err != nil || got != ";LEADER   *092345z4903.50N/07201.75W>x" {
+		t.Errorf("EncodeObject() = %q, %v", got, err)
+	}
+
+	got, err = EncodeObject(&Object{Name: "LEADER"}, pos, ts, "")
+	if err != nil || got != ";LEADER   _092345z4903.50N/07201.75W>" {
+		t.Errorf("删除对象 = %q, %v", got, err)
+	}
+	if _, err := EncodeObject(&Object{Name: "NAMETOOLONG"}, pos, ts, ""); err == nil {
+		t.Errorf("期望对象名称过长时失败")
+	}
+
+	got, err = EncodeItem(&Item{Name: "AID #2", Live: true}, pos, "")
+	if err != nil || got != ")AID #2!4903.50N/07201.75W>" {
+		t.Errorf("EncodeItem() = %q, %v", got, err)
+	}
+	got, err = EncodeItem(&Item{Name: "AID #2"}, pos, "")
+	if err != nil || got != ")AID #2_4903.50N/07201.75W>" {
+		t.Errorf("删除条目 = %q, %v", got, err)
+	}
+	if _, err := EncodeItem(&Item{Name: "AB"}, pos, ""); err == nil {
+		t.Errorf("期望条目名称过短时失败")
+	}
+}
+
+func TestEncodeTelemetry(t *testing.T) {
+	got, err := EncodeTelemetry(&Telemetry{Sequence: "5", Analog: []float64{199, 0, 255, 73, 12.5}, Digital: "01101001"}, "hi")
+	if err != nil || got != "T#005,199,000,255,073,12.5,01101001hi" {
+		t.Errorf("EncodeTelemetry() = %q, %v", got, err)
+	}
+	if _, err := EncodeTelemetry(&Telemetry{Sequence: "1", Digital: "012"}, ""); err == nil {
+		t.Errorf("期望数字通道格式错误时失败")
+	}
+
+	defs := []struct {
+		def  TelemetryDefinition
+		want string
+	}{
+		{TelemetryDefinition{Station: "N0QBF-11", Kind: "PARM", Names: []string{"Battery", "Btemp"}}, ":N0QBF-11 :PARM.Battery,Btemp"},
+		{TelemetryDefinition{Station: "N0QBF-11", Kind: "UNIT", Names: []string{"v/100", "deg.F"}}, ":N0QBF-11 :UNIT.v/100,deg.F"},
+		{TelemetryDefinition{Station: "N0QBF-11", Kind: "EQNS", Coefficients: [][3]float64{{0, 5.2, 0}, {0, 0.53, -32}}}, ":N0QBF-11 :EQNS.0,5.2,0,0,0.53,-32"},
+		{TelemetryDefinition{Station: "N0QBF-11", Kind: "BITS", Bits: "10110000", ProjectTitle: "Balloon"}, ":N0QBF-11 :BITS.10110000,Balloon"},
+	}
+	for _, tt := range defs {
+		got, err := EncodeTelemetryDefinition(&tt.def)
+		if err != nil || got != tt.want {
+			t.Errorf("EncodeTelemetryDefinition(%s) = %q, %v", tt.def.Kind, got, err)
+			continue
+		}
+		packet, err := ParseInfo("N0CALL", "APRS", nil, got)
+		if err != nil || packet.TelemetryDefinition.Kind != tt.def.Kind {
+			t.Errorf("往返解析失败: %v", err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package aprs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MessageType 消息类型
+type MessageType int
+
+const (
+	MessageText     MessageType = iota // 普通消息
+	MessageAck                         // 确认
+	MessageReject                      // 拒绝
+	MessageBulletin                    // 公告 (BLNn)
+)
+
+// Message APRS消息
+type Message struct {
+	Addressee string // 收件人，已去除填充空格
+	Text      string
+	ID        string // 消息编号，无编号时为空
+	Type      MessageType
+	ReplyAck  string // 回复确认编号 ({MM}AA 格式中的 AA)
+}
+
+// TelemetryDefinition 以消息形式发送的遥测参数定义
+type TelemetryDefinition struct {
+	Station      string       // 定义所属的站点（即消息收件人）
+	Kind         string       // PARM、UNIT、EQNS 或 BITS
+	Names        []string     // PARM/UNIT 的各通道名称或单位
+	Coefficients [][3]float64 // EQNS 的各模拟通道系数 a,b,c (值 = a*x² + b*x + c)
+	Bits         string       // BITS 的8个有效位
+	ProjectTitle string       // BITS 附带的项目名称
+}
+
+// parseMessage 解析 :ADDRESSEE:text{id 格式的消息
+func parseMessage(packet *Packet, body string) error {
+	if len(body) < 10 || body[9] != ':' {
+		return newParseError(packet.DataType, "消息收件人格式错误")
+	}
+
+	addressee := strings.TrimSpace(body[:9])
+	if addressee == "" {
+		return newParseError(packet.DataType, "消息收件人为空")
+	}
+	text := body[10:]
+
+	// 遥测参数定义
+	for _, kind := range []string{"PARM.", "UNIT.", "EQNS.", "BITS."} {
+		if strings.HasPrefix(text, kind) {
+			def, err := parseTelemetryDefinition(kind[:4], text[len(kind):])
+			if err != nil {
+				return newParseError(packet.DataType, "%v", err)
+			}
+			def.Station = addressee
+			packet.Type = TypeTelemetry
+			packet.TelemetryDefinition = def
+			return nil
+		}
+	}
+
+	msg := &Message{Addressee: addressee}
+
+	switch {
+	case strings.HasPrefix(text, "ack") && len(text) > 3 && len(text) <= 8:
+		msg.Type = MessageAck
+		msg.ID = strings.TrimSpace(text[3:])
+	case strings.HasPrefix(text, "rej") && len(text) > 3 && len(text) <= 8:
+		msg.Type = MessageReject
+		msg.ID = strings.TrimSpace(text[3:])
+	default:
+		if strings.HasPrefix(addressee, "BLN") {
+			msg.Type = MessageBulletin
+		}
+		if idx := strings.LastIndexByte(text, '{'); idx >= 0 {
+			id := strings.TrimRight(text[idx+1:], "\r\n ")
+			// 回复确认格式 {MM}AA
+			if brace := strings.IndexByte(id, '}'); brace >= 0 {
+				msg.ReplyAck = id[brace+1:]
+				id = id[:brace]
+			}
+			if len(id) <= 5 {
+				msg.ID = id
+				text = text[:idx]
+			}
+		}
+		msg.Text = text
+	}
+
+	packet.Type = TypeMessage
+	packet.Message = msg
+	return nil
+}
+
+// parseTelemetryDefinition 解析遥测参数定义消息的内容
+func parseTelemetryDefinition(kind, s string) (*TelemetryDefinition, error) {
+	def := &TelemetryDefinition{Kind: kind}
+	fields := strings.Split(s, ",")
+
+	switch kind {
+	case "PARM", "UNIT":
+		if len(fields) > 13 {
+			return nil, fmt.Errorf("%s 字段过多", kind)
+		}
+		def.Names = fields
+	case "EQNS":
+		if len(fields)%3 != 0 || len(fields) > 15 {
+			return nil, fmt.Errorf("EQNS 系数数量错误")
+		}
+		for i := 0; i < len(fields); i += 3 {
+			var coef [3]float64
+			for j := 0; j < 3; j++ {
+				v, err := strconv.ParseFloat(strings.TrimSpace(fields[i+j]), 64)
+				if err != nil {
+					return nil, fmt.Errorf("EQNS 系数格式错误: %q", fields[i+j])
+				}
+				coef[j] = v
+			}
+			def.Coefficients = append(def.Coefficients, coef)
+		}
+	case "BITS":
+		if len(s) < 8 || strings.Trim(s[:8], "01") != "" {
+			return nil, fmt.Errorf("BITS 格式错误")
+		}
+		def.Bits = s[:8]
+		if len(s) > 8 && s[8] == ',' {
+			def.ProjectTitle = s[9:]
+		}
+	}
+
+	return def, nil
+}
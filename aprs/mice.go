@@ -0,0 +1,195 @@
+package aprs
+
+import "strings"
+
+// Mic-E标准消息名称，按消息位 (A/B/C) 组成的值索引
+var micEMessages = [8]string{
+	"Emergency",  // 000
+	"Priority",   // 001
+	"Special",    // 010
+	"Committed",  // 011
+	"Returning",  // 100
+	"In Service", // 101
+	"En Route",   // 110
+	"Off Duty",   // 111
+}
+
+// MicE Mic-E报文的附加信息
+type MicE struct {
+	Message string // 消息名称，如 "En Route"
+	Custom  bool   // 是否为自定义消息 (C0-C6)
+	Code    int    // 消息位组成的值 (0-7)
+}
+
+// parseMicE 解析Mic-E报文，纬度和消息位编码在目的地址中
+func parseMicE(packet *Packet, body string) error {
+	dest := packet.Dest
+	if idx := strings.IndexByte(dest, '-'); idx >= 0 {
+		dest = dest[:idx]
+	}
+	if len(dest) != 6 {
+		return newParseError(packet.DataType, "Mic-E目的地址长度错误: %q", packet.Dest)
+	}
+	if len(body) < 8 {
+		return newParseError(packet.DataType, "Mic-E信息字段长度不足")
+	}
+
+	// 目的地址的每个字符解码为一个纬度数字和一个标志位
+	var digits [6]byte
+	var flags [6]bool
+	ambiguity := 0
+	standard, custom := false, false
+	for i := 0; i < 6; i++ {
+		c := dest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits[i] = c
+		case c >= 'A' && c <= 'J':
+			digits[i] = '0' + c - 'A'
+			flags[i] = true
+			if i < 3 {
+				custom = true
+			}
+		case c >= 'P' && c <= 'Y':
+			digits[i] = '0' + c - 'P'
+			flags[i] = true
+			if i < 3 {
+				standard = true
+			}
+		case c == 'K':
+			digits[i] = ' '
+			flags[i] = true
+			if i < 3 {
+				custom = true
+			}
+		case c == 'L':
+			digits[i] = ' '
+		case c == 'Z':
+			digits[i] = ' '
+			flags[i] = true
+			if i < 3 {
+				standard = true
+			}
+		default:
+			return newParseError(packet.DataType, "Mic-E目的地址包含无效字符: %q", c)
+		}
+		if digits[i] == ' ' {
+			ambiguity++
+		} else if ambiguity > 0 {
+			return newParseError(packet.DataType, "Mic-E位置模糊格式错误")
+		}
+	}
+
+	latDigits, _, err := resolveAmbiguity(string(digits[:]))
+	if err != nil {
+		return newParseError(packet.DataType, "Mic-E纬度格式错误")
+	}
+	latDeg := int(latDigits[0]-'0')*10 + int(latDigits[1]-'0')
+	latMin := float64(int(latDigits[2]-'0')*10+int(latDigits[3]-'0')) +
+		float64(int(latDigits[4]-'0')*10+int(latDigits[5]-'0'))/100
+	if latDeg > 89 || latMin >= 60 {
+		return newParseError(packet.DataType, "Mic-E纬度超出范围")
+	}
+	lat := float64(latDeg) + (latMin+ambiguityOffset(ambiguity))/60
+	if !flags[3] {
+		lat = -lat
+	}
+
+	for i := 0; i < 8; i++ {
+		if body[i] < 28 || body[i] > 127 {
+			return newParseError(packet.DataType, "Mic-E信息字段包含无效字符")
+		}
+	}
+
+	// 经度度数，带100度偏移标志
+	lonDeg := int(body[0]) - 28
+	if flags[4] {
+		lonDeg += 100
+	}
+	if lonDeg >= 180 && lonDeg <= 189 {
+		lonDeg -= 80
+	} else if lonDeg >= 190 && lonDeg <= 199 {
+		lonDeg -= 190
+	}
+	lonMin := int(body[1]) - 28
+	if lonMin >= 60 {
+		lonMin -= 60
+	}
+	lonHundredths := int(body[2]) - 28
+	if lonDeg > 179 || lonHundredths > 99 {
+		return newParseError(packet.DataType, "Mic-E经度超出范围")
+	}
+	lon := float64(lonDeg) + (float64(lonMin)+float64(lonHundredths)/100+ambiguityOffset(ambiguity))/60
+	if flags[5] {
+		lon = -lon
+	}
+
+	// 速度和航向
+	sp := int(body[3]) - 28
+	dc := int(body[4]) - 28
+	se := int(body[5]) - 28
+	speed := sp*10 + dc/10
+	if speed >= 800 {
+		speed -= 800
+	}
+	course := (dc%10)*100 + se
+	if course >= 400 {
+		course -= 400
+	}
+
+	pos := &Position{
+		Latitude:       lat,
+		Longitude:      lon,
+		Ambiguity:      ambiguity,
+		SymbolCode:     body[6],
+		SymbolTable:    body[7],
+		HasCourseSpeed: true,
+		Course:         course,
+		Speed:          float64(speed) * knotsToKmh,
+	}
+	if !isSymbolTable(pos.SymbolTable) {
+		return newParseError(packet.DataType, "无效的符号表标识: %q", pos.SymbolTable)
+	}
+
+	code := 0
+	for i := 0; i < 3; i++ {
+		if flags[i] {
+			code |= 4 >> i
+		}
+	}
+	mice := &MicE{Code: code}
+	switch {
+	case standard && custom:
+		mice.Message = "Unknown"
+	case custom:
+		mice.Custom = true
+		mice.Message = "Custom-" + string(rune('0'+7-code))
+	default:
+		mice.Message = micEMessages[code]
+	}
+
+	packet.Type = TypeMicE
+	packet.Position = pos
+	packet.MicE = mice
+	packet.Comment = parseMicEComment(pos, body[8:])
+	return nil
+}
+
+// parseMicEComment 解析Mic-E状态文本中的海拔，去掉设备类型标识
+func parseMicEComment(pos *Position, comment string) string {
+	// 部分设备在开头加上类型标识字符
+	if len(comment) > 0 && (comment[0] == '>' || comment[0] == ']' || comment[0] == '`' || comment[0] == '\'') {
+		comment = comment[1:]
+	}
+
+	// 海拔为3个Base91字符加 "}"，以海平面以下10000米为基准
+	if idx := strings.IndexByte(comment, '}'); idx >= 3 {
+		if alt, err := decodeBase91(comment[idx-3 : idx]); err == nil {
+			pos.HasAltitude = true
+			pos.Altitude = float64(alt - 10000)
+			comment = comment[:idx-3] + comment[idx+1:]
+		}
+	}
+
+	return strings.TrimSpace(comment)
+}
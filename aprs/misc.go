@@ -0,0 +1,225 @@
+package aprs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Object APRS对象
+type Object struct {
+	Name string // 对象名称 (9字符，已去除填充空格)
+	Live bool   // true为有效对象，false为已删除
+}
+
+// Item APRS条目
+type Item struct {
+	Name string // 条目名称 (3-9字符)
+	Live bool   // true为有效条目，false为已删除
+}
+
+// parseStatus 解析状态报文，可带 DDHHMMz 时间戳
+func parseStatus(packet *Packet, body string) error {
+	if len(body) >= 7 && body[6] == 'z' && allDigits(body[:6]) {
+		ts, err := parseTimestamp(body[:7])
+		if err == nil {
+			packet.Timestamp = ts
+			body = body[7:]
+		}
+	}
+
+	packet.Type = TypeStatus
+	packet.Status = strings.TrimRight(body, "\r\n")
+	return nil
+}
+
+// parseObject 解析 ;NAME_____*DDHHMMzPOSITION 格式的对象报文
+func parseObject(packet *Packet, body string) error {
+	if len(body) < 10 {
+		return newParseError(packet.DataType, "对象报文长度不足")
+	}
+
+	object := &Object{Name: strings.TrimRight(body[:9], " ")}
+	switch body[9] {
+	case '*':
+		object.Live = true
+	case '_':
+		object.Live = false
+	default:
+		return newParseError(packet.DataType, "对象状态标识错误: %q", body[9])
+	}
+	if object.Name == "" {
+		return newParseError(packet.DataType, "对象名称为空")
+	}
+
+	if err := parsePositionPacket(packet, body[10:], true); err != nil {
+		return err
+	}
+	packet.Type = TypeObject
+	packet.Object = object
+	return nil
+}
+
+// parseItem 解析 )NAME!POSITION 格式的条目报文
+func parseItem(packet *Packet, body string) error {
+	end := strings.IndexAny(body, "!_")
+	if end < 3 || end > 9 {
+		return newParseError(packet.DataType, "条目名称格式错误")
+	}
+
+	item := &Item{Name: body[:end], Live: body[end] == '!'}
+	if err := parsePositionPacket(packet, body[end+1:], false); err != nil {
+		return err
+	}
+	packet.Type = TypeItem
+	packet.Item = item
+	return nil
+}
+
+// parseCapabilities 解析 <IGATE,MSG_CNT=1,LOC_CNT=5 格式的站点能力报文
+func parseCapabilities(packet *Packet, body string) error {
+	capabilities := make(map[string]string)
+	for _, token := range strings.Split(body, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(token, "=")
+		capabilities[key] = value
+	}
+	if len(capabilities) == 0 {
+		return newParseError(packet.DataType, "能力报文为空")
+	}
+
+	packet.Type = TypeCapabilities
+	packet.Capabilities = capabilities
+	return nil
+}
+
+// parseThirdParty 解析 }SRC>DEST,PATH:INFO 格式的第三方报文
+func parseThirdParty(packet *Packet, body string) error {
+	inner, err := ParseTNC2(body)
+	if err != nil {
+		return newParseError(packet.DataType, "第三方报文解析失败: %v", err)
+	}
+
+	packet.Type = TypeThirdParty
+	packet.ThirdParty = inner
+	return nil
+}
+
+// parseNMEA 解析原始NMEA语句 ($GPRMC、$GPGGA、$GPGLL)
+func parseNMEA(packet *Packet, sentence string) error {
+	sentence = strings.TrimRight(sentence, "\r\n ")
+
+	// 校验和可选，存在时必须正确
+	if star := strings.LastIndexByte(sentence, '*'); star >= 0 && star+3 == len(sentence) {
+		want, err := strconv.ParseUint(sentence[star+1:], 16, 8)
+		if err != nil {
+			return newParseError(packet.DataType, "NMEA校验和格式错误")
+		}
+		if got := nmeaChecksum(sentence[1:star]); got != byte(want) {
+			return newParseError(packet.DataType, "NMEA校验和错误: %02X != %02X", got, want)
+		}
+		sentence = sentence[:star]
+	}
+
+	fields := strings.Split(sentence, ",")
+	if len(fields[0]) != 6 {
+		return newParseError(packet.DataType, "NMEA语句类型错误: %q", fields[0])
+	}
+
+	pos := &Position{}
+	var err error
+	switch fields[0][3:] {
+	case "RMC":
+		// $GPRMC,hhmmss,A,ddmm.mm,N,dddmm.mm,W,speed,course,ddmmyy,...
+		if len(fields) < 10 || fields[2] != "A" {
+			return newParseError(packet.DataType, "RMC语句无有效定位")
+		}
+		pos.Latitude, pos.Longitude, err = parseNMEALatLon(fields[3:7])
+		if err == nil && fields[7] != "" && fields[8] != "" {
+			speed, err1 := strconv.ParseFloat(fields[7], 64)
+			course, err2 := strconv.ParseFloat(fields[8], 64)
+			if err1 == nil && err2 == nil {
+				pos.HasCourseSpeed = true
+				pos.Speed = speed * knotsToKmh
+				pos.Course = int(course + 0.5)
+				if pos.Course == 0 {
+					pos.Course = 360
+				}
+			}
+		}
+	case "GGA":
+		// $GPGGA,hhmmss,ddmm.mm,N,dddmm.mm,W,fix,sats,hdop,alt,M,...
+		if len(fields) < 10 || fields[6] == "" || fields[6] == "0" {
+			return newParseError(packet.DataType, "GGA语句无有效定位")
+		}
+		pos.Latitude, pos.Longitude, err = parseNMEALatLon(fields[2:6])
+		if err == nil && fields[9] != "" {
+			if alt, err := strconv.ParseFloat(fields[9], 64); err == nil {
+				pos.HasAltitude = true
+				pos.Altitude = alt
+			}
+		}
+	case "GLL":
+		// $GPGLL,ddmm.mm,N,dddmm.mm,W,hhmmss,A
+		if len(fields) < 5 || (len(fields) > 6 && fields[6] != "A") {
+			return newParseError(packet.DataType, "GLL语句无有效定位")
+		}
+		pos.Latitude, pos.Longitude, err = parseNMEALatLon(fields[1:5])
+	default:
+		return newParseError(packet.DataType, "不支持的NMEA语句: %q", fields[0])
+	}
+	if err != nil {
+		return newParseError(packet.DataType, "%v", err)
+	}
+
+	packet.Type = TypeNMEA
+	packet.Position = pos
+	return nil
+}
+
+// parseNMEALatLon 解析NMEA的 纬度,N/S,经度,E/W 四个字段
+func parseNMEALatLon(fields []string) (float64, float64, error) {
+	lat, err := parseNMEACoord(fields[0], fields[1], 2, "N", "S")
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err := parseNMEACoord(fields[2], fields[3], 3, "E", "W")
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// parseNMEACoord 解析 (d)ddmm.mmmm 格式的坐标
+func parseNMEACoord(value, hemi string, degDigits int, positive, negative string) (float64, error) {
+	if len(value) < degDigits+2 || !allDigits(value[:degDigits]) {
+		return 0, fmt.Errorf("NMEA坐标格式错误: %q", value)
+	}
+	deg, _ := strconv.Atoi(value[:degDigits])
+	min, err := strconv.ParseFloat(value[degDigits:], 64)
+	if err != nil || min >= 60 {
+		return 0, fmt.Errorf("NMEA坐标格式错误: %q", value)
+	}
+
+	coord := float64(deg) + min/60
+	switch hemi {
+	case positive:
+	case negative:
+		coord = -coord
+	default:
+		return 0, fmt.Errorf("NMEA半球标识错误: %q", hemi)
+	}
+	return coord, nil
+}
+
+// nmeaChecksum 计算NMEA校验和 ($ 与 * 之间所有字节的异或)
+func nmeaChecksum(s string) byte {
+	var sum byte
+	for i := 0; i < len(s); i++ {
+		sum ^= s[i]
+	}
+	return sum
+}
@@ -0,0 +1,208 @@
+package aprs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"aprs_agent/ax25"
+)
+
+// PacketType APRS数据包类型
+type PacketType int
+
+const (
+	TypeUnknown PacketType = iota
+	TypePosition
+	TypeMicE
+	TypeMessage
+	TypeObject
+	TypeItem
+	TypeStatus
+	TypeWeather
+	TypeTelemetry
+	TypeCapabilities
+	TypeThirdParty
+	TypeNMEA
+	TypeQuery
+)
+
+// String 返回数据包类型名称
+func (t PacketType) String() string {
+	switch t {
+	case TypePosition:
+		return "position"
+	case TypeMicE:
+		return "mic-e"
+	case TypeMessage:
+		return "message"
+	case TypeObject:
+		return "object"
+	case TypeItem:
+		return "item"
+	case TypeStatus:
+		return "status"
+	case TypeWeather:
+		return "weather"
+	case TypeTelemetry:
+		return "telemetry"
+	case TypeCapabilities:
+		return "capabilities"
+	case TypeThirdParty:
+		return "third-party"
+	case TypeNMEA:
+		return "nmea"
+	case TypeQuery:
+		return "query"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseError APRS信息字段解析错误
+type ParseError struct {
+	DataType byte   // 数据类型标识符
+	Reason   string // 失败原因
+}
+
+// Error 实现error接口
+func (e *ParseError) Error() string {
+	if e.DataType == 0 {
+		return fmt.Sprintf("APRS解析失败: %s", e.Reason)
+	}
+	return fmt.Sprintf("APRS解析失败 (%q): %s", e.DataType, e.Reason)
+}
+
+// newParseError 创建解析错误
+func newParseError(dataType byte, format string, args ...interface{}) *ParseError {
+	return &ParseError{DataType: dataType, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Packet 解析后的APRS数据包
+type Packet struct {
+	Source   string
+	Dest     string
+	Path     []string
+	Raw      string // 信息字段原文
+	DataType byte   // 数据类型标识符
+	Type     PacketType
+
+	Position  *Position
+	Timestamp time.Time // 报文携带的时间戳，未携带时为零值
+	Messaging bool      // 发送站是否支持APRS消息
+	Comment   string
+
+	MicE                *MicE
+	Message             *Message
+	TelemetryDefinition *TelemetryDefinition
+	Object              *Object
+	Item                *Item
+	Status              string
+	Weather             *Weather
+	Telemetry           *Telemetry
+	Capabilities        map[string]string
+	ThirdParty          *Packet
+	Query               string
+}
+
+// nowFunc 当前时间，测试时可替换
+var nowFunc = time.Now
+
+// Parse 解析AX.25 UI帧中的APRS信息字段
+func Parse(frame *ax25.Frame) (*Packet, error) {
+	if !frame.IsUI() {
+		return nil, newParseError(0, "不是UI帧")
+	}
+
+	path := make([]string, 0, len(frame.Path))
+	for _, digi := range frame.Path {
+		s := digi.String()
+		if digi.H {
+			s += "*"
+		}
+		path = append(path, s)
+	}
+
+	return ParseInfo(frame.Src.String(), frame.Dest.String(), path, string(frame.Info))
+}
+
+// ParseTNC2 解析TNC2文本格式的数据包，如 N0CALL>APRS,WIDE1-1:!4903.50N/07201.75W-
+// 与ax25.ParseTNC2不同，这里接受APRS-IS中的非AX.25路径（如 TCPIP*、qAR）
+func ParseTNC2(line string) (*Packet, error) {
+	line = strings.TrimRight(line, "\r\n")
+
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return nil, newParseError(0, "缺少信息字段分隔符")
+	}
+	header, info := line[:colon], line[colon+1:]
+
+	gt := strings.IndexByte(header, '>')
+	if gt <= 0 {
+		return nil, newParseError(0, "缺少源地址")
+	}
+
+	parts := strings.Split(header[gt+1:], ",")
+	if parts[0] == "" {
+		return nil, newParseError(0, "缺少目的地址")
+	}
+
+	return ParseInfo(header[:gt], parts[0], parts[1:], info)
+}
+
+// ParseInfo 根据源地址、目的地址、路径和信息字段解析数据包
+func ParseInfo(source, dest string, path []string, info string) (*Packet, error) {
+	packet := &Packet{
+		Source: source,
+		Dest:   dest,
+		Path:   path,
+		Raw:    info,
+	}
+
+	if len(info) == 0 {
+		return nil, newParseError(0, "信息字段为空")
+	}
+
+	packet.DataType = info[0]
+	body := info[1:]
+
+	var err error
+	switch packet.DataType {
+	case '!', '=':
+		packet.Messaging = packet.DataType == '='
+		err = parsePositionPacket(packet, body, false)
+	case '/', '@':
+		packet.Messaging = packet.DataType == '@'
+		err = parsePositionPacket(packet, body, true)
+	case '`', '\'', 0x1c, 0x1d:
+		err = parseMicE(packet, body)
+	case ':':
+		err = parseMessage(packet, body)
+	case ';':
+		err = parseObject(packet, body)
+	case ')':
+		err = parseItem(packet, body)
+	case '>':
+		err = parseStatus(packet, body)
+	case '_':
+		err = parsePositionlessWeather(packet, body)
+	case 'T':
+		err = parseTelemetry(packet, body)
+	case '<':
+		err = parseCapabilities(packet, body)
+	case '}':
+		err = parseThirdParty(packet, body)
+	case '$':
+		err = parseNMEA(packet, info)
+	case '?':
+		packet.Type = TypeQuery
+		packet.Query = strings.TrimSpace(body)
+	default:
+		err = newParseError(packet.DataType, "不支持的数据类型")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
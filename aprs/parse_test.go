@@ -0,0 +1,364 @@
+package aprs
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"aprs_agent/ax25"
+)
+
+func init() {
+	nowFunc = func() time.Time {
+		return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	}
+}
+
+func approx(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func mustParse(t *testing.T, line string) *Packet {
+	t.Helper()
+	packet, err := ParseTNC2(line)
+	if err != nil {
+		t.Fatalf("解析 %q 失败: %v", line, err)
+	}
+	return packet
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		lat, lon  float64
+		symbol    string
+		messaging bool
+		comment   string
+	}{
+		{"未压缩", "N0CALL>APRS:!4903.50N/07201.75W-Test 001234", 49.058333, -72.029167, "/-", false, "Test 001234"},
+		{"支持消息", "N0CALL>APRS:=4903.50S\\07201.75E>", -49.058333, 72.029167, "\\>", true, ""},
+		{"压缩", "N0CALL>APRS:=/5L!!<*e7>7P[", 49.5, -72.75, "/>", true, ""},
+		{"叠加字符", "N0CALL>APRS:!4903.50N107201.75W#", 49.058333, -72.029167, "1#", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet := mustParse(t, tt.line)
+			if packet.Type != TypePosition {
+				t.Fatalf("类型 = %v, want position", packet.Type)
+			}
+			pos := packet.Position
+			if !approx(pos.Latitude, tt.lat, 1e-5) || !approx(pos.Longitude, tt.lon, 1e-5) {
+				t.Errorf("位置 = %.6f,%.6f, want %.6f,%.6f", pos.Latitude, pos.Longitude, tt.lat, tt.lon)
+			}
+			if got := string([]byte{pos.SymbolTable, pos.SymbolCode}); got != tt.symbol {
+				t.Errorf("符号 = %q, want %q", got, tt.symbol)
+			}
+			if packet.Messaging != tt.messaging {
+				t.Errorf("Messaging = %v, want %v", packet.Messaging, tt.messaging)
+			}
+			if packet.Comment != tt.comment {
+				t.Errorf("注释 = %q, want %q", packet.Comment, tt.comment)
+			}
+		})
+	}
+}
+
+func TestParsePositionExtensions(t *testing.T) {
+	packet := mustParse(t, "N0CALL>APRS:@092345z4903.50N/07201.75W>088/036/A=001234hello")
+	pos := packet.Position
+	if !packet.Timestamp.Equal(time.Date(2026, 10, 9, 23, 45, 0, 0, time.UTC)) {
+		t.Errorf("时间戳 = %v", packet.Timestamp)
+	}
+	if !pos.HasCourseSpeed || pos.Course != 88 || !approx(pos.Speed, 36*1.852, 1e-6) {
+		t.Errorf("航向速度 = %d %.2f", pos.Course, pos.Speed)
+	}
+	if !pos.HasAltitude || !approx(pos.Altitude, 1234*0.3048, 1e-6) {
+		t.Errorf("海拔 = %.2f", pos.Altitude)
+	}
+	if packet.Comment != "hello" {
+		t.Errorf("注释 = %q", packet.Comment)
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:=4903.50N/07201.75W#PHG5132")
+	if phg := packet.Position.PHG; phg == nil || *phg != (PHG{Power: 25, Height: 20, Gain: 3, Directivity: 90}) {
+		t.Errorf("PHG = %+v", packet.Position.PHG)
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:!4903.  N/07201.  W-")
+	if packet.Position.Ambiguity != 2 || !approx(packet.Position.Latitude, 49.058333, 1e-5) {
+		t.Errorf("模糊位置 = %d %.6f", packet.Position.Ambiguity, packet.Position.Latitude)
+	}
+
+	// 压缩位置中的航向速度
+	packet = mustParse(t, "N0CALL>APRS:=/5L!!<*e7>7P[")
+	pos = packet.Position
+	if !pos.HasCourseSpeed || pos.Course != 88 || !approx(pos.Speed/1.852, 36.2, 0.1) {
+		t.Errorf("压缩航向速度 = %d %.2f", pos.Course, pos.Speed)
+	}
+}
+
+func TestParseMicE(t *testing.T) {
+	packet := mustParse(t, "N0CALL>SU3UX0:`CI(n\"O>/]\"4T}test")
+	if packet.Type != TypeMicE {
+		t.Fatalf("类型 = %v, want mic-e", packet.Type)
+	}
+
+	pos := packet.Position
+	if !approx(pos.Latitude, 35.596667, 1e-5) || !approx(pos.Longitude, 139.752, 1e-5) {
+		t.Errorf("位置 = %.6f,%.6f", pos.Latitude, pos.Longitude)
+	}
+	if pos.Course != 251 || !approx(pos.Speed, 20*1.852, 1e-6) {
+		t.Errorf("航向速度 = %d %.2f", pos.Course, pos.Speed)
+	}
+	if !pos.HasAltitude || pos.Altitude != 61 {
+		t.Errorf("海拔 = %.1f", pos.Altitude)
+	}
+	if pos.SymbolTable != '/' || pos.SymbolCode != '>' {
+		t.Errorf("符号 = %c%c", pos.SymbolTable, pos.SymbolCode)
+	}
+	if packet.MicE.Message != "En Route" || packet.MicE.Custom {
+		t.Errorf("消息 = %+v", packet.MicE)
+	}
+	if packet.Comment != "test" {
+		t.Errorf("注释 = %q", packet.Comment)
+	}
+
+	// 南纬西经，自定义消息
+	packet = mustParse(t, "N0CALL>DF358P:`CI(n\"O>/")
+	if packet.Position.Latitude >= 0 || packet.Position.Longitude >= 0 {
+		t.Errorf("半球错误: %.6f,%.6f", packet.Position.Latitude, packet.Position.Longitude)
+	}
+	if !packet.MicE.Custom || packet.MicE.Message != "Custom-1" {
+		t.Errorf("自定义消息 = %+v", packet.MicE)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		line string
+		want Message
+	}{
+		{"N0CALL>APRS::WU2Z     :Testing{003", Message{Addressee: "WU2Z", Text: "Testing", ID: "003"}},
+		{"N0CALL>APRS::WU2Z     :No id", Message{Addressee: "WU2Z", Text: "No id"}},
+		{"N0CALL>APRS::KB2ICI-14:ack003", Message{Addressee: "KB2ICI-14", ID: "003", Type: MessageAck}},
+		{"N0CALL>APRS::KB2ICI-14:rej003", Message{Addressee: "KB2ICI-14", ID: "003", Type: MessageReject}},
+		{"N0CALL>APRS::N0CALL   :hi{MM}AA", Message{Addressee: "N0CALL", Text: "hi", ID: "MM", ReplyAck: "AA"}},
+		{"N0CALL>APRS::BLN1     :Net tonight", Message{Addressee: "BLN1", Text: "Net tonight", Type: MessageBulletin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			packet := mustParse(t, tt.line)
+			if packet.Type != TypeMessage {
+				t.Fatalf("类型 = %v, want message", packet.Type)
+			}
+			if *packet.Message != tt.want {
+				t.Errorf("消息 = %+v, want %+v", *packet.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTelemetry(t *testing.T) {
+	packet := mustParse(t, "N0QBF-11>APRS:T#005,199,000,255,073,123,01101001 hello")
+	tm := packet.Telemetry
+	if tm.Sequence != "005" || len(tm.Analog) != 5 || tm.Analog[2] != 255 || tm.Digital != "01101001" {
+		t.Errorf("遥测 = %+v", tm)
+	}
+	if packet.Comment != "hello" {
+		t.Errorf("注释 = %q", packet.Comment)
+	}
+
+	packet = mustParse(t, "N0QBF-11>APRS::N0QBF-11 :PARM.Battery,Btemp,ATemp,Pres,Alt,Camra,Chute,Sun,10m,ATV")
+	def := packet.TelemetryDefinition
+	if def.Kind != "PARM" || def.Station != "N0QBF-11" || len(def.Names) != 10 || def.Names[9] != "ATV" {
+		t.Errorf("PARM = %+v", def)
+	}
+
+	packet = mustParse(t, "N0QBF-11>APRS::N0QBF-11 :EQNS.0,5.2,0,0,.53,-32,3,4.39,49,-32,3,18,1,2,3")
+	def = packet.TelemetryDefinition
+	if len(def.Coefficients) != 5 || def.Coefficients[1] != [3]float64{0, 0.53, -32} {
+		t.Errorf("EQNS = %+v", def)
+	}
+
+	packet = mustParse(t, "N0QBF-11>APRS::N0QBF-11 :BITS.10110000,Balloon")
+	def = packet.TelemetryDefinition
+	if def.Bits != "10110000" || def.ProjectTitle != "Balloon" {
+		t.Errorf("BITS = %+v", def)
+	}
+}
+
+func TestParseWeather(t *testing.T) {
+	check := func(t *testing.T, w *Weather) {
+		t.Helper()
+		if w == nil {
+			t.Fatalf("缺少气象数据")
+		}
+		if w.WindDirection == nil || *w.WindDirection != 220 {
+			t.Errorf("风向错误")
+		}
+		if w.WindSpeed == nil || !approx(*w.WindSpeed, 4*0.44704, 1e-6) {
+			t.Errorf("风速错误")
+		}
+		if w.Temperature == nil || !approx(*w.Temperature, 25, 1e-6) {
+			t.Errorf("温度错误")
+		}
+		if w.Humidity == nil || *w.Humidity != 50 {
+			t.Errorf("湿度错误")
+		}
+		if w.Pressure == nil || !approx(*w.Pressure, 990, 1e-6) {
+			t.Errorf("气压错误")
+		}
+		if w.Rain1h == nil || *w.Rain1h != 0 {
+			t.Errorf("降雨量错误")
+		}
+	}
+
+	packet := mustParse(t, "N0CALL>APRS:@092345z4903.50N/07201.75W_220/004g005t077r000p000P000h50b09900wRSW")
+	if packet.Type != TypeWeather || packet.Position == nil {
+		t.Fatalf("类型 = %v, want weather", packet.Type)
+	}
+	check(t, packet.Weather)
+	if packet.Comment != "wRSW" {
+		t.Errorf("注释 = %q", packet.Comment)
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:_10090556c220s004g005t077r000p000P000h50b09900wRSW")
+	if packet.Type != TypeWeather {
+		t.Fatalf("类型 = %v, want weather", packet.Type)
+	}
+	check(t, packet.Weather)
+	if !packet.Timestamp.Equal(time.Date(2026, 10, 9, 5, 56, 0, 0, time.UTC)) {
+		t.Errorf("时间戳 = %v", packet.Timestamp)
+	}
+
+	// 缺失的字段用点表示
+	packet = mustParse(t, "N0CALL>APRS:!4903.50N/07201.75W_.../...g...t050")
+	if w := packet.Weather; w == nil || w.WindDirection != nil || w.WindGust != nil || w.Temperature == nil {
+		t.Errorf("缺失字段处理错误: %+v", w)
+	}
+}
+
+func TestParseObjectItem(t *testing.T) {
+	packet := mustParse(t, "N0CALL>APRS:;LEADER   *092345z4903.50N/07201.75W>088/036")
+	if packet.Type != TypeObject || packet.Object.Name != "LEADER" || !packet.Object.Live {
+		t.Errorf("对象 = %v %+v", packet.Type, packet.Object)
+	}
+	if packet.Position == nil || packet.Position.Course != 88 {
+		t.Errorf("对象位置错误: %+v", packet.Position)
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:;LEADER   _092345z4903.50N/07201.75W>")
+	if packet.Object.Live {
+		t.Errorf("已删除对象的Live应为false")
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:)AID #2!4903.50N/07201.75WA")
+	if packet.Type != TypeItem || packet.Item.Name != "AID #2" || !packet.Item.Live {
+		t.Errorf("条目 = %v %+v", packet.Type, packet.Item)
+	}
+}
+
+func TestParseOtherTypes(t *testing.T) {
+	packet := mustParse(t, "N0CALL>APRS:>092345zNet Control Center")
+	if packet.Type != TypeStatus || packet.Status != "Net Control Center" || packet.Timestamp.IsZero() {
+		t.Errorf("状态 = %+v", packet)
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:<IGATE,MSG_CNT=30,LOC_CNT=0")
+	if packet.Type != TypeCapabilities || packet.Capabilities["MSG_CNT"] != "30" {
+		t.Errorf("能力 = %+v", packet.Capabilities)
+	}
+	if _, ok := packet.Capabilities["IGATE"]; !ok {
+		t.Errorf("缺少IGATE能力")
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:}W1AW>APRS,TCPIP,N0CALL*:>hello")
+	if packet.Type != TypeThirdParty || packet.ThirdParty.Source != "W1AW" || packet.ThirdParty.Status != "hello" {
+		t.Errorf("第三方 = %+v", packet.ThirdParty)
+	}
+
+	packet = mustParse(t, "N0CALL>GPS:$GPRMC,063909,A,3349.4302,N,11700.3721,W,43.022,89.3,291099,13.6,E*52")
+	if packet.Type != TypeNMEA || !approx(packet.Position.Latitude, 33.823837, 1e-5) || !approx(packet.Position.Longitude, -117.006202, 1e-5) {
+		t.Errorf("NMEA = %+v", packet.Position)
+	}
+	if packet.Position.Course != 89 {
+		t.Errorf("NMEA航向 = %d", packet.Position.Course)
+	}
+
+	packet = mustParse(t, "N0CALL>APRS:?APRS?")
+	if packet.Type != TypeQuery || packet.Query != "APRS?" {
+		t.Errorf("查询 = %+v", packet)
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	frame := ax25.NewUIFrame(
+		ax25.MustParseAddress("N0CALL-9"),
+		ax25.MustParseAddress("APRS"),
+		[]ax25.Address{ax25.MustParseAddress("WIDE1-1*"), ax25.MustParseAddress("WIDE2-1")},
+		[]byte("!4903.50N/07201.75W-"),
+	)
+
+	packet, err := Parse(frame)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if packet.Source != "N0CALL-9" || packet.Dest != "APRS" || len(packet.Path) != 2 || packet.Path[0] != "WIDE1-1*" {
+		t.Errorf("地址 = %s %s %v", packet.Source, packet.Dest, packet.Path)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"空信息字段", "N0CALL>APRS:"},
+		{"未知类型", "N0CALL>APRS:Zfoo"},
+		{"位置过短", "N0CALL>APRS:!4903.50N"},
+		{"半球错误", "N0CALL>APRS:!4903.50X/07201.75W-"},
+		{"纬度超出范围", "N0CALL>APRS:!9903.50N/07201.75W-"},
+		{"消息格式错误", "N0CALL>APRS::SHORT:hi"},
+		{"遥测为空", "N0CALL>APRS:T#"},
+		{"对象过短", "N0CALL>APRS:;SHORT"},
+		{"Mic-E目的地址错误", "N0CALL>APRS:`CI(n\"O>/"},
+		{"NMEA校验和错误", "N0CALL>GPS:$GPRMC,063909,A,3349.4302,N,11700.3721,W,43.022,89.3,291099,13.6,E*00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTNC2(tt.line)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Errorf("期望 *ParseError，得到 %v", err)
+			}
+		})
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	// 截断的报文只能返回错误，不能panic
+	samples := []string{
+		"N0CALL>APRS:@092345z4903.50N/07201.75W>088/036/A=001234hello",
+		"N0CALL>APRS:=/5L!!<*e7>7P[",
+		"N0CALL>SU3UX0:`CI(n\"O>/]\"4T}test",
+		"N0CALL>APRS::WU2Z     :Testing{003",
+		"N0CALL>APRS:_10090556c220s004g005t077r000p000P000h50b09900wRSW",
+		"N0CALL>APRS:;LEADER   *092345z4903.50N/07201.75W>088/036",
+		"N0CALL>APRS:)AID #2!4903.50N/07201.75WA",
+		"N0QBF-11>APRS:T#005,199,000,255,073,123,01101001",
+		"N0QBF-11>APRS::N0QBF-11 :BITS.10110000,Balloon",
+		"N0CALL>APRS:}W1AW>APRS,TCPIP,N0CALL*:>hello",
+		"N0CALL>GPS:$GPGGA,063909,3349.4302,N,11700.3721,W,1,08,1.0,100.0,M,,,,",
+	}
+
+	for _, sample := range samples {
+		for i := 0; i <= len(sample); i++ {
+			ParseTNC2(sample[:i])
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package aprs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单位换算系数
+const (
+	knotsToKmh  = 1.852
+	feetToMeter = 0.3048
+	milesToKm   = 1.609344
+)
+
+// Position 位置信息
+type Position struct {
+	Latitude    float64 // 纬度，北纬为正
+	Longitude   float64 // 经度，东经为正
+	Ambiguity   int     // 位置模糊级别 (0-4)
+	SymbolTable byte    // 符号表标识 ('/'、'\\' 或叠加字符)
+	SymbolCode  byte    // 符号代码
+	Compressed  bool    // 是否为压缩格式
+
+	HasCourseSpeed bool
+	Course         int     // 航向 (度，1-360，0表示未知)
+	Speed          float64 // 速度 (km/h)
+
+	HasAltitude bool
+	Altitude    float64 // 海拔 (米)
+
+	Range float64 // 无线电覆盖范围 (千米)，0表示未提供
+	PHG   *PHG
+}
+
+// PHG 功率、天线高度、增益和方向性
+type PHG struct {
+	Power       int // 发射功率 (瓦)
+	Height      int // 天线有效高度 (英尺)
+	Gain        int // 天线增益 (dB)
+	Directivity int // 方向 (度)，0表示全向
+}
+
+// parsePositionPacket 解析 ! = / @ 位置报文
+func parsePositionPacket(packet *Packet, body string, hasTimestamp bool) error {
+	if hasTimestamp {
+		if len(body) < 7 {
+			return newParseError(packet.DataType, "时间戳长度不足")
+		}
+		ts, err := parseTimestamp(body[:7])
+		if err != nil {
+			return newParseError(packet.DataType, "%v", err)
+		}
+		packet.Timestamp = ts
+		body = body[7:]
+	}
+
+	pos, rest, err := parsePosition(body)
+	if err != nil {
+		return newParseError(packet.DataType, "%v", err)
+	}
+	packet.Position = pos
+	packet.Type = TypePosition
+
+	// 气象站符号的位置报文携带气象数据
+	if pos.SymbolCode == '_' {
+		weather, comment := parseWeatherFields(rest, !pos.Compressed || pos.HasCourseSpeed)
+		if weather != nil {
+			if pos.Compressed && pos.HasCourseSpeed {
+				weather.setWindFromPosition(pos)
+			}
+			packet.Weather = weather
+			packet.Type = TypeWeather
+			packet.Comment = comment
+			return nil
+		}
+	}
+
+	packet.Comment = parsePositionComment(pos, rest)
+	return nil
+}
+
+// parsePosition 解析压缩或未压缩的位置，返回剩余的注释部分
+func parsePosition(s string) (*Position, string, error) {
+	if len(s) == 0 {
+		return nil, "", fmt.Errorf("缺少位置数据")
+	}
+
+	// 未压缩格式以纬度数字（或模糊位置的空格）开头
+	if isDigit(s[0]) || s[0] == ' ' {
+		return parseUncompressedPosition(s)
+	}
+	return parseCompressedPosition(s)
+}
+
+// parseUncompressedPosition 解析 DDMM.HHN/DDDMM.HHW$ 格式
+func parseUncompressedPosition(s string) (*Position, string, error) {
+	if len(s) < 19 {
+		return nil, "", fmt.Errorf("未压缩位置长度不足")
+	}
+
+	lat, ambiguity, err := parseLatitude(s[:8])
+	if err != nil {
+		return nil, "", err
+	}
+	lon, err := parseLongitude(s[9:18], ambiguity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pos := &Position{
+		Latitude:    lat,
+		Longitude:   lon,
+		Ambiguity:   ambiguity,
+		SymbolTable: s[8],
+		SymbolCode:  s[18],
+	}
+	if !isSymbolTable(pos.SymbolTable) {
+		return nil, "", fmt.Errorf("无效的符号表标识: %q", pos.SymbolTable)
+	}
+
+	return pos, s[19:], nil
+}
+
+// parseLatitude 解析 DDMM.HHN 格式的纬度，返回纬度和模糊级别
+func parseLatitude(s string) (float64, int, error) {
+	if len(s) != 8 || s[4] != '.' {
+		return 0, 0, fmt.Errorf("纬度格式错误: %q", s)
+	}
+
+	hemi := s[7]
+	if hemi != 'N' && hemi != 'S' && hemi != 'n' && hemi != 's' {
+		return 0, 0, fmt.Errorf("纬度半球标识错误: %q", s)
+	}
+
+	digits, ambiguity, err := resolveAmbiguity(s[:4] + s[5:7])
+	if err != nil {
+		return 0, 0, fmt.Errorf("纬度格式错误: %q", s)
+	}
+
+	deg, _ := strconv.Atoi(digits[:2])
+	min, _ := strconv.ParseFloat(digits[2:4]+"."+digits[4:6], 64)
+	if deg > 90 || min >= 60 {
+		return 0, 0, fmt.Errorf("纬度超出范围: %q", s)
+	}
+
+	lat := float64(deg) + (min+ambiguityOffset(ambiguity))/60
+	if lat > 90 {
+		lat = 90
+	}
+	if hemi == 'S' || hemi == 's' {
+		lat = -lat
+	}
+	return lat, ambiguity, nil
+}
+
+// parseLongitude 解析 DDDMM.HHW 格式的经度，模糊级别沿用纬度
+func parseLongitude(s string, ambiguity int) (float64, error) {
+	if len(s) != 9 || s[5] != '.' {
+		return 0, fmt.Errorf("经度格式错误: %q", s)
+	}
+
+	hemi := s[8]
+	if hemi != 'E' && hemi != 'W' && hemi != 'e' && hemi != 'w' {
+		return 0, fmt.Errorf("经度半球标识错误: %q", s)
+	}
+
+	// 经度的模糊位置与纬度一致，空格按0处理
+	digits := []byte(s[:5] + s[6:8])
+	for i := range digits {
+		if digits[i] == ' ' {
+			digits[i] = '0'
+		} else if !isDigit(digits[i]) {
+			return 0, fmt.Errorf("经度格式错误: %q", s)
+		}
+	}
+
+	deg, _ := strconv.Atoi(string(digits[:3]))
+	min, _ := strconv.ParseFloat(string(digits[3:5])+"."+string(digits[5:7]), 64)
+	if deg > 180 || min >= 60 {
+		return 0, fmt.Errorf("经度超出范围: %q", s)
+	}
+
+	lon := float64(deg) + (min+ambiguityOffset(ambiguity))/60
+	if lon > 180 {
+		lon = 180
+	}
+	if hemi == 'W' || hemi == 'w' {
+		lon = -lon
+	}
+	return lon, nil
+}
+
+// resolveAmbiguity 将末尾用空格表示的模糊位置替换为0，返回模糊级别
+func resolveAmbiguity(digits string) (string, int, error) {
+	buf := []byte(digits)
+	ambiguity := 0
+	for i := len(buf) - 1; i >= 0; i-- {
+		if buf[i] != ' ' {
+			break
+		}
+		buf[i] = '0'
+		ambiguity++
+	}
+
+	for _, c := range buf {
+		if !isDigit(c) {
+			return "", 0, fmt.Errorf("包含非数字字符")
+		}
+	}
+	if ambiguity > 4 {
+		return "", 0, fmt.Errorf("模糊级别超出范围")
+	}
+	return string(buf), ambiguity, nil
+}
+
+// ambiguityOffset 返回模糊区域中心相对于下边界的偏移 (分)
+func ambiguityOffset(ambiguity int) float64 {
+	switch ambiguity {
+	case 1:
+		return 0.05
+	case 2:
+		return 0.5
+	case 3:
+		return 5
+	case 4:
+		return 30
+	default:
+		return 0
+	}
+}
+
+// parseCompressedPosition 解析Base91压缩位置 (13字节)
+func parseCompressedPosition(s string) (*Position, string, error) {
+	if len(s) < 13 {
+		return nil, "", fmt.Errorf("压缩位置长度不足")
+	}
+
+	table := s[0]
+	if !isSymbolTable(table) && !(table >= 'a' && table <= 'j') {
+		return nil, "", fmt.Errorf("无效的符号表标识: %q", table)
+	}
+	// 压缩格式中的叠加数字用 a-j 表示
+	if table >= 'a' && table <= 'j' {
+		table = '0' + (table - 'a')
+	}
+
+	y, err := decodeBase91(s[1:5])
+	if err != nil {
+		return nil, "", err
+	}
+	x, err := decodeBase91(s[5:9])
+	if err != nil {
+		return nil, "", err
+	}
+
+	pos := &Position{
+		Latitude:    90 - float64(y)/380926,
+		Longitude:   -180 + float64(x)/190463,
+		SymbolTable: table,
+		SymbolCode:  s[9],
+		Compressed:  true,
+	}
+
+	c, sp, t := s[10], s[11], s[12]
+	if c != ' ' {
+		if c < '!' || c > '{' || sp < '!' || sp > '{' || t < '!' || t > '{' {
+			return nil, "", fmt.Errorf("压缩位置扩展数据无效")
+		}
+
+		switch {
+		case (t-33)&0x18 == 0x10:
+			// GGA来源，cs为海拔
+			pos.HasAltitude = true
+			pos.Altitude = math.Pow(1.002, float64(int(c-33)*91+int(sp-33))) * feetToMeter
+		case c == '{':
+			pos.Range = 2 * math.Pow(1.08, float64(sp-33)) * milesToKm
+		case c <= 'z':
+			pos.HasCourseSpeed = true
+			pos.Course = int(c-33) * 4
+			pos.Speed = (math.Pow(1.08, float64(sp-33)) - 1) * knotsToKmh
+		}
+	}
+
+	return pos, s[13:], nil
+}
+
+// parsePositionComment 解析位置注释中的数据扩展和海拔，返回剩余注释
+func parsePositionComment(pos *Position, comment string) string {
+	if !pos.Compressed && len(comment) >= 7 {
+		ext := comment[:7]
+		switch {
+		case ext[3] == '/' && isCourseSpeed(ext):
+			// 未知的航向速度用 "..." 或空格表示
+			if allDigits(ext[:3]) && allDigits(ext[4:7]) {
+				course, _ := strconv.Atoi(ext[:3])
+				speed, _ := strconv.Atoi(ext[4:7])
+				pos.HasCourseSpeed = true
+				pos.Course = course
+				pos.Speed = float64(speed) * knotsToKmh
+			}
+			comment = comment[7:]
+		case strings.HasPrefix(ext, "PHG") && allDigits(ext[3:7]):
+			pos.PHG = parsePHG(ext[3:7])
+			comment = comment[7:]
+		case strings.HasPrefix(ext, "RNG") && allDigits(ext[3:7]):
+			miles, _ := strconv.Atoi(ext[3:7])
+			pos.Range = float64(miles) * milesToKm
+			comment = comment[7:]
+		case strings.HasPrefix(ext, "DFS") && allDigits(ext[3:7]):
+			comment = comment[7:]
+		}
+	}
+
+	// 海拔 /A=aaaaaa 可出现在注释任意位置
+	if idx := strings.Index(comment, "/A="); idx >= 0 && idx+9 <= len(comment) {
+		if alt, err := strconv.Atoi(comment[idx+3 : idx+9]); err == nil {
+			pos.HasAltitude = true
+			pos.Altitude = float64(alt) * feetToMeter
+			comment = comment[:idx] + comment[idx+9:]
+		}
+	}
+
+	return strings.TrimSpace(comment)
+}
+
+// isCourseSpeed 检查 ddd/sss 航向速度扩展
+func isCourseSpeed(ext string) bool {
+	for _, i := range []int{0, 1, 2, 4, 5, 6} {
+		if !isDigit(ext[i]) && ext[i] != '.' && ext[i] != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePHG 解析PHG扩展的4个数字
+func parsePHG(s string) *PHG {
+	p := int(s[0] - '0')
+	h := int(s[1] - '0')
+	g := int(s[2] - '0')
+	d := int(s[3] - '0')
+	return &PHG{
+		Power:       p * p,
+		Height:      int(10 * math.Pow(2, float64(h))),
+		Gain:        g,
+		Directivity: d * 45,
+	}
+}
+
+// decodeBase91 解码Base91数字
+func decodeBase91(s string) (int, error) {
+	value := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '{' {
+			return 0, fmt.Errorf("无效的Base91字符: %q", c)
+		}
+		value = value*91 + int(c-33)
+	}
+	return value, nil
+}
+
+// parseTimestamp 解析7字符时间戳：DDHHMMz、DDHHMM/ 或 HHMMSSh
+func parseTimestamp(s string) (time.Time, error) {
+	if len(s) != 7 || !allDigits(s[:6]) {
+		return time.Time{}, fmt.Errorf("时间戳格式错误: %q", s)
+	}
+
+	a, _ := strconv.Atoi(s[0:2])
+	b, _ := strconv.Atoi(s[2:4])
+	c, _ := strconv.Atoi(s[4:6])
+	now := nowFunc()
+
+	switch s[6] {
+	case 'z', '/':
+		// 日时分，本地时间按UTC处理
+		if a < 1 || a > 31 || b > 23 || c > 59 {
+			return time.Time{}, fmt.Errorf("时间戳超出范围: %q", s)
+		}
+		utc := now.UTC()
+		ts := time.Date(utc.Year(), utc.Month(), a, b, c, 0, 0, time.UTC)
+		if ts.After(utc.Add(24 * time.Hour)) {
+			ts = ts.AddDate(0, -1, 0)
+		}
+		return ts, nil
+	case 'h':
+		// 时分秒 (UTC)
+		if a > 23 || b > 59 || c > 59 {
+			return time.Time{}, fmt.Errorf("时间戳超出范围: %q", s)
+		}
+		utc := now.UTC()
+		ts := time.Date(utc.Year(), utc.Month(), utc.Day(), a, b, c, 0, time.UTC)
+		if ts.After(utc.Add(time.Hour)) {
+			ts = ts.AddDate(0, 0, -1)
+		}
+		return ts, nil
+	default:
+		return time.Time{}, fmt.Errorf("未知的时间戳格式: %q", s)
+	}
+}
+
+// isSymbolTable 检查符号表标识是否有效
+func isSymbolTable(c byte) bool {
+	return c == '/' || c == '\\' || (c >= 'A' && c <= 'Z') || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func allDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
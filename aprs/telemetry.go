@@ -0,0 +1,52 @@
+package aprs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Telemetry 遥测数据
+type Telemetry struct {
+	Sequence string    // 序号，通常为3位数字，也可为 "MIC"
+	Analog   []float64 // 模拟通道值 (最多5个)
+	Digital  string    // 8个数字通道位，未提供时为空
+}
+
+// parseTelemetry 解析 T#seq,a1,a2,a3,a4,a5,bbbbbbbb 格式的遥测报文
+func parseTelemetry(packet *Packet, body string) error {
+	if !strings.HasPrefix(body, "#") {
+		return newParseError(packet.DataType, "遥测报文缺少 #")
+	}
+
+	fields := strings.Split(body[1:], ",")
+	if len(fields) < 2 {
+		return newParseError(packet.DataType, "遥测数据字段不足")
+	}
+
+	telemetry := &Telemetry{Sequence: strings.TrimSpace(fields[0])}
+	if telemetry.Sequence == "" {
+		return newParseError(packet.DataType, "遥测序号为空")
+	}
+
+	for i, field := range fields[1:] {
+		if i == 5 {
+			// 第6个字段为8个数字位，其后为注释
+			if len(field) < 8 || strings.Trim(field[:8], "01") != "" {
+				return newParseError(packet.DataType, "遥测数字通道格式错误: %q", field)
+			}
+			telemetry.Digital = field[:8]
+			packet.Comment = strings.TrimSpace(strings.Join(append([]string{field[8:]}, fields[7:]...), ","))
+			break
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return newParseError(packet.DataType, "遥测模拟通道格式错误: %q", field)
+		}
+		telemetry.Analog = append(telemetry.Analog, value)
+	}
+
+	packet.Type = TypeTelemetry
+	packet.Telemetry = telemetry
+	return nil
+}
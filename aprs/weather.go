@@ -0,0 +1,192 @@
+package aprs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 气象单位换算系数
+const (
+	mphToMs            = 0.44704
+	hundredthInchToMm  = 0.254
+	inchToMm           = 25.4
+	tenthMillibarToHPa = 0.1
+)
+
+// Weather 气象数据，未报告的字段为nil
+type Weather struct {
+	WindDirection *int     // 风向 (度)
+	WindSpeed     *float64 // 持续风速 (m/s)
+	WindGust      *float64 // 阵风风速 (m/s)
+	Temperature   *float64 // 温度 (摄氏度)
+	Rain1h        *float64 // 过去1小时降雨量 (毫米)
+	Rain24h       *float64 // 过去24小时降雨量 (毫米)
+	RainMidnight  *float64 // 午夜以来降雨量 (毫米)
+	Humidity      *int     // 相对湿度 (%)
+	Pressure      *float64 // 气压 (hPa)
+	Luminosity    *int     // 光照强度 (W/m²)
+	Snow24h       *float64 // 过去24小时降雪量 (毫米)
+}
+
+// weatherFieldLen 气象字段标识及其数值长度
+var weatherFieldLen = map[byte]int{
+	'c': 3, 's': 3, 'g': 3, 't': 3, 'r': 3, 'p': 3, 'P': 3,
+	'h': 2, 'b': 5, 'L': 3, 'l': 3, '#': 3,
+}
+
+// parsePositionlessWeather 解析 _MMDDHHMM 开头的无位置气象报告
+func parsePositionlessWeather(packet *Packet, body string) error {
+	if len(body) < 8 || !allDigits(body[:8]) {
+		return newParseError(packet.DataType, "气象报告时间戳格式错误")
+	}
+
+	month, _ := strconv.Atoi(body[0:2])
+	day, _ := strconv.Atoi(body[2:4])
+	hour, _ := strconv.Atoi(body[4:6])
+	minute, _ := strconv.Atoi(body[6:8])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 {
+		return newParseError(packet.DataType, "气象报告时间戳超出范围")
+	}
+
+	now := nowFunc().UTC()
+	ts := time.Date(now.Year(), time.Month(month), day, hour, minute, 0, 0, time.UTC)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+
+	weather, comment := parseWeatherFields(body[8:], false)
+	if weather == nil {
+		return newParseError(packet.DataType, "未找到气象数据")
+	}
+
+	packet.Type = TypeWeather
+	packet.Timestamp = ts
+	packet.Weather = weather
+	packet.Comment = comment
+	return nil
+}
+
+// parseWeatherFields 解析气象字段，leadingWind表示开头为 ddd/sss 风向风速
+// 返回气象数据（无有效字段时为nil）和剩余的注释（通常为软件和设备标识）
+func parseWeatherFields(s string, leadingWind bool) (*Weather, string) {
+	weather := &Weather{}
+	found := false
+	windSeen := false
+
+	if leadingWind {
+		if len(s) >= 7 && s[3] == '/' && isCourseSpeed(s[:7]) {
+			if dir, ok := parseWeatherValue(s[:3]); ok {
+				d := int(dir)
+				weather.WindDirection = &d
+			}
+			if speed, ok := parseWeatherValue(s[4:7]); ok {
+				v := speed * mphToMs
+				weather.WindSpeed = &v
+			}
+			s = s[7:]
+			found = true
+		}
+		windSeen = true
+	}
+
+	for len(s) > 0 {
+		key := s[0]
+		length, ok := weatherFieldLen[key]
+		if !ok || len(s) < 1+length {
+			break
+		}
+		raw := s[1 : 1+length]
+		if !isWeatherValue(raw) {
+			break
+		}
+		s = s[1+length:]
+		found = true
+
+		value, ok := parseWeatherValue(raw)
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case 'c':
+			d := int(value)
+			weather.WindDirection = &d
+		case 's':
+			if windSeen {
+				v := value * inchToMm
+				weather.Snow24h = &v
+			} else {
+				v := value * mphToMs
+				weather.WindSpeed = &v
+				windSeen = true
+			}
+		case 'g':
+			v := value * mphToMs
+			weather.WindGust = &v
+		case 't':
+			v := (value - 32) * 5 / 9
+			weather.Temperature = &v
+		case 'r':
+			v := value * hundredthInchToMm
+			weather.Rain1h = &v
+		case 'p':
+			v := value * hundredthInchToMm
+			weather.Rain24h = &v
+		case 'P':
+			v := value * hundredthInchToMm
+			weather.RainMidnight = &v
+		case 'h':
+			h := int(value)
+			if h == 0 {
+				h = 100
+			}
+			weather.Humidity = &h
+		case 'b':
+			v := value * tenthMillibarToHPa
+			weather.Pressure = &v
+		case 'L':
+			l := int(value)
+			weather.Luminosity = &l
+		case 'l':
+			l := int(value) + 1000
+			weather.Luminosity = &l
+		}
+	}
+
+	if !found {
+		return nil, ""
+	}
+	return weather, strings.TrimSpace(s)
+}
+
+// setWindFromPosition 使用压缩位置中的航向速度作为风向风速
+func (w *Weather) setWindFromPosition(pos *Position) {
+	dir := pos.Course
+	speed := pos.Speed / 3.6
+	w.WindDirection = &dir
+	w.WindSpeed = &speed
+}
+
+// isWeatherValue 检查是否为有效的气象数值（数字、负号，或表示缺失的点和空格）
+func isWeatherValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isDigit(c) && c != '.' && c != ' ' && !(c == '-' && i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseWeatherValue 解析气象数值，缺失值返回false
+func parseWeatherValue(s string) (float64, bool) {
+	if strings.Trim(s, ". ") == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
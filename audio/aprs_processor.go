@@ -22,6 +22,8 @@ type APRSProcessor struct {
 	// 统计信息
 	peakLevel     float64
 	rmsLevel      float64
+	channelPeak   []float64 // 各声道的峰值电平
+	channelRMS    []float64 // 各声道的RMS电平
 	clippingCount int
 }
 
@@ -38,7 +40,9 @@ func NewAPRSProcessor() *APRSProcessor {
 	}
 }
 
-// ProcessAudio 处理APRS音频数据
+// ProcessAudio 处理APRS音频数据，input为channels个声道交错的16位PCM
+// 电平按声道分别统计，立体声声卡的左右声道接两部电台时互不影响；
+// 噪声门限、压缩器和限幅器逐个采样处理，与声道无关
 func (ap *APRSProcessor) ProcessAudio(input []byte, sampleRate int, channels int) []byte {
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
@@ -48,7 +52,7 @@ func (ap *APRSProcessor) ProcessAudio(input []byte, sampleRate int, channels int
 	copy(output, input)
 
 	// 计算音频电平
-	ap.calculateLevels(output)
+	ap.calculateLevels(output, channels)
 
 	// 应用噪声门限
 	if ap.isNoiseGateEnabled {
@@ -68,43 +72,93 @@ func (ap *APRSProcessor) ProcessAudio(input []byte, sampleRate int, channels int
 	return output
 }
 
-// calculateLevels 计算音频电平
-func (ap *APRSProcessor) calculateLevels(data []byte) {
-	if len(data) == 0 {
+// MeasureLevels 只统计音频电平而不处理音频，未启用APRS模式时用于电平载波检测
+func (ap *APRSProcessor) MeasureLevels(input []byte, channels int) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	ap.calculateLevels(input, channels)
+}
+
+// ProcessTransmit 处理待发射的调制音频，只应用限幅器
+// 噪声门限和压缩器会破坏AFSK波形，因此发射方向不使用
+func (ap *APRSProcessor) ProcessTransmit(input []byte) []byte {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	output := make([]byte, len(input))
+	copy(output, input)
+
+	if ap.isLimiterEnabled {
+		ap.applyLimiter(output)
+	}
+
+	return output
+}
+
+// calculateLevels 计算音频电平，总电平取所有声道，同时统计每个声道的电平
+func (ap *APRSProcessor) calculateLevels(data []byte, channels int) {
+	if channels < 1 {
+		channels = 1
+	}
+	if len(ap.channelRMS) != channels {
+		ap.channelRMS = make([]float64, channels)
+		ap.channelPeak = make([]float64, channels)
+	}
+
+	if len(data) < 2 {
 		ap.peakLevel = -96.0
 		ap.rmsLevel = -96.0
+		for ch := range ap.channelRMS {
+			ap.channelPeak[ch] = -96.0
+			ap.channelRMS[ch] = -96.0
+		}
 		return
 	}
 
-	// 计算RMS值
-	var sum float64
-	sampleCount := len(data) / 2 // 假设16位音频
+	sums := make([]float64, channels)
+	peaks := make([]float64, channels)
+	counts := make([]int, channels)
 
-	var peak float64
-	for j := 0; j < len(data); j += 2 {
+	// 16位音频，声道交错排列
+	for j := 0; j+1 < len(data); j += 2 {
+		ch := (j / 2) % channels
 		sample := int16(data[j]) | int16(data[j+1])<<8
 		sampleAbs := math.Abs(float64(sample))
-		sum += sampleAbs * sampleAbs
+		sums[ch] += sampleAbs * sampleAbs
+		counts[ch]++
 
-		if sampleAbs > peak {
-			peak = sampleAbs
+		if sampleAbs > peaks[ch] {
+			peaks[ch] = sampleAbs
 		}
 	}
 
-	rms := math.Sqrt(sum / float64(sampleCount))
+	var sum, peak float64
+	var count int
+	for ch := 0; ch < channels; ch++ {
+		ap.channelRMS[ch] = rmsToDB(sums[ch], counts[ch])
+		ap.channelPeak[ch] = amplitudeToDB(peaks[ch])
+		sum += sums[ch]
+		count += counts[ch]
+		peak = math.Max(peak, peaks[ch])
+	}
+	ap.rmsLevel = rmsToDB(sum, count)
+	ap.peakLevel = amplitudeToDB(peak)
+}
 
-	// 转换为分贝
-	if rms > 0 {
-		ap.rmsLevel = 20 * math.Log10(rms/32767.0)
-	} else {
-		ap.rmsLevel = -96.0
+// rmsToDB 由平方和计算RMS电平 (dBFS)
+func rmsToDB(sum float64, count int) float64 {
+	if count == 0 {
+		return -96.0
 	}
+	return amplitudeToDB(math.Sqrt(sum / float64(count)))
+}
 
-	if peak > 0 {
-		ap.peakLevel = 20 * math.Log10(peak/32767.0)
-	} else {
-		ap.peakLevel = -96.0
+// amplitudeToDB 将16位采样幅度转换为分贝 (dBFS)，静音时为-96dB
+func amplitudeToDB(amplitude float64) float64 {
+	if amplitude > 0 {
+		return 20 * math.Log10(amplitude/32767.0)
 	}
+	return -96.0
 }
 
 // applyNoiseGate 应用噪声门限
@@ -237,6 +291,16 @@ func (ap *APRSProcessor) GetRMSLevel() float64 {
 	return ap.rmsLevel
 }
 
+// GetChannelRMSLevel 获取指定声道的RMS电平，声道不存在时返回总电平
+func (ap *APRSProcessor) GetChannelRMSLevel(channel int) float64 {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+	if channel < 0 || channel >= len(ap.channelRMS) {
+		return ap.rmsLevel
+	}
+	return ap.channelRMS[channel]
+}
+
 // GetClippingCount 获取限幅次数
 func (ap *APRSProcessor) GetClippingCount() int {
 	ap.mu.RLock()
@@ -265,6 +329,8 @@ func (ap *APRSProcessor) GetStatus() map[string]interface{} {
 		"peak_threshold":       ap.peakThreshold,
 		"peak_level":           ap.peakLevel,
 		"rms_level":            ap.rmsLevel,
+		"channel_peak_levels":  append([]float64(nil), ap.channelPeak...),
+		"channel_rms_levels":   append([]float64(nil), ap.channelRMS...),
 		"clipping_count":       ap.clippingCount,
 	}
 }
@@ -0,0 +1,250 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"aprs_agent/config"
+)
+
+// pcm16 将采样值编码为16位小端PCM
+func pcm16(samples ...int16) []byte {
+	out := make([]byte, 0, len(samples)*2)
+	for _, s := range samples {
+		out = binary.LittleEndian.AppendUint16(out, uint16(s))
+	}
+	return out
+}
+
+func TestMixQueue(t *testing.T) {
+	q := newMixQueue(FormatInt16)
+
+	// 左右声道的两个通道同时播放，叠加为一个立体声输出
+	if err := q.push(0, pcm16(100, 0, 200, 0, 300, 0)); err != nil {
+		t.Fatalf("加入队列失败: %v", err)
+	}
+	if err := q.push(1, pcm16(0, -100, 0, -200)); err != nil {
+		t.Fatalf("加入队列失败: %v", err)
+	}
+	if q.size(0) != 1 || q.size(1) != 1 || q.size(2) != 0 {
+		t.Errorf("队列大小错误: %d %d %d", q.size(0), q.size(1), q.size(2))
+	}
+
+	out := make([]byte, 8)
+	if n := q.fill(out); n != 8 || !bytes.Equal(out, pcm16(100, -100, 200, -200)) {
+		t.Errorf("第一次输出 = %v (%d字节)", out, n)
+	}
+	if q.size(0) != 1 || q.size(1) != 0 {
+		t.Errorf("播放后队列大小错误: %d %d", q.size(0), q.size(1))
+	}
+
+	// 剩余数据之后补静音
+	if n := q.fill(out); n != 4 || !bytes.Equal(out, pcm16(300, 0, 0, 0)) {
+		t.Errorf("第二次输出 = %v (%d字节)", out, n)
+	}
+	if n := q.fill(out); n != 0 || !bytes.Equal(out, make([]byte, 8)) {
+		t.Errorf("空队列应输出静音: %v (%d字节)", out, n)
+	}
+
+	// 同一通道的数据块按顺序连续播放
+	q.push(0, pcm16(1))
+	q.push(0, pcm16(2, 3))
+	if n := q.fill(out); n != 6 || !bytes.Equal(out, pcm16(1, 2, 3, 0)) {
+		t.Errorf("连续播放输出 = %v (%d字节)", out, n)
+	}
+
+	// 叠加时限幅
+	q.push(0, pcm16(30000, -30000))
+	q.push(1, pcm16(10000, -10000))
+	q.fill(out[:4])
+	if !bytes.Equal(out[:4], pcm16(32767, -32768)) {
+		t.Errorf("限幅输出 = %v", out[:4])
+	}
+
+	// 清空一个通道不影响其他通道
+	q.push(0, pcm16(5))
+	q.push(1, pcm16(7))
+	q.clear(0)
+	if n := q.fill(out[:2]); n != 2 || !bytes.Equal(out[:2], pcm16(7)) {
+		t.Errorf("清空通道0后输出 = %v", out[:2])
+	}
+
+	// 队列已满
+	for i := 0; i < mixQueueLimit; i++ {
+		if err := q.push(3, pcm16(1)); err != nil {
+			t.Fatalf("第%d块加入失败: %v", i+1, err)
+		}
+	}
+	if err := q.push(3, pcm16(1)); err == nil {
+		t.Error("队列已满时应返回错误")
+	}
+	if err := q.push(4, pcm16(1)); err != nil {
+		t.Errorf("其他通道的队列不受影响: %v", err)
+	}
+}
+
+func TestMixQueueFloat32(t *testing.T) {
+	q := newMixQueue(FormatFloat32)
+	q.push(0, Int16ToPCM(pcm16(16384), FormatFloat32))
+	q.push(1, Int16ToPCM(pcm16(32767), FormatFloat32))
+
+	out := make([]byte, 4)
+	if n := q.fill(out); n != 4 || !bytes.Equal(PCMToInt16(out, FormatFloat32), pcm16(32767)) {
+		t.Errorf("float32限幅输出 = %v (%d字节)", out, n)
+	}
+}
+
+func TestExtractChannel(t *testing.T) {
+	stereo := pcm16(1, -1, 2, -2, 3, -3)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		channels int
+		index    int
+		want     []byte
+	}{
+		{"左声道", stereo, 2, 0, pcm16(1, 2, 3)},
+		{"右声道", stereo, 2, 1, pcm16(-1, -2, -3)},
+		{"单声道原样返回", pcm16(1, 2, 3), 1, 0, pcm16(1, 2, 3)},
+		{"奇数字节忽略最后一个字节", append(pcm16(1, -1, 2, -2), 0x7F), 2, 1, pcm16(-1, -2)},
+		{"不完整的最后一帧", pcm16(1, -1, 2), 2, 0, pcm16(1, 2)},
+		{"不完整的最后一帧缺少右声道", pcm16(1, -1, 2), 2, 1, pcm16(-1)},
+		{"声道超出范围时取第一个声道", stereo, 2, 2, pcm16(1, 2, 3)},
+		{"四声道第三声道", pcm16(1, 2, 3, 4, 5, 6, 7, 8), 4, 2, pcm16(3, 7)},
+		{"空数据", nil, 2, 0, []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractChannel(tt.data, tt.channels, tt.index); !bytes.Equal(got, tt.want) {
+				t.Errorf("extractChannel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterleaveChannels(t *testing.T) {
+	mono := pcm16(1, 2)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		channels int
+		side     int
+		want     []byte
+	}{
+		{"左声道", mono, 2, 0, pcm16(1, 0, 2, 0)},
+		{"右声道", mono, 2, 1, pcm16(0, 1, 0, 2)},
+		{"单声道通道复制到所有声道", mono, 2, -1, pcm16(1, 1, 2, 2)},
+		{"单声道输出原样返回", mono, 1, 1, mono},
+		{"声道超出范围时复制到所有声道", mono, 2, 2, pcm16(1, 1, 2, 2)},
+		{"奇数字节忽略最后一个字节", append(pcm16(1, 2), 0x7F), 2, 1, pcm16(0, 1, 0, 2)},
+		{"空数据", nil, 2, 0, []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interleaveChannels(tt.data, tt.channels, tt.side); !bytes.Equal(got, tt.want) {
+				t.Errorf("interleaveChannels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateLevels(t *testing.T) {
+	const full = 32767
+	const half = -6.0206 // 半幅度的电平 (dBFS)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		channels int
+		want     []float64 // 各声道的RMS电平，最后一个为总电平
+	}{
+		{"左声道有信号", pcm16(full, 0, -full, 0), 2, []float64{0, -96, -3.0103}},
+		{"右声道有信号", pcm16(0, full/2, 0, -full/2), 2, []float64{-96, half, half - 3.0103}},
+		{"单声道", pcm16(full/2, -full/2), 1, []float64{half, half}},
+		{"声道数为0时按单声道", pcm16(full/2, -full/2), 0, []float64{half, half}},
+		{"奇数字节忽略最后一个字节", append(pcm16(full/2, 0), 0x7F), 2, []float64{half, -96, half - 3.0103}},
+		{"数据不足一个采样", []byte{0x7F}, 2, []float64{-96, -96, -96}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ap := NewAPRSProcessor()
+			ap.MeasureLevels(tt.data, tt.channels)
+
+			channels := len(tt.want) - 1
+			for ch := 0; ch < channels; ch++ {
+				if got := ap.GetChannelRMSLevel(ch); !near(got, tt.want[ch]) {
+					t.Errorf("声道 %d 电平 = %.2f, want %.2f", ch, got, tt.want[ch])
+				}
+			}
+			total := tt.want[channels]
+			if got := ap.GetRMSLevel(); !near(got, total) {
+				t.Errorf("总电平 = %.2f, want %.2f", got, total)
+			}
+			// 声道不存在时返回总电平
+			if got := ap.GetChannelRMSLevel(channels); !near(got, total) {
+				t.Errorf("声道 %d 不存在时电平 = %.2f, want %.2f", channels, got, total)
+			}
+			if got := ap.GetChannelRMSLevel(-1); !near(got, total) {
+				t.Errorf("声道 -1 电平 = %.2f, want %.2f", got, total)
+			}
+		})
+	}
+}
+
+// near 电平是否在0.01dB以内
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 0.01
+}
+
+func TestOpenSoundCards(t *testing.T) {
+	cfg := &config.Config{
+		Audio: config.AudioConfig{
+			Input:  config.InputConfig{DeviceName: "USB Audio", Channels: 2},
+			Output: config.OutputConfig{DeviceName: "USB Audio Out", Channels: 2},
+		},
+		Channels: map[string]config.ChannelConfig{
+			"0": {Device: "USB Audio", Side: config.SideLeft},
+			"1": {Device: " usb audio", Side: config.SideRight},
+			"2": {Device: "hw:2,0", Side: config.SideMono},
+		},
+	}
+	old := NewAPRSProcessor()
+
+	cards, channelCards, err := openSoundCards(cfg, nil, map[string]*APRSProcessor{"hw:2,0": old})
+	if err != nil {
+		t.Fatalf("打开声卡失败: %v", err)
+	}
+	defer closeSoundCards(cards)
+
+	// 同一设备的左右声道共用一块声卡，其他设备单独打开
+	if len(cards) != 2 || len(channelCards) != 3 {
+		t.Fatalf("声卡数 = %d, 通道数 = %d, want 2, 3", len(cards), len(channelCards))
+	}
+	if channelCards[0] != channelCards[1] || channelCards[2] == channelCards[0] {
+		t.Error("通道使用的声卡错误")
+	}
+
+	// [audio.input] 的设备使用原配置，其他设备的输入输出都使用通道配置的设备名称
+	if channelCards[0].config != cfg {
+		t.Error("[audio.input] 的设备应使用原配置")
+	}
+	other := channelCards[2].config.Audio
+	if other.Input.DeviceName != "hw:2,0" || other.Output.DeviceName != "hw:2,0" || other.Input.Channels != 2 {
+		t.Errorf("设备 hw:2,0 的配置错误: %+v", other)
+	}
+	if cfg.Audio.Output.DeviceName != "USB Audio Out" {
+		t.Error("不应修改原配置")
+	}
+
+	// 同名设备沿用原来的APRS处理器
+	if channelCards[2].processor != old || channelCards[0].processor == old {
+		t.Error("APRS处理器沿用错误")
+	}
+}
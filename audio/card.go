@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	"aprs_agent/config"
+)
+
+// soundCard 一块声卡的输入输出，使用同一设备的通道共享
+// 立体声声卡的左右声道接两部电台时两个通道使用同一块声卡，使用其他设备的通道各自打开声卡
+type soundCard struct {
+	device    string // 通道配置的设备名称
+	config    *config.Config
+	input     AudioInput
+	output    AudioOutput
+	processor *APRSProcessor  // 输入电平统计和音频处理，按声卡的声道统计
+	channels  []*radioChannel // 使用该声卡的通道
+}
+
+// openSoundCards 按通道配置的设备创建声卡，每个不同的设备一块，返回所有声卡和每个通道使用的声卡
+// 设备名称不区分大小写；processors中与设备同名的APRS处理器会被沿用，以保留运行时的处理参数
+func openSoundCards(cfg *config.Config, devices DeviceManagerInterface, processors map[string]*APRSProcessor) ([]*soundCard, []*soundCard, error) {
+	var cards []*soundCard
+	byDevice := make(map[string]*soundCard)
+	var channelCards []*soundCard
+
+	for _, chCfg := range channelConfigs(cfg) {
+		key := config.DeviceKey(chCfg.Device)
+		card := byDevice[key]
+		if card == nil {
+			processor := processors[key]
+			if processor == nil {
+				processor = NewAPRSProcessor()
+			}
+			var err error
+			card, err = newSoundCard(cardConfig(cfg, chCfg.Device), devices, chCfg.Device, processor)
+			if err != nil {
+				closeSoundCards(cards)
+				return nil, nil, err
+			}
+			byDevice[key] = card
+			cards = append(cards, card)
+		}
+		channelCards = append(channelCards, card)
+	}
+	return cards, channelCards, nil
+}
+
+// cardConfig 生成声卡使用的配置
+// [audio.input] 的设备使用 [audio.input] 和 [audio.output] 中的设备名称，
+// 其他设备的输入输出都使用通道配置的设备名称；采样率、声道数、缓冲区和格式与 [audio.*] 相同
+func cardConfig(cfg *config.Config, device string) *config.Config {
+	if config.DeviceKey(device) == config.DeviceKey(cfg.Audio.Input.DeviceName) {
+		return cfg
+	}
+	c := *cfg
+	c.Audio.Input.DeviceName = device
+	c.Audio.Output.DeviceName = device
+	return &c
+}
+
+// newSoundCard 根据平台创建声卡的音频输入输出
+func newSoundCard(cfg *config.Config, devices DeviceManagerInterface, device string, processor *APRSProcessor) (*soundCard, error) {
+	card := &soundCard{device: device, config: cfg, processor: processor}
+
+	var err error
+	if runtime.GOOS == "darwin" {
+		// macOS平台
+		if card.input, err = newMacOSInput(cfg, devices); err != nil {
+			return nil, fmt.Errorf("创建macOS音频输入失败: %w", err)
+		}
+		if card.output, err = newMacOSOutput(cfg, devices); err != nil {
+			card.input.Close()
+			return nil, fmt.Errorf("创建macOS音频输出失败: %w", err)
+		}
+	} else {
+		// 其他平台使用通用实现
+		if card.input, err = newGenericInput(cfg, devices); err != nil {
+			return nil, fmt.Errorf("创建通用音频输入失败: %w", err)
+		}
+		if card.output, err = newGenericOutput(cfg, devices); err != nil {
+			card.input.Close()
+			return nil, fmt.Errorf("创建通用音频输出失败: %w", err)
+		}
+	}
+	return card, nil
+}
+
+// closeSoundCards 关闭声卡的音频输入输出
+func closeSoundCards(cards []*soundCard) {
+	for _, card := range cards {
+		if err := card.input.Close(); err != nil {
+			log.Printf("关闭音频输入失败: %v", err)
+		}
+		if err := card.output.Close(); err != nil {
+			log.Printf("关闭音频输出失败: %v", err)
+		}
+	}
+}
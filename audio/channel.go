@@ -0,0 +1,170 @@
+package audio
+
+import (
+	"fmt"
+	"log"
+
+	"aprs_agent/config"
+	"aprs_agent/csma"
+	"aprs_agent/modem"
+	"aprs_agent/ptt"
+	"aprs_agent/rig"
+)
+
+// rigStatus 能读取电台状态的PTT，如rigctld
+type rigStatus interface {
+	Status() rig.Status
+}
+
+// defaultDCDThreshold 电平载波检测的默认门限 (dBFS)
+const defaultDCDThreshold = -40.0
+
+// txHighWater 音频输出队列中的帧数达到此值时，发射调度暂停取出新帧
+// 保留少量已调制的帧使连续发射之间没有间隙，其余帧在发射队列中按优先级等待
+const txHighWater = 2
+
+// radioChannel 一个无线电通道的接收和发射链路
+// 立体声声卡的左右声道各接一部电台时，每个声道是一个独立的通道
+type radioChannel struct {
+	index       int
+	card        *soundCard // 通道使用的声卡
+	input       int        // 在交错输入PCM中的声道索引
+	demodulator *modem.Demodulator
+	deframer    *modem.Deframer
+	transmitter *Transmitter
+	scheduler   *csma.Scheduler
+	dcd         func() bool // 载波检测
+}
+
+// newRadioChannels 按通道配置创建接收和发射链路，cards为每个通道使用的声卡，接收帧交给dispatch分发
+// CM108 PTT未配置hidraw设备时，按设备管理器选择的音频设备查找
+func newRadioChannels(cfg *config.Config, devices DeviceManagerInterface, cards []*soundCard, dispatch func(modem.Frame)) ([]*radioChannel, error) {
+	var channels []*radioChannel
+	for i, chCfg := range channelConfigs(cfg) {
+		card := cards[i]
+		output, processor := card.output, card.processor
+
+		p, err := ptt.Open(ptt.Config{
+			Method: chCfg.PTT,
+			Device: chCfg.PTTDevice,
+			Line:   chCfg.PTTLine,
+			Invert: chCfg.PTTInvert,
+			GPIO:   chCfg.PTTGPIO,
+
+			AudioDevice: resolveDeviceName(devices, chCfg.Device),
+		})
+		if err != nil {
+			closeChannels(channels)
+			return nil, fmt.Errorf("通道 %d: %w", i, err)
+		}
+		// 未配置PTT (VOX) 时同样记录发射时间，发射超时时清空本通道的音频队列
+		// 每个通道只等待自己的音频播放完毕，左右声道的两部电台互不影响
+		index := i
+		keyer := ptt.NewKeyer(p, func() int { return output.GetQueueSize(index) })
+		keyer.SetLimits(ptt.Limits{
+			MaxTxTime:  cfg.GetMaxTxTime(),
+			DutyCycle:  cfg.Audio.Transmit.DutyCycle,
+			DutyWindow: cfg.GetDutyWindow(),
+		}, func() { output.ClearQueue(index) })
+
+		input := sideIndex(chCfg.Side)
+		if input < 0 {
+			// 单声道通道使用输入的第一个声道
+			input = 0
+		}
+
+		ch := &radioChannel{
+			index:       i,
+			card:        card,
+			input:       input,
+			demodulator: modem.NewDemodulator(cfg.Audio.Input.SampleRate),
+			deframer:    modem.NewDeframer(i),
+			transmitter: newTransmitter(cfg, i, chCfg, output, processor, keyer),
+		}
+
+		// 载波检测: 默认使用解调器锁定状态，也可以比较声道电平和门限
+		ch.dcd = ch.demodulator.DCD
+		if chCfg.DCD == config.DCDLevel {
+			threshold := chCfg.DCDThreshold
+			if threshold == 0 {
+				threshold = defaultDCDThreshold
+			}
+			ch.dcd = func() bool {
+				return processor.GetChannelRMSLevel(input) > threshold
+			}
+		}
+
+		// 发射链路: 发射队列 -> CSMA -> 发射器
+		transmitter := ch.transmitter
+		ch.scheduler = csma.New(csma.Config{
+			Send: transmitter.SendFrame,
+			DCD:  ch.dcd,
+			Busy: ch.txBusy(output),
+			Params: func() csma.Params {
+				params := transmitter.GetParams()
+				return csma.Params{
+					Persistence: params.Persistence,
+					SlotTime:    params.SlotTime,
+					FullDuplex:  params.FullDuplex,
+				}
+			},
+			// 在发射超时保护触发之前结束连续发射，让PTT松开后重新竞争信道
+			Keyed:     keyer.IsKeyed,
+			Airtime:   transmitter.Airtime,
+			MaxTxTime: cfg.GetMaxTxTime(),
+		})
+
+		// 接收链路: 声道PCM -> 解调器 -> HDLC解帧器
+		ch.demodulator.SetBitCallback(ch.deframer.ProcessBit)
+		ch.deframer.SetLevelFunc(func() float64 {
+			return processor.GetChannelRMSLevel(input)
+		})
+		if r, ok := p.(rigStatus); ok {
+			// 在接收帧中记录电台的频率和S表
+			ch.deframer.SetCallback(func(f modem.Frame) {
+				status := r.Status()
+				f.Frequency = status.Frequency
+				f.Strength = status.Strength
+				f.HasStrength = status.HasStrength
+				dispatch(f)
+			})
+		} else {
+			ch.deframer.SetCallback(dispatch)
+		}
+
+		channels = append(channels, ch)
+		card.channels = append(card.channels, ch)
+	}
+	return channels, nil
+}
+
+// txBusy 发射调度是否需要暂缓取出新帧：本通道的音频输出队列已满或发射占空比达到上限
+// 只在调度goroutine中调用，占空比状态变化时记录日志
+func (ch *radioChannel) txBusy(output AudioOutput) func() bool {
+	held := false
+	return func() bool {
+		exceeded := ch.transmitter.keyer.DutyCycleExceeded()
+		if exceeded != held {
+			held = exceeded
+			if held {
+				log.Printf("通道 %d 发射占空比达到上限，暂缓发射", ch.index)
+			} else {
+				log.Printf("通道 %d 发射占空比已恢复", ch.index)
+			}
+		}
+		return exceeded || output.GetQueueSize(ch.index) >= txHighWater
+	}
+}
+
+// closeChannels 停止发射调度并释放各通道的PTT设备
+func closeChannels(channels []*radioChannel) {
+	for _, ch := range channels {
+		ch.scheduler.Stop()
+		if n := ch.scheduler.Clear(); n > 0 {
+			log.Printf("通道 %d 丢弃了 %d 个未发射的帧", ch.index, n)
+		}
+		if err := ch.transmitter.close(); err != nil {
+			log.Printf("通道 %d 关闭PTT失败: %v", ch.index, err)
+		}
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+
+	"github.com/gen2brain/malgo"
 )
 
 // LinuxDeviceManager Linux专用音频设备管理器
 type LinuxDeviceManager struct {
 	devices []DeviceInfo
-	context interface{} // 使用interface{}避免编译时类型问题
+	context *malgo.AllocatedContext
 }
 
 // newLinuxDeviceManager 创建新的Linux设备管理器
@@ -22,6 +24,14 @@ func newLinuxDeviceManager() (DeviceManagerInterface, error) {
 		devices: []DeviceInfo{},
 	}
 
+	// 初始化malgo上下文，供音频输入输出打开设备使用
+	context, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		log.Printf("初始化音频上下文失败: %v", err)
+	} else {
+		manager.context = context
+	}
+
 	// 尝试使用系统命令枚举设备
 	if err := manager.enumerateDevicesWithCommands(); err != nil {
 		log.Printf("系统命令枚举失败，回退到malgo: %v", err)
@@ -291,8 +301,42 @@ func (dm *LinuxDeviceManager) tryArecordDevices() bool {
 
 // enumerateDevicesWithMalgo 使用malgo枚举设备（回退方法）
 func (dm *LinuxDeviceManager) enumerateDevicesWithMalgo() error {
-	// 简化版本：直接返回错误，让系统命令处理
-	return fmt.Errorf("malgo回退方法暂不可用")
+	if dm.context == nil {
+		return fmt.Errorf("音频上下文不可用")
+	}
+
+	kinds := []struct {
+		kind       malgo.DeviceType
+		deviceType string
+	}{
+		{malgo.Capture, "input"},
+		{malgo.Playback, "output"},
+	}
+
+	for _, k := range kinds {
+		devices, err := dm.context.Devices(k.kind)
+		if err != nil {
+			return fmt.Errorf("枚举%s设备失败: %w", k.deviceType, err)
+		}
+
+		for _, device := range devices {
+			dm.devices = append(dm.devices, DeviceInfo{
+				ID:          malgoDeviceIDString(device.ID),
+				Name:        strings.TrimSpace(device.Name()),
+				Type:        k.deviceType,
+				SampleRates: []int{8000, 11025, 16000, 22050, 44100, 48000, 96000},
+				Channels:    []int{1, 2},
+				Formats:     []string{"int16", "float32"},
+				IsDefault:   device.IsDefault != 0,
+			})
+		}
+	}
+
+	if len(dm.devices) == 0 {
+		return fmt.Errorf("malgo未找到音频设备")
+	}
+
+	return nil
 }
 
 // ListDevices 列出所有音频设备
@@ -371,17 +415,24 @@ func (dm *LinuxDeviceManager) GetDeviceCount() int {
 // RefreshDevices 刷新设备列表
 func (dm *LinuxDeviceManager) RefreshDevices() error {
 	dm.devices = []DeviceInfo{}
-	return dm.enumerateDevicesWithCommands()
+	if err := dm.enumerateDevicesWithCommands(); err != nil {
+		return dm.enumerateDevicesWithMalgo()
+	}
+	return nil
 }
 
 // Close 关闭设备管理器
 func (dm *LinuxDeviceManager) Close() error {
-	// Linux设备管理器不需要特殊清理
+	if dm.context != nil {
+		dm.context.Uninit()
+		dm.context.Free()
+		dm.context = nil
+	}
 	return nil
 }
 
 // GetContext 获取音频上下文
-func (dm *LinuxDeviceManager) GetContext() interface{} {
+func (dm *LinuxDeviceManager) GetContext() *malgo.AllocatedContext {
 	return dm.context
 }
 
@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package audio
+
+import "fmt"
+
+// newMacOSDeviceManager 创建macOS设备管理器（非macOS系统存根）
+func newMacOSDeviceManager() (DeviceManagerInterface, error) {
+	return nil, fmt.Errorf("macOS设备管理器仅在macOS系统上可用")
+}
@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gen2brain/malgo"
+)
+
+// 支持的PCM采样格式
+const (
+	FormatInt16   = "int16"
+	FormatFloat32 = "float32"
+)
+
+// resolveFormat 解析设备使用的采样格式，未配置时依次回退到处理格式和int16
+func resolveFormat(format string, fallback string) string {
+	if format != "" {
+		return format
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return FormatInt16
+}
+
+// malgoFormat 将配置中的格式名称转换为malgo格式
+func malgoFormat(format string) (malgo.FormatType, error) {
+	switch format {
+	case FormatInt16:
+		return malgo.FormatS16, nil
+	case FormatFloat32:
+		return malgo.FormatF32, nil
+	default:
+		return malgo.FormatUnknown, fmt.Errorf("不支持的音频格式: %s", format)
+	}
+}
+
+// bytesPerSample 获取单个采样占用的字节数
+func bytesPerSample(format string) int {
+	if format == FormatFloat32 {
+		return 4
+	}
+	return 2
+}
+
+// scaleSamples 按系数原地缩放PCM数据
+func scaleSamples(data []byte, format string, factor float64) {
+	if factor == 1.0 {
+		return
+	}
+
+	if format == FormatFloat32 {
+		for j := 0; j+3 < len(data); j += 4 {
+			sample := math.Float32frombits(binary.LittleEndian.Uint32(data[j:]))
+			adjusted := float64(sample) * factor
+
+			// 限制在[-1, 1]范围内
+			if adjusted > 1 {
+				adjusted = 1
+			} else if adjusted < -1 {
+				adjusted = -1
+			}
+
+			binary.LittleEndian.PutUint32(data[j:], math.Float32bits(float32(adjusted)))
+		}
+		return
+	}
+
+	for j := 0; j+1 < len(data); j += 2 {
+		sample := int16(binary.LittleEndian.Uint16(data[j:]))
+		adjusted := float64(sample) * factor
+
+		// 限制在16位范围内
+		if adjusted > 32767 {
+			adjusted = 32767
+		} else if adjusted < -32768 {
+			adjusted = -32768
+		}
+
+		binary.LittleEndian.PutUint16(data[j:], uint16(int16(adjusted)))
+	}
+}
+
+// pcmLevel 计算PCM数据的RMS电平（分贝）
+func pcmLevel(data []byte, format string) float64 {
+	size := bytesPerSample(format)
+	sampleCount := len(data) / size
+	if sampleCount == 0 {
+		return -96.0
+	}
+
+	var sum float64
+	for j := 0; j+size <= len(data); j += size {
+		var sample float64
+		if format == FormatFloat32 {
+			sample = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[j:])))
+		} else {
+			sample = float64(int16(binary.LittleEndian.Uint16(data[j:]))) / 32767.0
+		}
+		sum += sample * sample
+	}
+
+	rms := math.Sqrt(sum / float64(sampleCount))
+	if rms <= 0 {
+		return -96.0 // 静音
+	}
+	return 20 * math.Log10(rms)
+}
+
+// PCMToInt16 将指定格式的PCM数据转换为16位小端PCM
+func PCMToInt16(data []byte, format string) []byte {
+	if format != FormatFloat32 {
+		return data
+	}
+
+	output := make([]byte, len(data)/4*2)
+	for j, k := 0, 0; j+3 < len(data); j, k = j+4, k+2 {
+		sample := float64(math.Float32frombits(binary.LittleEndian.Uint32(data[j:])))
+		if sample > 1 {
+			sample = 1
+		} else if sample < -1 {
+			sample = -1
+		}
+		binary.LittleEndian.PutUint16(output[k:], uint16(int16(sample*32767)))
+	}
+	return output
+}
+
+// Int16ToPCM 将16位小端PCM转换为指定格式
+func Int16ToPCM(data []byte, format string) []byte {
+	if format != FormatFloat32 {
+		return data
+	}
+
+	output := make([]byte, len(data)/2*4)
+	for j, k := 0, 0; j+1 < len(data); j, k = j+2, k+4 {
+		sample := float32(int16(binary.LittleEndian.Uint16(data[j:]))) / 32767.0
+		binary.LittleEndian.PutUint32(output[k:], math.Float32bits(sample))
+	}
+	return output
+}
+
+// malgoDeviceIDString 提取设备ID中的可读名称
+// PulseAudio和ALSA后端的设备ID本身就是以NUL结尾的设备名称（如 "hw:1,0"）
+func malgoDeviceIDString(id malgo.DeviceID) string {
+	end := 0
+	for end < len(id) && id[end] != 0 {
+		end++
+	}
+
+	name := string(id[:end])
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e {
+			// 不可打印的二进制ID，使用十六进制表示
+			return id.String()
+		}
+	}
+	return name
+}
+
+// findMalgoDevice 在malgo上下文中按名称查找设备
+// 名称为空时返回nil，表示使用系统默认设备
+func findMalgoDevice(ctx *malgo.AllocatedContext, kind malgo.DeviceType, name string) (*malgo.DeviceID, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	devices, err := ctx.Devices(kind)
+	if err != nil {
+		return nil, fmt.Errorf("枚举音频设备失败: %w", err)
+	}
+
+	// 优先精确匹配设备名称或ID
+	for i := range devices {
+		if strings.TrimSpace(devices[i].Name()) == name || malgoDeviceIDString(devices[i].ID) == name {
+			return &devices[i].ID, nil
+		}
+	}
+
+	// 其次进行模糊匹配（系统命令枚举的名称可能只是设备名的一部分）
+	for i := range devices {
+		if strings.Contains(devices[i].Name(), name) || strings.Contains(malgoDeviceIDString(devices[i].ID), name) {
+			return &devices[i].ID, nil
+		}
+	}
+
+	return nil, fmt.Errorf("未找到设备: %s", name)
+}
+
+// extractChannel 从交织的16位多声道PCM中提取指定声道，index超出声道数时提取第一个声道
+func extractChannel(data []byte, channels int, index int) []byte {
+	if channels <= 1 {
+		return data
+	}
+	if index < 0 || index >= channels {
+		index = 0
+	}
+
+	frameSize := channels * 2
+	output := make([]byte, 0, len(data)/channels)
+	for j := index * 2; j+1 < len(data); j += frameSize {
+		output = append(output, data[j], data[j+1])
+	}
+	return output
+}
@@ -3,37 +3,248 @@
 package audio
 
 import (
-	"aprs_agent/config"
 	"context"
 	"fmt"
+	"log"
+	"sync"
+
+	"aprs_agent/config"
+
+	"github.com/gen2brain/malgo"
 )
 
-// genericInput 通用音频输入存根
+// genericInput 基于malgo的通用音频输入（Linux/Windows）
 type genericInput struct {
-	config  *config.Config
-	devices DeviceManagerInterface
+	config     *config.Config
+	devices    DeviceManagerInterface
+	device     *malgo.Device
+	isRunning  bool
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	level      float64
+	gain       float64
+	buffer     []byte
+	callback   func([]byte, int)
+	deviceName string
+	format     string
 }
 
 // newGenericInput 创建通用音频输入
 func newGenericInput(cfg *config.Config, devices DeviceManagerInterface) (AudioInput, error) {
-	return &genericInput{
-		config:  cfg,
-		devices: devices,
-	}, nil
+	format := resolveFormat(cfg.Audio.Input.Format, cfg.Audio.Processing.Format)
+	input := &genericInput{
+		config:    cfg,
+		devices:   devices,
+		isRunning: false,
+		level:     -96.0,
+		gain:      cfg.Audio.Input.Gain,
+		format:    format,
+		buffer:    make([]byte, cfg.Audio.Input.BufferSize*cfg.Audio.Input.Channels*bytesPerSample(format)),
+	}
+
+	return input, nil
 }
 
-// 实现AudioInput接口
+// Start 启动音频输入流
 func (g *genericInput) Start(ctx context.Context) error {
-	return fmt.Errorf("通用音频输入暂未实现，请使用平台专用版本")
-}
-
-func (g *genericInput) Stop() error                                 { return nil }
-func (g *genericInput) Close() error                                { return nil }
-func (g *genericInput) GetLevel() float64                           { return 0.0 }
-func (g *genericInput) SetGain(gain float64) error                  { return nil }
-func (g *genericInput) GetGain() float64                            { return 1.0 }
-func (g *genericInput) SetCallback(callback func([]byte, int))      {}
-func (g *genericInput) IsRunning() bool                             { return false }
-func (g *genericInput) UpdateConfig(newConfig *config.Config) error { return nil }
-func (g *genericInput) GetBuffer() []byte                           { return nil }
-func (g *genericInput) GetConfig() *config.Config                   { return g.config }
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.isRunning {
+		return fmt.Errorf("音频输入已在运行")
+	}
+
+	malgoCtx := g.devices.GetContext()
+	if malgoCtx == nil {
+		return fmt.Errorf("音频上下文不可用")
+	}
+
+	inputCfg := g.config.Audio.Input
+	sampleFormat, err := malgoFormat(g.format)
+	if err != nil {
+		return err
+	}
+
+	// 检查设备支持（仅对设备管理器已知的设备进行检查）
+	deviceName := inputCfg.DeviceName
+	if deviceName != "" {
+		if _, err := g.devices.GetDeviceByName(deviceName, "input"); err == nil &&
+			!g.devices.IsDeviceSupported(deviceName, "input", inputCfg.SampleRate, inputCfg.Channels, g.format) {
+			return fmt.Errorf("设备 %s 不支持指定的配置", deviceName)
+		}
+	}
+
+	deviceID, err := findMalgoDevice(malgoCtx, malgo.Capture, deviceName)
+	if err != nil {
+		return fmt.Errorf("查找输入设备失败: %w", err)
+	}
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
+	deviceConfig.SampleRate = uint32(inputCfg.SampleRate)
+	deviceConfig.PeriodSizeInFrames = uint32(inputCfg.BufferSize)
+	deviceConfig.Capture.Format = sampleFormat
+	deviceConfig.Capture.Channels = uint32(inputCfg.Channels)
+	deviceConfig.Pulse.StreamNameCapture = "aprs_agent"
+	if deviceID != nil {
+		deviceConfig.Capture.DeviceID = deviceID.Pointer()
+	}
+
+	device, err := malgo.InitDevice(malgoCtx.Context, deviceConfig, malgo.DeviceCallbacks{
+		Data: g.onData,
+	})
+	if err != nil {
+		return fmt.Errorf("初始化输入设备失败: %w", err)
+	}
+
+	if err := device.Start(); err != nil {
+		device.Uninit()
+		return fmt.Errorf("启动输入设备失败: %w", err)
+	}
+
+	if deviceName == "" {
+		deviceName = "默认设备"
+	}
+	g.device = device
+	g.deviceName = deviceName
+	g.isRunning = true
+	g.ctx, g.cancel = context.WithCancel(ctx)
+
+	// 上下文取消时自动停止
+	go func() {
+		<-g.ctx.Done()
+		g.Stop()
+	}()
+
+	log.Printf("音频输入已启动: %s (%dHz, %d声道, %s)", deviceName, inputCfg.SampleRate, inputCfg.Channels, g.format)
+	return nil
+}
+
+// onData malgo采集回调
+func (g *genericInput) onData(_, input []byte, frameCount uint32) {
+	if len(input) == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	if len(g.buffer) != len(input) {
+		g.buffer = make([]byte, len(input))
+	}
+	copy(g.buffer, input)
+	scaleSamples(g.buffer, g.format, g.gain)
+	g.level = pcmLevel(g.buffer, g.format)
+
+	// 回调使用独立的数据副本，避免被下一次采集覆盖
+	data := make([]byte, len(g.buffer))
+	copy(data, g.buffer)
+	callback := g.callback
+	g.mu.Unlock()
+
+	if callback != nil {
+		callback(data, int(frameCount))
+	}
+}
+
+// Stop 停止音频输入流
+func (g *genericInput) Stop() error {
+	g.mu.Lock()
+	if !g.isRunning {
+		g.mu.Unlock()
+		return nil
+	}
+
+	device := g.device
+	g.device = nil
+	g.isRunning = false
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.mu.Unlock()
+
+	// 在锁外停止设备，避免与采集回调死锁
+	if device != nil {
+		if err := device.Stop(); err != nil {
+			log.Printf("停止输入设备失败: %v", err)
+		}
+		device.Uninit()
+	}
+
+	log.Println("音频输入已停止")
+	return nil
+}
+
+// Close 关闭音频输入
+func (g *genericInput) Close() error {
+	return g.Stop()
+}
+
+// GetLevel 获取当前音频级别
+func (g *genericInput) GetLevel() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.level
+}
+
+// SetGain 设置增益
+func (g *genericInput) SetGain(gain float64) error {
+	if gain < 0.0 || gain > 2.0 {
+		return fmt.Errorf("增益必须在0.0-2.0之间")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.gain = gain
+	return nil
+}
+
+// GetGain 获取当前增益
+func (g *genericInput) GetGain() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.gain
+}
+
+// SetCallback 设置音频数据回调函数，数据格式与配置的采样格式一致
+func (g *genericInput) SetCallback(callback func([]byte, int)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.callback = callback
+}
+
+// IsRunning 检查是否正在运行
+func (g *genericInput) IsRunning() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.isRunning
+}
+
+// UpdateConfig 更新配置
+func (g *genericInput) UpdateConfig(newConfig *config.Config) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.isRunning {
+		return fmt.Errorf("无法在运行时更新配置")
+	}
+
+	g.config = newConfig
+	g.gain = newConfig.Audio.Input.Gain
+	g.format = resolveFormat(newConfig.Audio.Input.Format, newConfig.Audio.Processing.Format)
+
+	// 重新分配缓冲区
+	g.buffer = make([]byte, newConfig.Audio.Input.BufferSize*newConfig.Audio.Input.Channels*bytesPerSample(g.format))
+
+	return nil
+}
+
+// GetBuffer 获取当前音频缓冲区
+func (g *genericInput) GetBuffer() []byte {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.buffer
+}
+
+// GetConfig 获取当前配置
+func (g *genericInput) GetConfig() *config.Config {
+	return g.config
+}
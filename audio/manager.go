@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"runtime"
+	"strings"
 	"sync"
 
+	"aprs_agent/ax25"
 	"aprs_agent/config"
+	"aprs_agent/csma"
+	"aprs_agent/gps"
+	"aprs_agent/modem"
 )
 
 // AudioInput 音频输入接口
@@ -30,7 +34,7 @@ type AudioOutput interface {
 	Start(ctx context.Context) error
 	Stop() error
 	Close() error
-	PlayAudio(data []byte) error
+	PlayAudio(channel int, data []byte) error // 按无线电通道排队，不同通道的音频混音播放
 	GetLevel() float64
 	SetVolume(volume float64) error
 	GetVolume() float64
@@ -38,21 +42,30 @@ type AudioOutput interface {
 	UpdateConfig(newConfig *config.Config) error
 	GetBuffer() []byte
 	GetConfig() *config.Config
-	GetQueueSize() int
-	ClearQueue()
+	GetQueueSize(channel int) int
+	ClearQueue(channel int)
 }
 
 // Manager 音频管理器
+// 每个不同的通道设备打开一块声卡，input、output和aprsProcessor为通道0所用声卡的输入输出和处理器
 type Manager struct {
 	config        *config.Config
 	input         AudioInput
 	output        AudioOutput
 	devices       DeviceManagerInterface
 	aprsProcessor *APRSProcessor
+	cards         []*soundCard
+	channels      []*radioChannel
 	mu            sync.RWMutex
 	isRunning     bool
 	ctx           context.Context
 	cancel        context.CancelFunc
+
+	// 接收帧处理函数，使用独立的锁避免与音频回调死锁
+	handlersMu    sync.RWMutex
+	frameHandlers []func(modem.Frame)
+
+	position func() (gps.Fix, bool) // GPS定位，为nil时状态中只有本站位置
 }
 
 // NewManager 创建新的音频管理器
@@ -65,48 +78,230 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	manager := &Manager{
-		config:        cfg,
-		devices:       devices,
-		aprsProcessor: NewAPRSProcessor(),
-		ctx:           ctx,
-		cancel:        cancel,
-		isRunning:     false,
-	}
-
-	// 根据平台创建音频输入输出
-	if runtime.GOOS == "darwin" {
-		// macOS平台
-		input, err := newMacOSInput(cfg, devices)
-		if err != nil {
-			cancel()
-			return nil, fmt.Errorf("创建macOS音频输入失败: %w", err)
-		}
-		manager.input = input
+		config:    cfg,
+		devices:   devices,
+		ctx:       ctx,
+		cancel:    cancel,
+		isRunning: false,
+	}
 
-		output, err := newMacOSOutput(cfg, devices)
-		if err != nil {
-			cancel()
-			return nil, fmt.Errorf("创建macOS音频输出失败: %w", err)
-		}
-		manager.output = output
+	if err := manager.setup(cfg, nil); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+// setup 按通道配置打开声卡并创建各通道的链路，processors为沿用的APRS处理器
+// 接收链路: 声卡输入 -> APRS处理器 -> 按声道拆分 -> 各通道的解调器 -> HDLC解帧器
+// 发射链路: AX.25帧 -> AFSK调制 -> 限幅 -> 写入通道的声道 -> 通道所用声卡的输出
+func (m *Manager) setup(cfg *config.Config, processors map[string]*APRSProcessor) error {
+	cards, channelCards, err := openSoundCards(cfg, m.devices, processors)
+	if err != nil {
+		return err
+	}
+
+	channels, err := newRadioChannels(cfg, m.devices, channelCards, m.dispatchFrame)
+	if err != nil {
+		closeSoundCards(cards)
+		return err
+	}
+
+	for _, card := range cards {
+		card := card
+		card.input.SetCallback(func(data []byte, frameCount int) {
+			m.onAudio(card, data, frameCount)
+		})
+	}
+
+	m.cards = cards
+	m.channels = channels
+	m.input = channelCards[0].input
+	m.output = channelCards[0].output
+	m.aprsProcessor = channelCards[0].processor
+	return nil
+}
+
+// channelConfigs 获取通道配置，未经LoadConfig加载的配置没有通道时使用 [audio.*] 生成通道0
+func channelConfigs(cfg *config.Config) []config.ChannelConfig {
+	if channels := cfg.GetChannelConfigs(); len(channels) > 0 {
+		return channels
+	}
+	return []config.ChannelConfig{{
+		Device:     cfg.Audio.Input.DeviceName,
+		Side:       config.SideMono,
+		TxDelay:    cfg.Audio.Transmit.TxDelay,
+		TxTail:     cfg.Audio.Transmit.TxTail,
+		Persist:    cfg.Audio.Transmit.Persist,
+		SlotTime:   cfg.Audio.Transmit.SlotTime,
+		FullDuplex: cfg.Audio.Transmit.FullDuplex,
+	}}
+}
+
+// onAudio 声卡输入回调，将采集数据送入使用该声卡的各通道的接收链路
+func (m *Manager) onAudio(card *soundCard, data []byte, frameCount int) {
+	inputCfg := card.config.Audio.Input
+	format := resolveFormat(inputCfg.Format, card.config.Audio.Processing.Format)
+
+	pcm := PCMToInt16(data, format)
+	if card.config.System.APRSMode {
+		pcm = card.processor.ProcessAudio(pcm, inputCfg.SampleRate, inputCfg.Channels)
 	} else {
-		// 其他平台使用通用实现
-		input, err := newGenericInput(cfg, devices)
-		if err != nil {
-			cancel()
-			return nil, fmt.Errorf("创建通用音频输入失败: %w", err)
-		}
-		manager.input = input
+		card.processor.MeasureLevels(pcm, inputCfg.Channels)
+	}
+
+	// 解调器只处理单声道16位PCM，每个通道取出自己的声道
+	for _, ch := range card.channels {
+		ch.demodulator.ProcessInt16(extractChannel(pcm, inputCfg.Channels, ch.input))
+	}
+}
+
+// ChannelCount 获取无线电通道数
+func (m *Manager) ChannelCount() int {
+	return len(m.channels)
+}
+
+// transmitterFor 获取指定通道的发射器
+func (m *Manager) transmitterFor(channel int) (*Transmitter, error) {
+	if channel < 0 || channel >= m.ChannelCount() {
+		return nil, fmt.Errorf("无效的通道: %d", channel)
+	}
+	return m.channels[channel].transmitter, nil
+}
+
+// Transmit 在指定通道发送AX.25帧（不含FCS），帧以普通优先级进入发射队列
+func (m *Manager) Transmit(channel int, data []byte) error {
+	return m.TransmitPriority(channel, data, csma.PriorityNormal)
+}
+
+// TransmitPriority 以指定优先级将AX.25帧（不含FCS）加入通道的发射队列
+// 帧在信道空闲并按p-persistence获得发射机会后发出，全双工通道不检测信道
+func (m *Manager) TransmitPriority(channel int, data []byte, priority csma.Priority) error {
+	if channel < 0 || channel >= m.ChannelCount() {
+		return fmt.Errorf("无效的通道: %d", channel)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("帧数据为空")
+	}
+	ch := m.channels[channel]
+	if !ch.card.output.IsRunning() {
+		return fmt.Errorf("音频输出未运行")
+	}
+	return ch.scheduler.Enqueue(data, priority)
+}
+
+// GetTxQueueSize 获取指定通道等待发射的帧数，包括发射队列和音频输出队列
+func (m *Manager) GetTxQueueSize(channel int) int {
+	if channel < 0 || channel >= m.ChannelCount() {
+		return 0
+	}
+	ch := m.channels[channel]
+	return ch.scheduler.Len() + ch.card.output.GetQueueSize(channel)
+}
 
-		output, err := newGenericOutput(cfg, devices)
-		if err != nil {
-			cancel()
-			return nil, fmt.Errorf("创建通用音频输出失败: %w", err)
+// DCD 指定通道是否检测到载波
+func (m *Manager) DCD(channel int) bool {
+	if channel < 0 || channel >= m.ChannelCount() {
+		return false
+	}
+	return m.channels[channel].dcd()
+}
+
+// ChannelDeviceName 获取指定通道使用的输入设备名称
+// 配置中的名称可能为空（默认设备）或只是设备名的一部分，这里返回设备管理器中的实际名称
+func (m *Manager) ChannelDeviceName(channel int) string {
+	if _, err := m.transmitterFor(channel); err != nil {
+		return ""
+	}
+	card := m.channels[channel].card
+	return resolveDeviceName(m.devices, card.config.Audio.Input.DeviceName)
+}
+
+// resolveDeviceName 在设备管理器中查找配置的输入设备，返回实际名称
+func resolveDeviceName(devices DeviceManagerInterface, configured string) string {
+	if devices == nil {
+		return configured
+	}
+
+	var match string
+	for _, device := range devices.GetAllDevices() {
+		if device.Type != "input" {
+			continue
+		}
+		switch {
+		case configured == "" && device.IsDefault:
+			return device.Name
+		case configured != "" && device.Name == configured:
+			return device.Name
+		case configured != "" && match == "" && strings.Contains(device.Name, configured):
+			match = device.Name
 		}
-		manager.output = output
 	}
 
-	return manager, nil
+	if match != "" {
+		return match
+	}
+	if configured == "" {
+		return "default"
+	}
+	return configured
+}
+
+// GetDevices 获取设备管理器
+func (m *Manager) GetDevices() DeviceManagerInterface {
+	return m.devices
+}
+
+// GetTxParams 获取指定通道的发射参数
+func (m *Manager) GetTxParams(channel int) (TxParams, error) {
+	transmitter, err := m.transmitterFor(channel)
+	if err != nil {
+		return TxParams{}, err
+	}
+	return transmitter.GetParams(), nil
+}
+
+// SetTxParams 设置指定通道的发射参数
+func (m *Manager) SetTxParams(channel int, params TxParams) error {
+	transmitter, err := m.transmitterFor(channel)
+	if err != nil {
+		return err
+	}
+	transmitter.SetParams(params)
+	return nil
+}
+
+// TransmitFrame 在指定通道发送AX.25帧
+func (m *Manager) TransmitFrame(channel int, frame *ax25.Frame) error {
+	data, err := frame.Encode()
+	if err != nil {
+		return fmt.Errorf("编码AX.25帧失败: %w", err)
+	}
+	return m.Transmit(channel, data)
+}
+
+// GetTransmitter 获取通道0的发射器
+func (m *Manager) GetTransmitter() *Transmitter {
+	return m.channels[0].transmitter
+}
+
+// AddFrameHandler 添加接收帧处理函数，每个校验通过的帧都会分发给所有处理函数
+func (m *Manager) AddFrameHandler(handler func(modem.Frame)) {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	m.frameHandlers = append(m.frameHandlers, handler)
+}
+
+// dispatchFrame 将接收到的帧分发给所有处理函数
+func (m *Manager) dispatchFrame(frame modem.Frame) {
+	m.handlersMu.RLock()
+	handlers := m.frameHandlers
+	m.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(frame)
+	}
 }
 
 // StartInput 启动音频输入流
@@ -118,8 +313,13 @@ func (m *Manager) StartInput(ctx context.Context) error {
 		return fmt.Errorf("音频管理器已在运行")
 	}
 
-	if err := m.input.Start(ctx); err != nil {
-		return fmt.Errorf("启动音频输入失败: %w", err)
+	for i, card := range m.cards {
+		if err := card.input.Start(ctx); err != nil {
+			for _, started := range m.cards[:i] {
+				started.input.Stop()
+			}
+			return fmt.Errorf("启动音频输入失败: %w", err)
+		}
 	}
 
 	m.isRunning = true
@@ -136,8 +336,16 @@ func (m *Manager) StartOutput(ctx context.Context) error {
 		return fmt.Errorf("音频输入流未启动")
 	}
 
-	if err := m.output.Start(ctx); err != nil {
-		return fmt.Errorf("启动音频输出失败: %w", err)
+	for i, card := range m.cards {
+		if err := card.output.Start(ctx); err != nil {
+			for _, started := range m.cards[:i] {
+				started.output.Stop()
+			}
+			return fmt.Errorf("启动音频输出失败: %w", err)
+		}
+	}
+	for _, ch := range m.channels {
+		ch.scheduler.Start(ctx)
 	}
 
 	log.Println("音频输出流已启动")
@@ -153,12 +361,18 @@ func (m *Manager) Stop() error {
 		return nil
 	}
 
-	if err := m.input.Stop(); err != nil {
-		log.Printf("停止音频输入失败: %v", err)
+	// 先停止发射调度，未发出的帧留在队列中
+	for _, ch := range m.channels {
+		ch.scheduler.Stop()
 	}
 
-	if err := m.output.Stop(); err != nil {
-		log.Printf("停止音频输出失败: %v", err)
+	for _, card := range m.cards {
+		if err := card.input.Stop(); err != nil {
+			log.Printf("停止音频输入失败: %v", err)
+		}
+		if err := card.output.Stop(); err != nil {
+			log.Printf("停止音频输出失败: %v", err)
+		}
 	}
 
 	m.isRunning = false
@@ -171,17 +385,8 @@ func (m *Manager) Close() error {
 	m.Stop()
 	m.cancel()
 
-	if m.input != nil {
-		if err := m.input.Close(); err != nil {
-			log.Printf("关闭音频输入失败: %v", err)
-		}
-	}
-
-	if m.output != nil {
-		if err := m.output.Close(); err != nil {
-			log.Printf("关闭音频输出失败: %v", err)
-		}
-	}
+	closeSoundCards(m.cards)
+	closeChannels(m.channels)
 
 	if m.devices != nil {
 		if err := m.devices.Close(); err != nil {
@@ -199,7 +404,7 @@ func (m *Manager) ListDevices() {
 	}
 }
 
-// GetInputLevel 获取输入音量级别
+// GetInputLevel 获取通道0所用声卡的输入音量级别
 func (m *Manager) GetInputLevel() float64 {
 	if m.input != nil {
 		return m.input.GetLevel()
@@ -207,7 +412,7 @@ func (m *Manager) GetInputLevel() float64 {
 	return 0.0
 }
 
-// GetOutputLevel 获取输出音量级别
+// GetOutputLevel 获取通道0所用声卡的输出音量级别
 func (m *Manager) GetOutputLevel() float64 {
 	if m.output != nil {
 		return m.output.GetLevel()
@@ -215,20 +420,30 @@ func (m *Manager) GetOutputLevel() float64 {
 	return 0.0
 }
 
-// SetInputGain 设置输入增益
+// SetInputGain 设置所有声卡的输入增益
 func (m *Manager) SetInputGain(gain float64) error {
-	if m.input != nil {
-		return m.input.SetGain(gain)
+	if len(m.cards) == 0 {
+		return fmt.Errorf("音频输入未初始化")
 	}
-	return fmt.Errorf("音频输入未初始化")
+	for _, card := range m.cards {
+		if err := card.input.SetGain(gain); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// SetOutputVolume 设置输出音量
+// SetOutputVolume 设置所有声卡的输出音量
 func (m *Manager) SetOutputVolume(volume float64) error {
-	if m.output != nil {
-		return m.output.SetVolume(volume)
+	if len(m.cards) == 0 {
+		return fmt.Errorf("音频输出未初始化")
 	}
-	return fmt.Errorf("音频输出未初始化")
+	for _, card := range m.cards {
+		if err := card.output.SetVolume(volume); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // IsRunning 检查音频管理器是否正在运行
@@ -254,48 +469,133 @@ func (m *Manager) UpdateConfig(newConfig *config.Config) error {
 
 	m.config = newConfig
 
-	// 重新初始化音频组件
-	if err := m.input.UpdateConfig(newConfig); err != nil {
-		return fmt.Errorf("更新输入配置失败: %w", err)
-	}
-
-	if err := m.output.UpdateConfig(newConfig); err != nil {
-		return fmt.Errorf("更新输出配置失败: %w", err)
+	// 通道的设备、采样率和声道配置可能改变，重新打开声卡并重建各通道的调制解调器和PTT
+	// 同一设备沿用原来的APRS处理器，保留运行时设置的处理参数
+	processors := make(map[string]*APRSProcessor)
+	for _, card := range m.cards {
+		processors[config.DeviceKey(card.device)] = card.processor
 	}
+	closeChannels(m.channels)
+	closeSoundCards(m.cards)
+	m.cards, m.channels = nil, nil
 
-	return nil
+	return m.setup(newConfig, processors)
 }
 
-// GetAPRSProcessor 获取APRS音频处理器
+// GetAPRSProcessor 获取通道0所用声卡的APRS音频处理器
 func (m *Manager) GetAPRSProcessor() *APRSProcessor {
 	return m.aprsProcessor
 }
 
-// GetAPRSStatus 获取APRS处理器状态
+// GetDemodulator 获取通道0的AFSK解调器
+func (m *Manager) GetDemodulator() *modem.Demodulator {
+	return m.channels[0].demodulator
+}
+
+// GetDeframerStats 获取所有通道的HDLC解帧统计信息之和
+func (m *Manager) GetDeframerStats() modem.DeframerStats {
+	var total modem.DeframerStats
+	for _, ch := range m.channels {
+		stats := ch.deframer.GetStats()
+		total.Frames += stats.Frames
+		total.FCSErrors += stats.FCSErrors
+		total.Aborts += stats.Aborts
+		total.TooShort += stats.TooShort
+		total.TooLong += stats.TooLong
+		total.Unaligned += stats.Unaligned
+	}
+	return total
+}
+
+// GetAPRSStatus 获取APRS处理器状态（包含解码统计，便于评估门限和压缩设置对解码的影响）和发射保护统计
 func (m *Manager) GetAPRSStatus() map[string]interface{} {
-	if m.aprsProcessor != nil {
-		return m.aprsProcessor.GetStatus()
+	if m.aprsProcessor == nil {
+		return nil
 	}
-	return nil
+
+	status := m.aprsProcessor.GetStatus()
+	stats := m.GetDeframerStats()
+	status["frames_decoded"] = stats.Frames
+	status["fcs_errors"] = stats.FCSErrors
+	status["frame_aborts"] = stats.Aborts
+	status["frames_too_short"] = stats.TooShort
+	status["frames_too_long"] = stats.TooLong
+	status["frames_unaligned"] = stats.Unaligned
+
+	// 发射保护：看门狗触发次数和各通道统计窗口内的发射占空比
+	var trips uint64
+	dutyCycles := make([]float64, len(m.channels))
+	for i, ch := range m.channels {
+		guard := ch.transmitter.GetGuardStats()
+		trips += guard.WatchdogTrips
+		dutyCycles[i] = guard.DutyCycle
+	}
+	status["tx_watchdog_trips"] = trips
+	status["tx_duty_cycles"] = dutyCycles
+
+	m.addPositionStatus(status)
+	return status
+}
+
+// SetPositionSource 设置GPS定位来源，如 gps.Receiver.Fix
+func (m *Manager) SetPositionSource(source func() (gps.Fix, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.position = source
+}
+
+// addPositionStatus 在状态中加入本站位置：有GPS定位时使用GPS，否则使用配置的本站位置
+func (m *Manager) addPositionStatus(status map[string]interface{}) {
+	m.mu.RLock()
+	source := m.position
+	m.mu.RUnlock()
+
+	if source != nil {
+		if fix, ok := source(); ok {
+			status["position_source"] = "gps"
+			status["latitude"] = fix.Latitude
+			status["longitude"] = fix.Longitude
+			if fix.HasAltitude {
+				status["altitude"] = fix.Altitude
+			}
+			if fix.HasCourseSpeed {
+				status["course"] = fix.Course
+				status["speed"] = fix.Speed
+			}
+			status["gps_time"] = fix.Time
+			status["satellites"] = fix.Satellites
+			return
+		}
+	}
+
+	station := m.config.Station
+	if !station.HasLocation() {
+		status["position_source"] = "none"
+		return
+	}
+	status["position_source"] = "station"
+	status["latitude"] = station.Latitude
+	status["longitude"] = station.Longitude
+	status["altitude"] = station.Altitude
 }
 
-// SetAPRSNoiseGate 设置APRS噪声门限
+// SetAPRSNoiseGate 设置所有声卡的APRS噪声门限
 func (m *Manager) SetAPRSNoiseGate(threshold float64) {
-	if m.aprsProcessor != nil {
-		m.aprsProcessor.SetNoiseGateThreshold(threshold)
+	for _, card := range m.cards {
+		card.processor.SetNoiseGateThreshold(threshold)
 	}
 }
 
-// SetAPRSCompression 设置APRS压缩比
+// SetAPRSCompression 设置所有声卡的APRS压缩比
 func (m *Manager) SetAPRSCompression(ratio float64) {
-	if m.aprsProcessor != nil {
-		m.aprsProcessor.SetCompressionRatio(ratio)
+	for _, card := range m.cards {
+		card.processor.SetCompressionRatio(ratio)
 	}
 }
 
-// SetAPRSPeakThreshold 设置APRS峰值门限
+// SetAPRSPeakThreshold 设置所有声卡的APRS峰值门限
 func (m *Manager) SetAPRSPeakThreshold(threshold float64) {
-	if m.aprsProcessor != nil {
-		m.aprsProcessor.SetPeakThreshold(threshold)
+	for _, card := range m.cards {
+		card.processor.SetPeakThreshold(threshold)
 	}
 }
@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// mixQueueLimit 每个无线电通道最多排队的音频块数
+const mixQueueLimit = 10
+
+// mixQueue 按无线电通道分开的音频播放队列
+// 同一通道的音频块按顺序播放；不同通道同时有音频时逐样本叠加后输出，
+// 立体声声卡的左右声道各接一部电台时，两个通道可以同时发射，互不等待
+type mixQueue struct {
+	mu     sync.Mutex
+	format string
+	queues map[int][][]byte // 每个通道等待播放的音频块，第一块为正在播放的剩余部分
+}
+
+// newMixQueue 创建播放队列，format为音频块的采样格式
+func newMixQueue(format string) *mixQueue {
+	return &mixQueue{
+		format: format,
+		queues: make(map[int][][]byte),
+	}
+}
+
+// push 将音频块加入通道的队列
+func (q *mixQueue) push(channel int, data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.queues[channel]) >= mixQueueLimit {
+		return fmt.Errorf("通道 %d 音频队列已满", channel)
+	}
+	if len(data) > 0 {
+		q.queues[channel] = append(q.queues[channel], data)
+	}
+	return nil
+}
+
+// size 获取通道队列中的音频块数（包含正在播放的数据块）
+func (q *mixQueue) size(channel int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queues[channel])
+}
+
+// clear 清空通道的队列，不影响其他通道
+func (q *mixQueue) clear(channel int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.queues, channel)
+}
+
+// fill 从各通道的队列中取出数据叠加到output，返回有音频的字节数，其余部分为静音
+func (q *mixQueue) fill(output []byte) int {
+	for j := range output {
+		output[j] = 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	written := 0
+	for channel, chunks := range q.queues {
+		n := 0
+		for n < len(output) && len(chunks) > 0 {
+			m := min(len(output)-n, len(chunks[0]))
+			mixSamples(output[n:n+m], chunks[0][:m], q.format)
+			n += m
+			if m == len(chunks[0]) {
+				chunks = chunks[1:]
+			} else {
+				chunks[0] = chunks[0][m:]
+			}
+		}
+		written = max(written, n)
+
+		if len(chunks) == 0 {
+			delete(q.queues, channel)
+		} else {
+			q.queues[channel] = chunks
+		}
+	}
+	return written
+}
+
+// mixSamples 将src逐样本叠加到dst，超出范围时限幅
+func mixSamples(dst, src []byte, format string) {
+	if format == FormatFloat32 {
+		for j := 0; j+3 < len(src); j += 4 {
+			a := math.Float32frombits(binary.LittleEndian.Uint32(dst[j:]))
+			b := math.Float32frombits(binary.LittleEndian.Uint32(src[j:]))
+			sum := max(-1, min(1, a+b))
+			binary.LittleEndian.PutUint32(dst[j:], math.Float32bits(sum))
+		}
+		return
+	}
+
+	for j := 0; j+1 < len(src); j += 2 {
+		a := int32(int16(binary.LittleEndian.Uint16(dst[j:])))
+		b := int32(int16(binary.LittleEndian.Uint16(src[j:])))
+		sum := max(-32768, min(32767, a+b))
+		binary.LittleEndian.PutUint16(dst[j:], uint16(int16(sum)))
+	}
+}
@@ -3,39 +3,258 @@
 package audio
 
 import (
-	"aprs_agent/config"
 	"context"
 	"fmt"
+	"log"
+	"sync"
+
+	"aprs_agent/config"
+
+	"github.com/gen2brain/malgo"
 )
 
-// genericOutput 通用音频输出存根
+// genericOutput 基于malgo的通用音频输出（Linux/Windows）
 type genericOutput struct {
-	config  *config.Config
-	devices DeviceManagerInterface
+	config     *config.Config
+	devices    DeviceManagerInterface
+	device     *malgo.Device
+	isRunning  bool
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	level      float64
+	volume     float64
+	buffer     []byte
+	queue      *mixQueue // 各无线电通道的播放队列，由播放回调混音输出
+	deviceName string
+	format     string
 }
 
 // newGenericOutput 创建通用音频输出
 func newGenericOutput(cfg *config.Config, devices DeviceManagerInterface) (AudioOutput, error) {
-	return &genericOutput{
-		config:  cfg,
-		devices: devices,
-	}, nil
+	format := resolveFormat(cfg.Audio.Output.Format, cfg.Audio.Processing.Format)
+	output := &genericOutput{
+		config:    cfg,
+		devices:   devices,
+		isRunning: false,
+		level:     -96.0,
+		volume:    cfg.Audio.Output.Volume,
+		format:    format,
+		buffer:    make([]byte, cfg.Audio.Output.BufferSize*cfg.Audio.Output.Channels*bytesPerSample(format)),
+		queue:     newMixQueue(format),
+	}
+
+	return output, nil
 }
 
-// 实现AudioOutput接口
+// Start 启动音频输出流
 func (g *genericOutput) Start(ctx context.Context) error {
-	return fmt.Errorf("通用音频输出暂未实现，请使用平台专用版本")
-}
-
-func (g *genericOutput) Stop() error                                 { return nil }
-func (g *genericOutput) Close() error                                { return nil }
-func (g *genericOutput) PlayAudio(data []byte) error                 { return nil }
-func (g *genericOutput) GetLevel() float64                           { return 0.0 }
-func (g *genericOutput) SetVolume(volume float64) error              { return nil }
-func (g *genericOutput) GetVolume() float64                          { return 1.0 }
-func (g *genericOutput) IsRunning() bool                             { return false }
-func (g *genericOutput) UpdateConfig(newConfig *config.Config) error { return nil }
-func (g *genericOutput) GetBuffer() []byte                           { return nil }
-func (g *genericOutput) GetConfig() *config.Config                   { return g.config }
-func (g *genericOutput) GetQueueSize() int                           { return 0 }
-func (g *genericOutput) ClearQueue()                                 {}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.isRunning {
+		return fmt.Errorf("音频输出已在运行")
+	}
+
+	malgoCtx := g.devices.GetContext()
+	if malgoCtx == nil {
+		return fmt.Errorf("音频上下文不可用")
+	}
+
+	outputCfg := g.config.Audio.Output
+	sampleFormat, err := malgoFormat(g.format)
+	if err != nil {
+		return err
+	}
+
+	// 检查设备支持（仅对设备管理器已知的设备进行检查）
+	deviceName := outputCfg.DeviceName
+	if deviceName != "" {
+		if _, err := g.devices.GetDeviceByName(deviceName, "output"); err == nil &&
+			!g.devices.IsDeviceSupported(deviceName, "output", outputCfg.SampleRate, outputCfg.Channels, g.format) {
+			return fmt.Errorf("设备 %s 不支持指定的配置", deviceName)
+		}
+	}
+
+	deviceID, err := findMalgoDevice(malgoCtx, malgo.Playback, deviceName)
+	if err != nil {
+		return fmt.Errorf("查找输出设备失败: %w", err)
+	}
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.SampleRate = uint32(outputCfg.SampleRate)
+	deviceConfig.PeriodSizeInFrames = uint32(outputCfg.BufferSize)
+	deviceConfig.Playback.Format = sampleFormat
+	deviceConfig.Playback.Channels = uint32(outputCfg.Channels)
+	deviceConfig.Pulse.StreamNamePlayback = "aprs_agent"
+	if deviceID != nil {
+		deviceConfig.Playback.DeviceID = deviceID.Pointer()
+	}
+
+	device, err := malgo.InitDevice(malgoCtx.Context, deviceConfig, malgo.DeviceCallbacks{
+		Data: g.onData,
+	})
+	if err != nil {
+		return fmt.Errorf("初始化输出设备失败: %w", err)
+	}
+
+	if err := device.Start(); err != nil {
+		device.Uninit()
+		return fmt.Errorf("启动输出设备失败: %w", err)
+	}
+
+	if deviceName == "" {
+		deviceName = "默认设备"
+	}
+	g.device = device
+	g.deviceName = deviceName
+	g.isRunning = true
+	g.ctx, g.cancel = context.WithCancel(ctx)
+
+	// 上下文取消时自动停止
+	go func() {
+		<-g.ctx.Done()
+		g.Stop()
+	}()
+
+	log.Printf("音频输出已启动: %s (%dHz, %d声道, %s)", deviceName, outputCfg.SampleRate, outputCfg.Channels, g.format)
+	return nil
+}
+
+// onData malgo播放回调，从各通道的队列中取出数据混音后填充输出缓冲区，队列为空时输出静音
+func (g *genericOutput) onData(output, _ []byte, _ uint32) {
+	g.mu.RLock()
+	volume := g.volume
+	queue := g.queue
+	g.mu.RUnlock()
+
+	written := queue.fill(output)
+	scaleSamples(output[:written], g.format, volume)
+
+	g.mu.Lock()
+	g.level = pcmLevel(output, g.format)
+	g.mu.Unlock()
+}
+
+// Stop 停止音频输出流
+func (g *genericOutput) Stop() error {
+	g.mu.Lock()
+	if !g.isRunning {
+		g.mu.Unlock()
+		return nil
+	}
+
+	device := g.device
+	g.device = nil
+	g.isRunning = false
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.mu.Unlock()
+
+	// 在锁外停止设备，避免与播放回调死锁
+	if device != nil {
+		if err := device.Stop(); err != nil {
+			log.Printf("停止输出设备失败: %v", err)
+		}
+		device.Uninit()
+	}
+
+	log.Println("音频输出已停止")
+	return nil
+}
+
+// Close 关闭音频输出
+func (g *genericOutput) Close() error {
+	return g.Stop()
+}
+
+// PlayAudio 将无线电通道的音频数据加入播放队列，数据格式与配置的采样格式一致
+func (g *genericOutput) PlayAudio(channel int, data []byte) error {
+	g.mu.RLock()
+	running, queue := g.isRunning, g.queue
+	g.mu.RUnlock()
+	if !running {
+		return fmt.Errorf("音频输出未运行")
+	}
+	return queue.push(channel, data)
+}
+
+// GetLevel 获取当前音频级别
+func (g *genericOutput) GetLevel() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.level
+}
+
+// SetVolume 设置音量
+func (g *genericOutput) SetVolume(volume float64) error {
+	if volume < 0.0 || volume > 1.0 {
+		return fmt.Errorf("音量必须在0.0-1.0之间")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.volume = volume
+	return nil
+}
+
+// GetVolume 获取当前音量
+func (g *genericOutput) GetVolume() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.volume
+}
+
+// IsRunning 检查是否正在运行
+func (g *genericOutput) IsRunning() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.isRunning
+}
+
+// UpdateConfig 更新配置
+func (g *genericOutput) UpdateConfig(newConfig *config.Config) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.isRunning {
+		return fmt.Errorf("无法在运行时更新配置")
+	}
+
+	g.config = newConfig
+	g.volume = newConfig.Audio.Output.Volume
+	g.format = resolveFormat(newConfig.Audio.Output.Format, newConfig.Audio.Processing.Format)
+	g.queue = newMixQueue(g.format)
+
+	// 重新分配缓冲区
+	g.buffer = make([]byte, newConfig.Audio.Output.BufferSize*newConfig.Audio.Output.Channels*bytesPerSample(g.format))
+
+	return nil
+}
+
+// GetBuffer 获取当前音频缓冲区
+func (g *genericOutput) GetBuffer() []byte {
+	return g.buffer
+}
+
+// GetConfig 获取当前配置
+func (g *genericOutput) GetConfig() *config.Config {
+	return g.config
+}
+
+// GetQueueSize 获取无线电通道的队列大小（包含正在播放的数据块）
+func (g *genericOutput) GetQueueSize(channel int) int {
+	g.mu.RLock()
+	queue := g.queue
+	g.mu.RUnlock()
+	return queue.size(channel)
+}
+
+// ClearQueue 清空无线电通道的音频队列，其他通道继续播放
+func (g *genericOutput) ClearQueue(channel int) {
+	g.mu.RLock()
+	queue := g.queue
+	g.mu.RUnlock()
+	queue.clear(channel)
+}
@@ -25,7 +25,7 @@ type macOSOutput struct {
 	level      float64
 	volume     float64
 	buffer     []byte
-	queue      chan []byte
+	queue      *mixQueue
 	deviceName string
 }
 
@@ -38,7 +38,7 @@ func newMacOSOutput(cfg *config.Config, devices DeviceManagerInterface) (AudioOu
 		level:     0.0,
 		volume:    cfg.Audio.Output.Volume,
 		buffer:    make([]byte, cfg.Audio.Output.BufferSize*cfg.Audio.Output.Channels*2), // 假设16位音频
-		queue:     make(chan []byte, 10),                                                 // 音频数据队列
+		queue:     newMixQueue(FormatInt16),
 	}
 
 	return output, nil
@@ -126,33 +126,36 @@ func (o *macOSOutput) Close() error {
 }
 
 // processAudio 音频处理协程
+// 按实时速率从各通道的队列中取出音频混音，队列和PTT随播放时间推进，与通用输出的播放回调一致
 func (o *macOSOutput) processAudio() {
-	ticker := time.NewTicker(100 * time.Millisecond)
+	const period = 100 * time.Millisecond
+	ticker := time.NewTicker(period)
 	defer ticker.Stop()
 
+	outputCfg := o.config.Audio.Output
+	buffer := make([]byte, outputCfg.SampleRate*int(period/time.Millisecond)/1000*outputCfg.Channels*2)
+
 	for {
 		select {
 		case <-o.ctx.Done():
 			return
 		case <-ticker.C:
-			// 定期处理音频数据
-			// 这里可以添加音频效果处理
+			written := o.queue.fill(buffer)
+
+			o.mu.Lock()
+			o.applyVolume(buffer[:written])
+			o.calculateLevel(buffer)
+			o.mu.Unlock()
 		}
 	}
 }
 
-// PlayAudio 播放音频数据
-func (o *macOSOutput) PlayAudio(data []byte) error {
+// PlayAudio 将无线电通道的音频数据加入播放队列
+func (o *macOSOutput) PlayAudio(channel int, data []byte) error {
 	if !o.isRunning {
 		return fmt.Errorf("音频输出未运行")
 	}
-
-	select {
-	case o.queue <- data:
-		return nil
-	default:
-		return fmt.Errorf("音频队列已满")
-	}
+	return o.queue.push(channel, data)
 }
 
 // GetLevel 获取当前音频级别
@@ -216,16 +219,14 @@ func (o *macOSOutput) GetConfig() *config.Config {
 	return o.config
 }
 
-// GetQueueSize 获取队列大小
-func (o *macOSOutput) GetQueueSize() int {
-	return len(o.queue)
+// GetQueueSize 获取无线电通道的队列大小
+func (o *macOSOutput) GetQueueSize(channel int) int {
+	return o.queue.size(channel)
 }
 
-// ClearQueue 清空音频队列
-func (o *macOSOutput) ClearQueue() {
-	for len(o.queue) > 0 {
-		<-o.queue
-	}
+// ClearQueue 清空无线电通道的音频队列
+func (o *macOSOutput) ClearQueue(channel int) {
+	o.queue.clear(channel)
 }
 
 // 实现与原始Output相同的接口方法
@@ -241,7 +242,7 @@ func (o *macOSOutput) calculateLevel(data []byte) {
 
 	for j := 0; j < len(data); j += 2 {
 		sample := int16(data[j]) | int16(data[j+1])<<8
-		sum += float64(sample * sample)
+		sum += float64(sample) * float64(sample)
 	}
 
 	rms := math.Sqrt(sum / float64(sampleCount))
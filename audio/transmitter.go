@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"aprs_agent/config"
+	"aprs_agent/modem"
+	"aprs_agent/ptt"
+)
+
+// TxParams 发射参数
+type TxParams struct {
+	TxDelay     time.Duration // 发射前导时间，期间发送HDLC标志
+	TxTail      time.Duration // 音频播放完毕后保持PTT的时间
+	Persistence int           // CSMA p-persistence参数 (0-255)
+	SlotTime    time.Duration // CSMA时隙
+	FullDuplex  bool          // 全双工，发射前不检测信道
+}
+
+// Transmitter AFSK发射器
+// 将AX.25帧调制为音频，经APRS处理器限幅后送入音频输出播放
+// 配置了PTT时，音频入队前按下PTT，队列播放完毕并经过TXTAIL后松开；连续发射超时时强制松开
+type Transmitter struct {
+	mu        sync.Mutex
+	output    AudioOutput
+	index     int // 无线电通道编号，音频按通道排队播放
+	processor *APRSProcessor
+	keyer     *ptt.Keyer // 控制PTT并记录发射时间
+	modulator *modem.Modulator
+	params    TxParams
+	format    string
+	channels  int
+	side      int // 发射使用的输出声道，-1表示所有声道
+}
+
+// newTransmitter 创建发射器，发射参数取自通道配置
+func newTransmitter(cfg *config.Config, index int, ch config.ChannelConfig, output AudioOutput, processor *APRSProcessor, keyer *ptt.Keyer) *Transmitter {
+	return &Transmitter{
+		output:    output,
+		index:     index,
+		processor: processor,
+		keyer:     keyer,
+		modulator: modem.NewModulator(cfg.Audio.Output.SampleRate),
+		params: TxParams{
+			TxDelay:     ch.GetTxDelay(),
+			TxTail:      ch.GetTxTail(),
+			Persistence: ch.Persist,
+			SlotTime:    ch.GetSlotTime(),
+			FullDuplex:  ch.FullDuplex,
+		},
+		format:   resolveFormat(cfg.Audio.Output.Format, cfg.Audio.Processing.Format),
+		channels: cfg.Audio.Output.Channels,
+		side:     sideIndex(ch.Side),
+	}
+}
+
+// SendFrame 调制并播放一个AX.25帧（不含FCS）
+func (t *Transmitter) SendFrame(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("帧数据为空")
+	}
+
+	// TXTAIL由PTT控制器在音频播放完毕后计时，这里只附加一个结束标志
+	t.mu.Lock()
+	params := t.params
+	pcm := t.modulator.ModulateFrame(data, modem.FlagsForDuration(params.TxDelay), 0)
+	t.mu.Unlock()
+
+	// 限幅后转换为输出设备的声道数和格式，音量由输出设备调节
+	pcm = t.processor.ProcessTransmit(pcm)
+	pcm = Int16ToPCM(interleaveChannels(pcm, t.channels, t.side), t.format)
+
+	play := func() error {
+		if err := t.output.PlayAudio(t.index, pcm); err != nil {
+			return fmt.Errorf("播放调制音频失败: %w", err)
+		}
+		return nil
+	}
+	return t.keyer.Send(play, params.TxTail)
+}
+
+// Airtime 估算一帧的发射时间，不含TXTAIL
+func (t *Transmitter) Airtime(data []byte) time.Duration {
+	t.mu.Lock()
+	txDelay := t.params.TxDelay
+	t.mu.Unlock()
+	return modem.FrameDuration(len(data), modem.FlagsForDuration(txDelay))
+}
+
+// close 松开PTT并释放PTT设备
+func (t *Transmitter) close() error {
+	return t.keyer.Close()
+}
+
+// GetGuardStats 获取发射保护统计信息
+func (t *Transmitter) GetGuardStats() ptt.GuardStats {
+	return t.keyer.GetStats()
+}
+
+// GetParams 获取发射参数
+func (t *Transmitter) GetParams() TxParams {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.params
+}
+
+// SetParams 设置发射参数
+func (t *Transmitter) SetParams(params TxParams) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.params = params
+}
+
+// sideIndex 获取通道声道在交错PCM中的索引，单声道时返回-1
+func sideIndex(side string) int {
+	switch side {
+	case config.SideLeft:
+		return 0
+	case config.SideRight:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// interleaveChannels 将16位单声道PCM转换为多声道交错PCM
+// side为-1或超出声道数时复制到所有声道，否则只写入该声道，其余声道静音
+func interleaveChannels(data []byte, channels int, side int) []byte {
+	if channels <= 1 {
+		return data
+	}
+	if side >= channels {
+		side = -1
+	}
+
+	output := make([]byte, 0, len(data)*channels)
+	for j := 0; j+1 < len(data); j += 2 {
+		sample := binary.LittleEndian.Uint16(data[j:])
+		for ch := 0; ch < channels; ch++ {
+			if side >= 0 && ch != side {
+				output = binary.LittleEndian.AppendUint16(output, 0)
+				continue
+			}
+			output = binary.LittleEndian.AppendUint16(output, sample)
+		}
+	}
+	return output
+}
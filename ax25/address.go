@@ -0,0 +1,150 @@
+package ax25
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 地址字段长度限制
+const (
+	AddressLen  = 7 // 编码后的地址长度 (6字节呼号+1字节SSID)
+	MaxCallLen  = 6 // 呼号最大长度
+	MaxSSID     = 15
+	MaxDigis    = 8 // 最多中继地址数
+	ssidMask    = 0x1E
+	hBitMask    = 0x80
+	reserveBits = 0x60
+	extBit      = 0x01
+)
+
+// Address AX.25地址（呼号-SSID）
+type Address struct {
+	Call string
+	SSID int
+	// H 地址SSID字节的最高位：中继地址中表示"已转发"(H位)，
+	// 源/目的地址中表示命令/响应(C位)
+	H bool
+}
+
+// ParseAddress 解析 "N0CALL-9" 形式的地址，末尾的 "*" 表示已转发
+func ParseAddress(s string) (Address, error) {
+	var addr Address
+
+	if strings.HasSuffix(s, "*") {
+		addr.H = true
+		s = strings.TrimSuffix(s, "*")
+	}
+
+	call := s
+	if idx := strings.IndexByte(s, '-'); idx >= 0 {
+		call = s[:idx]
+		ssid, err := strconv.Atoi(s[idx+1:])
+		if err != nil || ssid < 0 || ssid > MaxSSID {
+			return Address{}, fmt.Errorf("无效的SSID: %s", s)
+		}
+		addr.SSID = ssid
+	}
+
+	if err := validateCall(call); err != nil {
+		return Address{}, err
+	}
+	addr.Call = call
+
+	return addr, nil
+}
+
+// MustParseAddress 解析地址，失败时panic，仅用于常量地址
+func MustParseAddress(s string) Address {
+	addr, err := ParseAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+// validateCall 检查呼号是否符合AX.25要求（1-6个大写字母或数字）
+func validateCall(call string) error {
+	if len(call) == 0 || len(call) > MaxCallLen {
+		return fmt.Errorf("呼号长度必须在1-%d之间: %q", MaxCallLen, call)
+	}
+	for _, c := range call {
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return fmt.Errorf("呼号包含无效字符: %q", call)
+		}
+	}
+	return nil
+}
+
+// String 返回地址的文本形式，SSID为0时省略
+func (a Address) String() string {
+	if a.SSID == 0 {
+		return a.Call
+	}
+	return fmt.Sprintf("%s-%d", a.Call, a.SSID)
+}
+
+// Equal 比较呼号和SSID是否相同（忽略H位）
+func (a Address) Equal(other Address) bool {
+	return a.Call == other.Call && a.SSID == other.SSID
+}
+
+// encode 将地址编码为7字节，last表示是否为地址字段的最后一个地址
+func (a Address) encode(last bool) ([]byte, error) {
+	if err := validateCall(a.Call); err != nil {
+		return nil, err
+	}
+	if a.SSID < 0 || a.SSID > MaxSSID {
+		return nil, fmt.Errorf("无效的SSID: %d", a.SSID)
+	}
+
+	buf := make([]byte, AddressLen)
+	for i := 0; i < MaxCallLen; i++ {
+		c := byte(' ')
+		if i < len(a.Call) {
+			c = a.Call[i]
+		}
+		buf[i] = c << 1
+	}
+
+	ssid := byte(reserveBits) | byte(a.SSID<<1)
+	if a.H {
+		ssid |= hBitMask
+	}
+	if last {
+		ssid |= extBit
+	}
+	buf[6] = ssid
+
+	return buf, nil
+}
+
+// decodeAddress 解码7字节地址，返回地址以及是否为最后一个地址
+func decodeAddress(data []byte) (Address, bool, error) {
+	if len(data) < AddressLen {
+		return Address{}, false, fmt.Errorf("地址字段长度不足")
+	}
+
+	call := make([]byte, 0, MaxCallLen)
+	for i := 0; i < MaxCallLen; i++ {
+		if data[i]&extBit != 0 {
+			return Address{}, false, fmt.Errorf("地址字段中出现意外的扩展位")
+		}
+		c := data[i] >> 1
+		if c == ' ' {
+			continue
+		}
+		call = append(call, c)
+	}
+
+	addr := Address{
+		Call: strings.TrimSpace(string(call)),
+		SSID: int(data[6]&ssidMask) >> 1,
+		H:    data[6]&hBitMask != 0,
+	}
+	if err := validateCall(addr.Call); err != nil {
+		return Address{}, false, err
+	}
+
+	return addr, data[6]&extBit != 0, nil
+}
@@ -0,0 +1,317 @@
+package ax25
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FrameType 帧类型
+type FrameType int
+
+const (
+	FrameI FrameType = iota // 信息帧
+	FrameS                  // 监控帧
+	FrameU                  // 无编号帧
+)
+
+// String 返回帧类型名称
+func (t FrameType) String() string {
+	switch t {
+	case FrameI:
+		return "I"
+	case FrameS:
+		return "S"
+	default:
+		return "U"
+	}
+}
+
+// 控制字段常量（模8，不含P/F位）
+const (
+	ControlUI    = 0x03
+	ControlSABM  = 0x2F
+	ControlSABME = 0x6F
+	ControlDISC  = 0x43
+	ControlDM    = 0x0F
+	ControlUA    = 0x63
+	ControlFRMR  = 0x87
+	ControlXID   = 0xAF
+	ControlTEST  = 0xE3
+
+	ControlRR   = 0x01
+	ControlRNR  = 0x05
+	ControlREJ  = 0x09
+	ControlSREJ = 0x0D
+
+	PFBit = 0x10 // 轮询/结束位
+)
+
+// 协议标识
+const (
+	PIDNoLayer3 = 0xF0 // 无第三层协议，APRS使用
+)
+
+// Frame AX.25 v2.2帧
+type Frame struct {
+	Dest    Address
+	Src     Address
+	Path    []Address // 中继路径，最多8个
+	Control byte
+	PID     byte // 仅I帧和UI帧有效
+	Info    []byte
+}
+
+// NewUIFrame 创建APRS使用的UI帧
+// UI帧按AX.25 v2命令帧编码：目的地址C位为1，源地址C位为0
+func NewUIFrame(src, dest Address, path []Address, info []byte) *Frame {
+	dest.H, src.H = true, false
+	return &Frame{
+		Dest:    dest,
+		Src:     src,
+		Path:    path,
+		Control: ControlUI,
+		PID:     PIDNoLayer3,
+		Info:    info,
+	}
+}
+
+// Type 根据控制字段判断帧类型
+func (f *Frame) Type() FrameType {
+	switch {
+	case f.Control&0x01 == 0:
+		return FrameI
+	case f.Control&0x03 == 0x01:
+		return FrameS
+	default:
+		return FrameU
+	}
+}
+
+// IsUI 是否为UI帧
+func (f *Frame) IsUI() bool {
+	return f.Control&^PFBit == ControlUI
+}
+
+// HasPID 该帧类型是否携带PID字段
+func (f *Frame) HasPID() bool {
+	return f.Type() == FrameI || f.IsUI()
+}
+
+// PF 获取轮询/结束位
+func (f *Frame) PF() bool {
+	return f.Control&PFBit != 0
+}
+
+// NS 获取I帧的发送序号
+func (f *Frame) NS() int {
+	return int(f.Control>>1) & 0x07
+}
+
+// NR 获取I帧和S帧的接收序号
+func (f *Frame) NR() int {
+	return int(f.Control>>5) & 0x07
+}
+
+// IsCommand 按AX.25 v2规则判断是否为命令帧（目的地址C位为1，源地址C位为0）
+func (f *Frame) IsCommand() bool {
+	return f.Dest.H && !f.Src.H
+}
+
+// ControlName 返回控制字段的助记名称
+func (f *Frame) ControlName() string {
+	switch f.Type() {
+	case FrameI:
+		return fmt.Sprintf("I%d%d", f.NR(), f.NS())
+	case FrameS:
+		switch f.Control & 0x0F {
+		case ControlRR:
+			return fmt.Sprintf("RR%d", f.NR())
+		case ControlRNR:
+			return fmt.Sprintf("RNR%d", f.NR())
+		case ControlREJ:
+			return fmt.Sprintf("REJ%d", f.NR())
+		default:
+			return fmt.Sprintf("SREJ%d", f.NR())
+		}
+	}
+
+	switch f.Control &^ PFBit {
+	case ControlUI:
+		return "UI"
+	case ControlSABM:
+		return "SABM"
+	case ControlSABME:
+		return "SABME"
+	case ControlDISC:
+		return "DISC"
+	case ControlDM:
+		return "DM"
+	case ControlUA:
+		return "UA"
+	case ControlFRMR:
+		return "FRMR"
+	case ControlXID:
+		return "XID"
+	case ControlTEST:
+		return "TEST"
+	default:
+		return fmt.Sprintf("U%02X", f.Control)
+	}
+}
+
+// Decode 解码不含FCS的AX.25帧
+func Decode(data []byte) (*Frame, error) {
+	frame := &Frame{}
+
+	offset := 0
+	var addrs []Address
+	for {
+		if len(addrs) >= 2+MaxDigis {
+			return nil, fmt.Errorf("地址数量超过 %d 个", 2+MaxDigis)
+		}
+
+		addr, last, err := decodeAddress(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("解析第%d个地址失败: %w", len(addrs)+1, err)
+		}
+		addrs = append(addrs, addr)
+		offset += AddressLen
+
+		if last {
+			break
+		}
+	}
+
+	if len(addrs) < 2 {
+		return nil, fmt.Errorf("缺少源地址")
+	}
+
+	frame.Dest = addrs[0]
+	frame.Src = addrs[1]
+	if len(addrs) > 2 {
+		frame.Path = addrs[2:]
+	}
+
+	if offset >= len(data) {
+		return nil, fmt.Errorf("缺少控制字段")
+	}
+	frame.Control = data[offset]
+	offset++
+
+	if frame.HasPID() {
+		if offset >= len(data) {
+			return nil, fmt.Errorf("缺少PID字段")
+		}
+		frame.PID = data[offset]
+		offset++
+	}
+
+	frame.Info = append([]byte(nil), data[offset:]...)
+	return frame, nil
+}
+
+// Encode 将帧编码为字节（不含FCS）
+func (f *Frame) Encode() ([]byte, error) {
+	if len(f.Path) > MaxDigis {
+		return nil, fmt.Errorf("中继地址不能超过 %d 个", MaxDigis)
+	}
+
+	addrs := make([]Address, 0, 2+len(f.Path))
+	addrs = append(addrs, f.Dest, f.Src)
+	addrs = append(addrs, f.Path...)
+
+	buf := make([]byte, 0, len(addrs)*AddressLen+2+len(f.Info))
+	for i, addr := range addrs {
+		encoded, err := addr.encode(i == len(addrs)-1)
+		if err != nil {
+			return nil, fmt.Errorf("编码地址 %s 失败: %w", addr, err)
+		}
+		buf = append(buf, encoded...)
+	}
+
+	buf = append(buf, f.Control)
+	if f.HasPID() {
+		buf = append(buf, f.PID)
+	}
+	buf = append(buf, f.Info...)
+
+	return buf, nil
+}
+
+// String 返回TNC2格式文本，如 N0CALL-9>APRS,WIDE1-1*,WIDE2-1:payload
+// 最后一个已转发的中继地址后加 "*"
+func (f *Frame) String() string {
+	var sb strings.Builder
+	sb.WriteString(f.Src.String())
+	sb.WriteByte('>')
+	sb.WriteString(f.Dest.String())
+
+	lastRepeated := -1
+	for i, digi := range f.Path {
+		if digi.H {
+			lastRepeated = i
+		}
+	}
+
+	for i, digi := range f.Path {
+		sb.WriteByte(',')
+		sb.WriteString(digi.String())
+		if i == lastRepeated {
+			sb.WriteByte('*')
+		}
+	}
+
+	sb.WriteByte(':')
+	sb.Write(f.Info)
+	return sb.String()
+}
+
+// ParseTNC2 解析TNC2格式文本为UI帧
+// 带 "*" 的中继地址及其之前的所有中继地址都被视为已转发
+func ParseTNC2(s string) (*Frame, error) {
+	s = strings.TrimRight(s, "\r\n")
+
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return nil, fmt.Errorf("缺少信息字段分隔符 ':'")
+	}
+	header, info := s[:colon], s[colon+1:]
+
+	gt := strings.IndexByte(header, '>')
+	if gt < 0 {
+		return nil, fmt.Errorf("缺少源地址分隔符 '>'")
+	}
+
+	src, err := ParseAddress(header[:gt])
+	if err != nil {
+		return nil, fmt.Errorf("解析源地址失败: %w", err)
+	}
+
+	parts := strings.Split(header[gt+1:], ",")
+	dest, err := ParseAddress(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("解析目的地址失败: %w", err)
+	}
+
+	if len(parts)-1 > MaxDigis {
+		return nil, fmt.Errorf("中继地址不能超过 %d 个", MaxDigis)
+	}
+
+	var path []Address
+	lastRepeated := -1
+	for i, part := range parts[1:] {
+		digi, err := ParseAddress(part)
+		if err != nil {
+			return nil, fmt.Errorf("解析中继地址失败: %w", err)
+		}
+		if digi.H {
+			lastRepeated = i
+		}
+		path = append(path, digi)
+	}
+	for i := range path {
+		path[i].H = i <= lastRepeated
+	}
+
+	return NewUIFrame(src, dest, path, []byte(info)), nil
+}
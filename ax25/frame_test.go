@@ -0,0 +1,198 @@
+package ax25
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Address
+		wantErr bool
+	}{
+		{"N0CALL", Address{Call: "N0CALL"}, false},
+		{"N0CALL-9", Address{Call: "N0CALL", SSID: 9}, false},
+		{"WIDE1-1*", Address{Call: "WIDE1", SSID: 1, H: true}, false},
+		{"N0CALL-16", Address{}, true},
+		{"TOOLONGX", Address{}, true},
+		{"n0call", Address{}, true},
+		{"", Address{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAddress(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAddress(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseAddress(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrameEncodeDecode(t *testing.T) {
+	frame := NewUIFrame(
+		MustParseAddress("N0CALL-9"),
+		MustParseAddress("APRS"),
+		[]Address{MustParseAddress("WIDE1-1*"), MustParseAddress("WIDE2-1")},
+		[]byte("!4903.50N/07201.75W-Test"),
+	)
+
+	data, err := frame.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+
+	// 目的地址 "APRS  " 左移一位，SSID字节带保留位和C位
+	want := []byte{'A' << 1, 'P' << 1, 'R' << 1, 'S' << 1, ' ' << 1, ' ' << 1, 0xE0}
+	if !bytes.Equal(data[:7], want) {
+		t.Errorf("目的地址编码错误: % X", data[:7])
+	}
+	// 源地址SSID字节只有保留位和SSID，C位为0
+	if data[13] != 0x60|9<<1 {
+		t.Errorf("源地址SSID字节 = %02X, want %02X", data[13], 0x60|9<<1)
+	}
+	// 最后一个地址带扩展位
+	if data[27]&0x01 == 0 || data[20]&0x01 != 0 {
+		t.Errorf("地址扩展位错误")
+	}
+	// 已转发的中继地址带H位
+	if data[20]&0x80 == 0 || data[27]&0x80 != 0 {
+		t.Errorf("H位编码错误")
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+
+	if got := decoded.String(); got != "N0CALL-9>APRS,WIDE1-1*,WIDE2-1:!4903.50N/07201.75W-Test" {
+		t.Errorf("TNC2格式错误: %s", got)
+	}
+	if !decoded.IsUI() || decoded.PID != PIDNoLayer3 {
+		t.Errorf("控制字段或PID错误: %02X %02X", decoded.Control, decoded.PID)
+	}
+	if !decoded.IsCommand() {
+		t.Error("UI帧应编码为命令帧")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid, _ := NewUIFrame(MustParseAddress("N0CALL"), MustParseAddress("APRS"), nil, []byte("x")).Encode()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"空数据", nil},
+		{"只有目的地址", valid[:7]},
+		{"缺少控制字段", valid[:14]},
+		{"缺少PID", valid[:15]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.data); err == nil {
+				t.Errorf("期望解码失败")
+			}
+		})
+	}
+
+	// 超过8个中继地址
+	var data []byte
+	for i := 0; i < 11; i++ {
+		addr, _ := MustParseAddress("WIDE1").encode(i == 10)
+		data = append(data, addr...)
+	}
+	data = append(data, ControlUI, PIDNoLayer3)
+	if _, err := Decode(data); err == nil {
+		t.Errorf("期望中继地址过多时解码失败")
+	}
+}
+
+func TestFrameTypes(t *testing.T) {
+	tests := []struct {
+		control byte
+		typ     FrameType
+		name    string
+		hasPID  bool
+	}{
+		{0x03, FrameU, "UI", true},
+		{0x13, FrameU, "UI", true},
+		{0x3F, FrameU, "SABM", false},
+		{0x63, FrameU, "UA", false},
+		{0x43, FrameU, "DISC", false},
+		{0x41, FrameS, "RR2", false},
+		{0x29, FrameS, "REJ1", false},
+		{0x46, FrameI, "I23", true},
+	}
+
+	for _, tt := range tests {
+		f := &Frame{Control: tt.control}
+		if f.Type() != tt.typ {
+			t.Errorf("控制字段 %02X: 类型 = %v, want %v", tt.control, f.Type(), tt.typ)
+		}
+		if f.ControlName() != tt.name {
+			t.Errorf("控制字段 %02X: 名称 = %s, want %s", tt.control, f.ControlName(), tt.name)
+		}
+		if f.HasPID() != tt.hasPID {
+			t.Errorf("控制字段 %02X: HasPID = %v, want %v", tt.control, f.HasPID(), tt.hasPID)
+		}
+	}
+
+	// I帧编解码
+	frame := &Frame{
+		Dest:    MustParseAddress("N0CALL"),
+		Src:     MustParseAddress("N1CALL-1"),
+		Control: 0x46,
+		PID:     PIDNoLayer3,
+		Info:    []byte("hello"),
+	}
+	data, err := frame.Encode()
+	if err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if decoded.NS() != 3 || decoded.NR() != 2 || string(decoded.Info) != "hello" {
+		t.Errorf("I帧解码错误: %+v", decoded)
+	}
+}
+
+func TestParseTNC2(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"N0CALL-9>APRS,WIDE1-1*,WIDE2-1:payload", "N0CALL-9>APRS,WIDE1-1*,WIDE2-1:payload", false},
+		{"N0CALL>APRS:>status:with:colons", "N0CALL>APRS:>status:with:colons", false},
+		{"N0CALL>APRS,DIGI1,DIGI2*,WIDE2-1:x", "N0CALL>APRS,DIGI1,DIGI2*,WIDE2-1:x", false},
+		{"N0CALL>APRS", "", true},
+		{"N0CALL:payload", "", true},
+		{"N0CALL>APRS,TCPIP*,qAC,T2TEST:x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			frame, err := ParseTNC2(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTNC2() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && frame.String() != tt.want {
+				t.Errorf("ParseTNC2() = %s, want %s", frame.String(), tt.want)
+			}
+		})
+	}
+
+	// "*" 之前的中继地址都已转发
+	frame, _ := ParseTNC2("N0CALL>APRS,DIGI1,DIGI2*,WIDE2-1:x")
+	if !frame.Path[0].H || !frame.Path[1].H || frame.Path[2].H {
+		t.Errorf("H位设置错误: %+v", frame.Path)
+	}
+}
@@ -0,0 +1,324 @@
+// Package beacon 定时发送位置、对象和自定义信标
+// 每个信标按固定间隔、时隙或SmartBeaconing发送到射频通道或APRS-IS，射频信标经发射队列和CSMA发出
+package beacon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"aprs_agent/aprs"
+	"aprs_agent/ax25"
+	"aprs_agent/csma"
+)
+
+// ToCall 信标使用的目的地址 (APZ为实验性软件保留)
+const ToCall = "APZAGT"
+
+// nowFunc 获取当前时间，测试时可替换
+var nowFunc = time.Now
+
+// 信标类型
+const (
+	TypePosition = "position" // 本站位置
+	TypeObject   = "object"   // 对象
+	TypeRaw      = "raw"      // TNC2格式的自定义数据包
+)
+
+// Modem 信标使用的调制解调器接口，由audio.Manager实现
+type Modem interface {
+	TransmitPriority(channel int, data []byte, priority csma.Priority) error
+}
+
+// ISClient APRS-IS客户端接口，由igate.Client实现
+type ISClient interface {
+	Send(line string) error
+}
+
+// Fix GPS定位结果
+type Fix struct {
+	Latitude  float64 // 纬度，北纬为正
+	Longitude float64 // 经度，东经为正
+
+	HasAltitude bool
+	Altitude    float64 // 海拔 (米)
+
+	HasCourseSpeed bool
+	Course         int     // 航向 (度，1-360)
+	Speed          float64 // 速度 (km/h)
+}
+
+// PositionSource 获取当前GPS定位，没有有效定位时返回false
+type PositionSource func() (Fix, bool)
+
+// Beacon 信标定义
+type Beacon struct {
+	Type     string         // 信标类型: TypePosition, TypeObject, TypeRaw
+	Channel  int            // 发射通道
+	APRSIS   bool           // 发送到APRS-IS而不是射频
+	Source   string         // 源呼号
+	Path     []ax25.Address // 中继路径
+	Interval time.Duration  // 发送间隔
+	Delay    time.Duration  // 启动后第一次发送的延迟
+	Slot     time.Duration  // 时隙，HasSlot为true时在每个间隔内的固定时刻发送
+	HasSlot  bool
+
+	Position  aprs.Position // 符号、坐标、海拔、PHG和是否压缩
+	UseGPS    bool          // 使用GPS位置，坐标、海拔、航向和速度取自定位
+	Messaging bool          // 声明支持消息
+	Comment   string
+
+	Object string // 对象名称
+	Raw    string // TNC2格式的完整数据包
+
+	Smart *SmartBeaconing // 不为nil时按GPS速度和航向发送，忽略Interval和Slot
+}
+
+// Stats 信标统计信息
+type Stats struct {
+	Sent    uint64 // 已发送的信标数
+	Failed  uint64 // 编码或发送失败的信标数
+	Skipped uint64 // 因没有GPS定位而跳过的信标数
+}
+
+// Scheduler 信标调度器，每个信标一个goroutine
+type Scheduler struct {
+	modem   Modem
+	client  ISClient // 为nil时不能发送到APRS-IS
+	beacons []Beacon
+
+	mu     sync.Mutex
+	gps    PositionSource
+	stats  Stats
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建信标调度器，client为nil时发送到APRS-IS的信标发送失败
+func New(m Modem, client ISClient, beacons []Beacon) *Scheduler {
+	return &Scheduler{modem: m, client: client, beacons: beacons}
+}
+
+// SetPositionSource 设置GPS位置来源
+func (s *Scheduler) SetPositionSource(source PositionSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gps = source
+}
+
+// GetStats 获取统计信息
+func (s *Scheduler) GetStats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Start 开始按计划发送信标，ctx取消时停止
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	for i := range s.beacons {
+		s.wg.Add(1)
+		if s.beacons[i].Smart != nil {
+			go s.runSmart(ctx, i, &s.beacons[i])
+		} else {
+			go s.run(ctx, i, &s.beacons[i])
+		}
+	}
+}
+
+// Stop 停止发送信标
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		s.wg.Wait()
+	}
+}
+
+// run 按计划发送一个信标
+func (s *Scheduler) run(ctx context.Context, index int, b *Beacon) {
+	defer s.wg.Done()
+
+	next := b.firstTime(nowFunc())
+	for {
+		timer := time.NewTimer(next.Sub(nowFunc()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.Send(index)
+		next = b.nextTime(next, nowFunc())
+	}
+}
+
+// Send 立即发送第index个信标
+func (s *Scheduler) Send(index int) {
+	if index < 0 || index >= len(s.beacons) {
+		return
+	}
+	b := &s.beacons[index]
+
+	s.mu.Lock()
+	gps := s.gps
+	s.mu.Unlock()
+
+	var fix Fix
+	if b.UseGPS && b.Type != TypeRaw {
+		ok := false
+		if gps != nil {
+			fix, ok = gps()
+		}
+		if !ok {
+			log.Printf("[信标] 信标 %d 没有GPS定位，跳过", index)
+			s.count(func(st *Stats) { st.Skipped++ })
+			return
+		}
+	}
+	s.sendFix(index, b, fix)
+}
+
+// sendFix 发送信标并更新统计信息
+func (s *Scheduler) sendFix(index int, b *Beacon, fix Fix) {
+	if err := s.send(b, fix); err != nil {
+		log.Printf("[信标] 信标 %d 发送失败: %v", index, err)
+		s.count(func(st *Stats) { st.Failed++ })
+		return
+	}
+	s.count(func(st *Stats) { st.Sent++ })
+}
+
+// count 更新统计信息
+func (s *Scheduler) count(update func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.stats)
+}
+
+// send 编码并发送信标
+func (s *Scheduler) send(b *Beacon, fix Fix) error {
+	if b.APRSIS {
+		if s.client == nil {
+			return fmt.Errorf("APRS-IS未启用")
+		}
+		line, err := b.ISLine(fix)
+		if err != nil {
+			return err
+		}
+		return s.client.Send(line)
+	}
+
+	frame, err := b.Frame(fix)
+	if err != nil {
+		return err
+	}
+	data, err := frame.Encode()
+	if err != nil {
+		return fmt.Errorf("编码AX.25帧失败: %w", err)
+	}
+	// 信标可以延后，队列中的中继和客户端数据优先发射
+	return s.modem.TransmitPriority(b.Channel, data, csma.PriorityLow)
+}
+
+// Info 生成信标的信息字段，UseGPS时使用fix中的位置
+// SmartBeaconing信标使用压缩格式，航向和速度编码在压缩数据中
+func (b *Beacon) Info(fix Fix) (string, error) {
+	pos := b.Position
+	if b.Smart != nil {
+		pos.Compressed = true
+	}
+	if b.UseGPS {
+		pos.Latitude, pos.Longitude = fix.Latitude, fix.Longitude
+		pos.HasAltitude, pos.Altitude = fix.HasAltitude, fix.Altitude
+		pos.HasCourseSpeed, pos.Course, pos.Speed = fix.HasCourseSpeed, fix.Course, fix.Speed
+	}
+
+	switch b.Type {
+	case TypePosition:
+		return aprs.EncodePosition(&pos, time.Time{}, b.Messaging, b.Comment)
+	case TypeObject:
+		return aprs.EncodeObject(&aprs.Object{Name: b.Object, Live: true}, &pos, nowFunc(), b.Comment)
+	default:
+		return "", fmt.Errorf("信标类型 %s 没有信息字段", b.Type)
+	}
+}
+
+// Frame 生成发送到射频的UI帧
+func (b *Beacon) Frame(fix Fix) (*ax25.Frame, error) {
+	if b.Type == TypeRaw {
+		return ax25.ParseTNC2(b.Raw)
+	}
+
+	src, err := ax25.ParseAddress(b.Source)
+	if err != nil {
+		return nil, fmt.Errorf("信标呼号无效: %w", err)
+	}
+	info, err := b.Info(fix)
+	if err != nil {
+		return nil, err
+	}
+	return ax25.NewUIFrame(src, ax25.MustParseAddress(ToCall), b.Path, []byte(info)), nil
+}
+
+// ISLine 生成发送到APRS-IS的TNC2数据包，本站发出的数据包路径为 TCPIP*
+func (b *Beacon) ISLine(fix Fix) (string, error) {
+	if b.Type == TypeRaw {
+		return b.Raw, nil
+	}
+
+	info, err := b.Info(fix)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(b.Source) + ">" + ToCall + ",TCPIP*:" + info, nil
+}
+
+// firstTime 计算第一次发送的时间
+func (b *Beacon) firstTime(now time.Time) time.Time {
+	if b.HasSlot {
+		return b.slotTime(now)
+	}
+	return now.Add(b.Delay)
+}
+
+// nextTime 计算上次计划时间prev之后的发送时间
+// 系统休眠等原因错过发送时间时，从当前时间重新计算而不补发
+func (b *Beacon) nextTime(prev, now time.Time) time.Time {
+	if b.HasSlot {
+		if now.Before(prev) {
+			now = prev
+		}
+		return b.slotTime(now)
+	}
+	next := prev.Add(b.Interval)
+	if next.Before(now) {
+		next = now.Add(b.Interval)
+	}
+	return next
+}
+
+// slotTime 计算now之后的下一个时隙
+// 时隙从整点开始按间隔划分，间隔能整除1小时时每小时的发送时刻相同
+func (b *Beacon) slotTime(now time.Time) time.Time {
+	t := now.Truncate(b.Interval).Add(b.Slot)
+	if !t.After(now) {
+		t = t.Add(b.Interval)
+	}
+	return t
+}
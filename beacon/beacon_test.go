@@ -0,0 +1,342 @@
+package beacon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"aprs_agent/aprs"
+	"aprs_agent/ax25"
+	"aprs_agent/csma"
+)
+
+// fakeModem 记录发射的帧
+type fakeModem struct {
+	mu     sync.Mutex
+	frames []*ax25.Frame
+	prio   []csma.Priority
+}
+
+func (m *fakeModem) TransmitPriority(channel int, data []byte, priority csma.Priority) error {
+	frame, err := ax25.Decode(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.frames = append(m.frames, frame)
+	m.prio = append(m.prio, priority)
+	return nil
+}
+
+func (m *fakeModem) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.frames)
+}
+
+// fakeIS 记录发送到APRS-IS的数据包
+type fakeIS struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *fakeIS) Send(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, line)
+	return nil
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fixedNow 将当前时间固定为t，测试结束时恢复
+func fixedNow(t *testing.T, now time.Time) {
+	saved := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = saved })
+}
+
+func station() Beacon {
+	return Beacon{
+		Type:     TypePosition,
+		Source:   "N0CALL-10",
+		Path:     []ax25.Address{ax25.MustParseAddress("WIDE2-1")},
+		Interval: 30 * time.Minute,
+		Position: aprs.Position{Latitude: 49.058333, Longitude: -72.029167, SymbolTable: '/', SymbolCode: '-'},
+		Comment:  "aprs_agent",
+	}
+}
+
+func TestInfo(t *testing.T) {
+	fixedNow(t, time.Date(2026, 10, 9, 23, 45, 0, 0, time.UTC))
+
+	tests := []struct {
+		name   string
+		mutate func(b *Beacon)
+		fix    Fix
+		want   string
+	}{
+		{"固定位置", nil, Fix{}, "!4903.50N/07201.75W-aprs_agent"},
+		{"支持消息和PHG", func(b *Beacon) {
+			b.Messaging = true
+			b.Position.PHG = &aprs.PHG{Power: 25, Height: 20, Gain: 3}
+		}, Fix{}, "=4903.50N/07201.75W-PHG5130aprs_agent"},
+		{"海拔", func(b *Beacon) {
+			b.Position.HasAltitude, b.Position.Altitude = true, 1234*0.3048
+		}, Fix{}, "!4903.50N/07201.75W-aprs_agent/A=001234"},
+		{"GPS位置", func(b *Beacon) {
+			b.UseGPS, b.Comment = true, ""
+			b.Position.SymbolCode = '>'
+		}, Fix{Latitude: 35.5966, Longitude: 139.752, HasCourseSpeed: true, Course: 88, Speed: 36 * 1.852}, "!3535.80N/13945.12E>088/036"},
+		{"对象", func(b *Beacon) {
+			b.Type, b.Object, b.Comment = TypeObject, "EVENT", "Field Day"
+		}, Fix{}, ";EVENT    *092345z4903.50N/07201.75W-Field Day"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := station()
+			if tt.mutate != nil {
+				tt.mutate(&b)
+			}
+			got, err := b.Info(tt.fix)
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Info() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrame(t *testing.T) {
+	b := station()
+	frame, err := b.Frame(Fix{})
+	if err != nil {
+		t.Fatalf("生成帧失败: %v", err)
+	}
+	if want := "N0CALL-10>APZAGT,WIDE2-1:!4903.50N/07201.75W-aprs_agent"; frame.String() != want {
+		t.Errorf("Frame() = %q, want %q", frame.String(), want)
+	}
+
+	line, err := b.ISLine(Fix{})
+	if err != nil {
+		t.Fatalf("生成APRS-IS数据包失败: %v", err)
+	}
+	if want := "N0CALL-10>APZAGT,TCPIP*:!4903.50N/07201.75W-aprs_agent"; line != want {
+		t.Errorf("ISLine() = %q, want %q", line, want)
+	}
+
+	raw := Beacon{Type: TypeRaw, Raw: "N0CALL>APRS,WIDE1-1:>Net tonight 20:00"}
+	frame, err = raw.Frame(Fix{})
+	if err != nil {
+		t.Fatalf("解析raw信标失败: %v", err)
+	}
+	if frame.String() != raw.Raw {
+		t.Errorf("raw Frame() = %q, want %q", frame.String(), raw.Raw)
+	}
+	if line, _ := raw.ISLine(Fix{}); line != raw.Raw {
+		t.Errorf("raw ISLine() = %q, want %q", line, raw.Raw)
+	}
+}
+
+func TestNextTime(t *testing.T) {
+	base := time.Date(2026, 10, 9, 12, 0, 0, 0, time.UTC)
+	at := func(m, s int) time.Time { return base.Add(time.Duration(m)*time.Minute + time.Duration(s)*time.Second) }
+
+	interval := Beacon{Interval: 10 * time.Minute, Delay: time.Minute}
+	slotted := Beacon{Interval: 10 * time.Minute, Slot: 3*time.Minute + 30*time.Second, HasSlot: true}
+
+	if got := interval.firstTime(at(0, 5)); !got.Equal(at(1, 5)) {
+		t.Errorf("第一次发送 = %v, want %v", got, at(1, 5))
+	}
+	if got := slotted.firstTime(at(5, 0)); !got.Equal(at(13, 30)) {
+		t.Errorf("第一次时隙 = %v, want %v", got, at(13, 30))
+	}
+
+	tests := []struct {
+		name string
+		b    Beacon
+		prev time.Time
+		now  time.Time
+		want time.Time
+	}{
+		{"固定间隔", interval, at(1, 5), at(1, 5), at(11, 5)},
+		{"错过发送时间不补发", interval, at(1, 5), at(25, 0), at(35, 0)},
+		{"时隙", slotted, at(3, 30), at(3, 30), at(13, 30)},
+		{"定时器提前触发", slotted, at(13, 30), at(13, 29), at(23, 30)},
+		{"错过时隙", slotted, at(3, 30), at(27, 0), at(33, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.b.nextTime(tt.prev, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	m := &fakeModem{}
+	is := &fakeIS{}
+
+	rf := station()
+	rf.Channel, rf.Interval, rf.Delay = 1, 20*time.Millisecond, 0
+	gps := station()
+	gps.UseGPS, gps.Interval, gps.Delay = true, time.Hour, 0
+	toIS := station()
+	toIS.APRSIS, toIS.Interval, toIS.Delay = true, time.Hour, 0
+
+	s := New(m, is, []Beacon{rf, gps, toIS})
+	s.Start(context.Background())
+
+	// 射频信标按间隔重复发送，使用低优先级进入发射队列
+	waitFor(t, func() bool { return m.count() >= 3 })
+	waitFor(t, func() bool { return s.GetStats().Skipped == 1 })
+	s.Stop()
+
+	m.mu.Lock()
+	for i, frame := range m.frames {
+		if frame.Src.String() != "N0CALL-10" || m.prio[i] != csma.PriorityLow {
+			t.Errorf("帧 %d = %s, 优先级 %d", i, frame, m.prio[i])
+		}
+	}
+	m.mu.Unlock()
+
+	is.mu.Lock()
+	if len(is.lines) != 1 {
+		t.Errorf("APRS-IS数据包 = %v, want 1个", is.lines)
+	}
+	is.mu.Unlock()
+
+	// 有GPS定位后发送GPS位置
+	s.SetPositionSource(func() (Fix, bool) { return Fix{Latitude: 35.5, Longitude: 139.75}, true })
+	before := m.count()
+	s.Send(1)
+	if m.count() != before+1 {
+		t.Fatal("有GPS定位时应发送信标")
+	}
+	m.mu.Lock()
+	info := string(m.frames[len(m.frames)-1].Info)
+	m.mu.Unlock()
+	if want := "!3530.00N/13945.00E-aprs_agent"; info != want {
+		t.Errorf("GPS信标 = %q, want %q", info, want)
+	}
+}
+
+func TestSmartBeaconing(t *testing.T) {
+	sb := &SmartBeaconing{
+		FastSpeed: 90, FastRate: 3 * time.Minute,
+		SlowSpeed: 5, SlowRate: 30 * time.Minute,
+		TurnAngle: 28, TurnSlope: 410, TurnTime: 15 * time.Second,
+	}
+
+	rates := []struct {
+		speed float64
+		want  time.Duration
+	}{
+		{0, 30 * time.Minute},
+		{5, 30 * time.Minute},
+		{45, 6 * time.Minute},
+		{90, 3 * time.Minute},
+		{120, 3 * time.Minute},
+	}
+	for _, tt := range rates {
+		if got := sb.Rate(tt.speed); got != tt.want {
+			t.Errorf("Rate(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+
+	now := time.Date(2026, 10, 9, 12, 0, 0, 0, time.UTC)
+	moving := func(speed float64, course int) Fix {
+		return Fix{HasCourseSpeed: true, Speed: speed, Course: course}
+	}
+	last := smartState{sent: now.Add(-time.Minute), course: 90, hasCourse: true}
+
+	tests := []struct {
+		name string
+		fix  Fix
+		last smartState
+		want bool
+	}{
+		{"第一次定位", moving(0, 0), smartState{}, true},
+		{"静止未到慢速间隔", Fix{}, last, false},
+		{"静止到达慢速间隔", Fix{}, smartState{sent: now.Add(-30 * time.Minute)}, true},
+		{"高速到达快速间隔", moving(100, 90), smartState{sent: now.Add(-3 * time.Minute), course: 90, hasCourse: true}, true},
+		{"中速未到间隔", moving(45, 90), last, false},
+		// 90km/h时转弯门限为 28+410/90 ≈ 32.6 度
+		{"高速转弯", moving(90, 125), last, true},
+		{"高速小角度转弯", moving(90, 120), last, false},
+		{"跨越正北转弯", moving(90, 340), smartState{sent: now.Add(-time.Minute), course: 20, hasCourse: true}, true},
+		// 10km/h时转弯门限为 28+41 = 69 度
+		{"低速转弯角度不足", moving(10, 150), last, false},
+		{"低速急转弯", moving(10, 180), last, true},
+		{"慢速时不检测转弯", moving(4, 270), last, false},
+		{"转弯间隔太短", moving(90, 180), smartState{sent: now.Add(-10 * time.Second), course: 90, hasCourse: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sb.due(tt.fix, tt.last, now); got != tt.want {
+				t.Errorf("due() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartScheduler(t *testing.T) {
+	savedPoll := smartPoll
+	smartPoll = 5 * time.Millisecond
+	defer func() { smartPoll = savedPoll }()
+
+	m := &fakeModem{}
+	b := station()
+	b.UseGPS, b.Delay, b.Comment = true, 0, ""
+	b.Smart = &SmartBeaconing{
+		FastSpeed: 90, FastRate: time.Hour,
+		SlowSpeed: 5, SlowRate: time.Hour,
+		TurnAngle: 28, TurnSlope: 410,
+	}
+
+	var mu sync.Mutex
+	fix := Fix{Latitude: 35.5, Longitude: 139.75, HasCourseSpeed: true, Course: 90, Speed: 90}
+	s := New(m, nil, []Beacon{b})
+	s.SetPositionSource(func() (Fix, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		return fix, true
+	})
+	s.Start(context.Background())
+	defer s.Stop()
+
+	// 有定位后立即发送压缩位置
+	waitFor(t, func() bool { return m.count() == 1 })
+	m.mu.Lock()
+	info := string(m.frames[0].Info)
+	m.mu.Unlock()
+	if info[0] != '!' || len(info) != 14 {
+		t.Errorf("应发送压缩位置: %q", info)
+	}
+
+	// 航向不变时不发送，转弯后立即发送
+	time.Sleep(30 * time.Millisecond)
+	if m.count() != 1 {
+		t.Fatalf("直行时不应发送，已发送 %d 个", m.count())
+	}
+	mu.Lock()
+	fix.Course = 180
+	mu.Unlock()
+	waitFor(t, func() bool { return m.count() == 2 })
+}
@@ -0,0 +1,112 @@
+package beacon
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// smartPoll SmartBeaconing检查GPS定位的间隔，测试时可替换
+var smartPoll = time.Second
+
+// SmartBeaconing HamHUD SmartBeaconing参数
+// 按速度在快慢两个间隔之间调整发送间隔，转弯超过门限时立即发送
+type SmartBeaconing struct {
+	FastSpeed float64       // 高速门限 (km/h)，达到时按FastRate发送
+	FastRate  time.Duration // 高速时的发送间隔
+	SlowSpeed float64       // 低速门限 (km/h)，低于时按SlowRate发送且不检测转弯
+	SlowRate  time.Duration // 低速时的发送间隔
+	TurnAngle float64       // 最小转弯角度 (度)
+	TurnSlope float64       // 转弯斜率 (度·km/h)，速度越低转弯门限越大
+	TurnTime  time.Duration // 两次转弯信标之间的最短时间
+}
+
+// smartState 上次发送的时间和航向
+type smartState struct {
+	sent      time.Time
+	course    int
+	hasCourse bool
+}
+
+// Rate 按速度计算发送间隔，两个门限之间与速度成反比
+func (sb *SmartBeaconing) Rate(speed float64) time.Duration {
+	switch {
+	case speed <= sb.SlowSpeed:
+		return sb.SlowRate
+	case speed >= sb.FastSpeed:
+		return sb.FastRate
+	default:
+		return time.Duration(float64(sb.FastRate) * sb.FastSpeed / speed)
+	}
+}
+
+// TurnThreshold 按速度计算触发发送的转弯角度
+func (sb *SmartBeaconing) TurnThreshold(speed float64) float64 {
+	return sb.TurnAngle + sb.TurnSlope/speed
+}
+
+// due 是否应在now发送信标
+func (sb *SmartBeaconing) due(fix Fix, last smartState, now time.Time) bool {
+	if last.sent.IsZero() {
+		return true
+	}
+	elapsed := now.Sub(last.sent)
+
+	var speed float64
+	if fix.HasCourseSpeed {
+		speed = fix.Speed
+	}
+	if elapsed >= sb.Rate(speed) {
+		return true
+	}
+
+	// 低速时航向不可靠，不检测转弯
+	if speed <= sb.SlowSpeed || !last.hasCourse || elapsed < sb.TurnTime {
+		return false
+	}
+	return headingChange(fix.Course, last.course) > sb.TurnThreshold(speed)
+}
+
+// headingChange 计算两个航向之间的夹角 (0-180度)
+func headingChange(a, b int) float64 {
+	diff := math.Mod(math.Abs(float64(a-b)), 360)
+	if diff > 180 {
+		diff = 360 - diff
+	}
+	return diff
+}
+
+// runSmart 按SmartBeaconing发送一个信标，没有GPS定位时等待
+func (s *Scheduler) runSmart(ctx context.Context, index int, b *Beacon) {
+	defer s.wg.Done()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(b.Delay):
+	}
+
+	ticker := time.NewTicker(smartPoll)
+	defer ticker.Stop()
+
+	var last smartState
+	for {
+		s.mu.Lock()
+		gps := s.gps
+		s.mu.Unlock()
+
+		if gps != nil {
+			if fix, ok := gps(); ok && b.Smart.due(fix, last, nowFunc()) {
+				// 发送失败时同样等待下一个间隔，避免每秒重试
+				s.sendFix(index, b, fix)
+				last = smartState{sent: nowFunc(), course: fix.Course, hasCourse: fix.HasCourseSpeed}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,14 +2,38 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"aprs_agent/ax25"
 
 	"github.com/spf13/viper"
 )
 
 // Config 表示应用程序的配置结构
 type Config struct {
-	Audio  AudioConfig  `mapstructure:"audio"`
-	System SystemConfig `mapstructure:"system"`
+	Audio      AudioConfig      `mapstructure:"audio"`
+	KISS       KISSConfig       `mapstructure:"kiss"`
+	AGW        AGWConfig        `mapstructure:"agw"`
+	IGate      IGateConfig      `mapstructure:"igate"`
+	Digipeater DigipeaterConfig `mapstructure:"digipeater"`
+	Station    StationConfig    `mapstructure:"station"`
+	GPS        GPSConfig        `mapstructure:"gps"`
+	System     SystemConfig     `mapstructure:"system"`
+
+	// Channels 无线电通道 [channel.N]，N从0开始连续编号
+	// 没有配置时由 [audio.*] 生成通道0
+	Channels map[string]ChannelConfig `mapstructure:"channel"`
+
+	// Beacons 定时信标 [beacon.N]
+	Beacons map[string]BeaconConfig `mapstructure:"beacon"`
+
+	// SmartBeaconing 设置了 smart 的信标使用的SmartBeaconing参数
+	SmartBeaconing SmartBeaconingConfig `mapstructure:"smartbeaconing"`
 }
 
 // AudioConfig 音频相关配置
@@ -17,6 +41,7 @@ type AudioConfig struct {
 	Input      InputConfig      `mapstructure:"input"`
 	Output     OutputConfig     `mapstructure:"output"`
 	Processing ProcessingConfig `mapstructure:"processing"`
+	Transmit   TransmitConfig   `mapstructure:"transmit"`
 }
 
 // InputConfig 输入音频配置
@@ -47,6 +72,248 @@ type ProcessingConfig struct {
 	Format           string `mapstructure:"format"`
 }
 
+// TransmitConfig 发射配置
+type TransmitConfig struct {
+	TxDelay    int  `mapstructure:"txdelay"`    // 发射前导时间 (毫秒)
+	TxTail     int  `mapstructure:"txtail"`     // 发射尾部时间 (毫秒)
+	Persist    int  `mapstructure:"persist"`    // CSMA p-persistence参数 (0-255)
+	SlotTime   int  `mapstructure:"slottime"`   // CSMA时隙 (毫秒)
+	FullDuplex bool `mapstructure:"fullduplex"` // 全双工，发射前不检测信道
+
+	MaxTxTime  int `mapstructure:"max_tx_time"` // 单次连续发射的最长时间 (秒)，超过时强制松开PTT并清空音频队列，0表示不限制
+	DutyCycle  int `mapstructure:"duty_cycle"`  // 每个通道滚动窗口内发射时间占比的上限 (百分比)，0表示不限制
+	DutyWindow int `mapstructure:"duty_window"` // 占空比统计窗口 (秒)
+}
+
+// ChannelConfig 无线电通道配置
+// 未设置的项使用 [audio.input] 和 [audio.transmit] 中的值
+type ChannelConfig struct {
+	Device   string `mapstructure:"device"`   // 音频设备名称，输入输出使用同名设备，每个不同的设备打开一块声卡
+	Side     string `mapstructure:"side"`     // 使用的声道: mono, left, right
+	Modem    string `mapstructure:"modem"`    // 调制方式: afsk
+	Baud     int    `mapstructure:"baud"`     // 波特率
+	Callsign string `mapstructure:"callsign"` // 通道呼号
+	PTT      string `mapstructure:"ptt"`      // PTT方式: none (VOX), serial, cm108, gpio, rigctld
+
+	PTTDevice string `mapstructure:"ptt_device"` // PTT设备，如串口 /dev/ttyUSB0、/dev/hidraw0、/dev/gpiochip0，rigctld为 host:port
+	PTTLine   string `mapstructure:"ptt_line"`   // 串口PTT使用的控制线: rts (默认), dtr
+	PTTGPIO   int    `mapstructure:"ptt_gpio"`   // CM108 PTT使用的GPIO (1-8，默认3)，或GPIO PTT的线偏移
+	PTTInvert bool   `mapstructure:"ptt_invert"` // 反转PTT电平，GPIO PTT为低电平有效
+
+	DCD          string  `mapstructure:"dcd"`           // 载波检测方式: demod (解调器锁定，默认), level (输入电平)
+	DCDThreshold float64 `mapstructure:"dcd_threshold"` // 电平载波检测的门限 (dBFS)，为0时使用-40
+
+	TxDelay    int  `mapstructure:"txdelay"`    // 发射前导时间 (毫秒)
+	TxTail     int  `mapstructure:"txtail"`     // 发射尾部时间 (毫秒)
+	Persist    int  `mapstructure:"persist"`    // CSMA p-persistence参数 (0-255)
+	SlotTime   int  `mapstructure:"slottime"`   // CSMA时隙 (毫秒)
+	FullDuplex bool `mapstructure:"fullduplex"` // 全双工，发射前不检测信道
+}
+
+// 声道
+const (
+	SideMono  = "mono"
+	SideLeft  = "left"
+	SideRight = "right"
+)
+
+// DeviceKey 获取用于比较的设备名称，不区分大小写并忽略首尾空白，空字符串表示默认设备
+func DeviceKey(device string) string {
+	return strings.ToLower(strings.TrimSpace(device))
+}
+
+// 载波检测方式
+const (
+	DCDDemod = "demod" // 解调器锁相环锁定且有码元跳变
+	DCDLevel = "level" // 输入电平超过门限，适合解调器无法锁定的信号
+)
+
+// KISSConfig KISS TNC服务配置
+type KISSConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Port       int    `mapstructure:"port"`
+	PtyEnabled bool   `mapstructure:"pty_enabled"` // 是否通过伪终端提供KISS (仅Linux)
+	PtyLink    string `mapstructure:"pty_link"`    // 伪终端从设备的符号链接
+}
+
+// AGWConfig AGWPE兼容服务配置
+type AGWConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
+}
+
+// StationConfig 本站固定位置，没有GPS定位时使用
+type StationConfig struct {
+	Latitude  float64 `mapstructure:"lat"`      // 纬度，北纬为正
+	Longitude float64 `mapstructure:"lon"`      // 经度，东经为正
+	Altitude  float64 `mapstructure:"altitude"` // 海拔 (米)
+}
+
+// HasLocation 是否设置了本站位置
+func (s StationConfig) HasLocation() bool {
+	return s.Latitude != 0 || s.Longitude != 0
+}
+
+// GPSConfig GPS配置
+type GPSConfig struct {
+	Type    string `mapstructure:"type"`    // 数据源: none (默认), nmea, gpsd
+	Device  string `mapstructure:"device"`  // NMEA串口或pty，如 /dev/ttyACM0
+	Baud    int    `mapstructure:"baud"`    // NMEA串口波特率，默认4800
+	Address string `mapstructure:"address"` // gpsd地址，默认 localhost:2947
+}
+
+// GPS数据源
+const (
+	GPSNone = "none"
+	GPSNMEA = "nmea"
+	GPSD    = "gpsd"
+)
+
+// IGateConfig APRS-IS iGate配置
+type IGateConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Server   string `mapstructure:"server"`   // APRS-IS服务器
+	Port     int    `mapstructure:"port"`     // APRS-IS端口
+	Callsign string `mapstructure:"callsign"` // 登录呼号
+	Passcode int    `mapstructure:"passcode"` // 验证码，-1表示根据呼号计算
+	Filter   string `mapstructure:"filter"`   // 服务器端过滤器
+
+	TxEnabled      bool   `mapstructure:"tx_enabled"`       // 是否将发给本地站点的APRS-IS消息转发到射频
+	TxPath         string `mapstructure:"tx_path"`          // 转发到射频使用的中继路径，逗号分隔
+	HeardMinutes   int    `mapstructure:"heard_minutes"`    // 站点被直接收听后视为本地站点的分钟数
+	TxLimitMinute  int    `mapstructure:"tx_limit_minute"`  // 每分钟转发到射频的最大数据包数
+	TxLimitStation int    `mapstructure:"tx_limit_station"` // 每个站点每分钟转发到射频的最大消息数
+}
+
+// DigipeaterConfig 中继器配置
+type DigipeaterConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	Callsign      string `mapstructure:"callsign"`       // 本站呼号
+	Aliases       string `mapstructure:"aliases"`        // 别名，逗号分隔
+	FillIn        bool   `mapstructure:"fill_in"`        // 补点中继，只处理WIDE1-1
+	MaxHops       int    `mapstructure:"max_hops"`       // WIDEn-N中n的最大值
+	DedupeSeconds int    `mapstructure:"dedupe_seconds"` // 重复包检测时间窗口（秒）
+	Preempt       string `mapstructure:"preempt"`        // 抢先中继模式: off, drop, mark, trace
+
+	Routes map[string]RouteConfig `mapstructure:"route"` // 路由规则 [digipeater.route.N]
+}
+
+// RouteConfig 中继路由规则
+type RouteConfig struct {
+	From         int    `mapstructure:"from"`          // 接收通道
+	To           int    `mapstructure:"to"`            // 发射通道
+	PathContains string `mapstructure:"path_contains"` // 只转发路径中含有该文本的帧，如 WIDE2
+	SourceRegex  string `mapstructure:"source_regex"`  // 只转发源呼号匹配该正则表达式的帧
+}
+
+// BeaconConfig 定时信标配置
+type BeaconConfig struct {
+	Type     string `mapstructure:"type"`     // 信标类型: position (默认), object, raw
+	Channel  int    `mapstructure:"channel"`  // 发射通道
+	APRSIS   bool   `mapstructure:"aprs_is"`  // 发送到APRS-IS而不是射频，需要启用iGate
+	Callsign string `mapstructure:"callsign"` // 源呼号，未设置时使用通道呼号，发送到APRS-IS时使用iGate呼号
+	Interval int    `mapstructure:"interval"` // 发送间隔 (秒)，默认1800
+	Delay    int    `mapstructure:"delay"`    // 启动后第一次发送的延迟 (秒)，默认60
+	Slot     string `mapstructure:"slot"`     // 时隙 "mm:ss"，设置后在每个间隔内的固定时刻发送，忽略delay
+	Path     string `mapstructure:"path"`     // 中继路径，逗号分隔
+
+	Symbol     string  `mapstructure:"symbol"`     // 符号表和符号代码，如 "/-"，默认 "/-"
+	Latitude   float64 `mapstructure:"lat"`        // 纬度，北纬为正
+	Longitude  float64 `mapstructure:"lon"`        // 经度，东经为正
+	GPS        bool    `mapstructure:"gps"`        // 使用GPS位置，没有定位时不发送
+	Altitude   float64 `mapstructure:"altitude"`   // 海拔 (米)，0表示不发送
+	Power      int     `mapstructure:"power"`      // PHG发射功率 (瓦)，0表示不发送PHG
+	Height     int     `mapstructure:"height"`     // PHG天线有效高度 (英尺)
+	Gain       int     `mapstructure:"gain"`       // PHG天线增益 (dB)
+	Dir        int     `mapstructure:"dir"`        // PHG天线方向 (度)，0表示全向
+	Compressed bool    `mapstructure:"compressed"` // 使用压缩位置格式
+	Messaging  bool    `mapstructure:"messaging"`  // 声明支持消息 (= 而不是 !)
+	Comment    string  `mapstructure:"comment"`    // 注释
+
+	ObjectName string `mapstructure:"object_name"` // 对象名称，type为object时使用
+	Raw        string `mapstructure:"raw"`         // TNC2格式的完整数据包，type为raw时使用，如 N0CALL>APRS,WIDE2-1:>状态
+
+	Smart bool `mapstructure:"smart"` // 按 [smartbeaconing] 参数发送GPS位置，忽略interval和slot
+}
+
+// 信标类型
+const (
+	BeaconPosition = "position"
+	BeaconObject   = "object"
+	BeaconRaw      = "raw"
+)
+
+// HasLocation 是否设置了信标位置 (本节或 [station] 中的lat/lon)
+func (b BeaconConfig) HasLocation() bool {
+	return b.Latitude != 0 || b.Longitude != 0
+}
+
+// GetInterval 获取信标发送间隔
+func (b BeaconConfig) GetInterval() time.Duration {
+	return time.Duration(b.Interval) * time.Second
+}
+
+// GetDelay 获取信标第一次发送的延迟
+func (b BeaconConfig) GetDelay() time.Duration {
+	return time.Duration(b.Delay) * time.Second
+}
+
+// GetSlot 解析时隙 "mm:ss" 或秒数，未设置时返回false
+func (b BeaconConfig) GetSlot() (time.Duration, bool, error) {
+	slot := strings.TrimSpace(b.Slot)
+	if slot == "" {
+		return 0, false, nil
+	}
+
+	var minutes, seconds int
+	var err error
+	if m, sec, ok := strings.Cut(slot, ":"); ok {
+		if minutes, err = strconv.Atoi(m); err == nil {
+			seconds, err = strconv.Atoi(sec)
+		}
+		if err == nil && (seconds < 0 || seconds >= 60) {
+			err = fmt.Errorf("秒数必须在0-59之间")
+		}
+	} else {
+		seconds, err = strconv.Atoi(slot)
+	}
+	if err != nil || minutes < 0 || seconds < 0 {
+		return 0, false, fmt.Errorf("无效的时隙 %q", b.Slot)
+	}
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, true, nil
+}
+
+// GetPath 获取信标中继路径
+func (b BeaconConfig) GetPath() []string {
+	return splitList(b.Path)
+}
+
+// SmartBeaconingConfig SmartBeaconing配置
+type SmartBeaconingConfig struct {
+	FastSpeed float64 `mapstructure:"fast_speed"` // 高速门限 (km/h)
+	FastRate  int     `mapstructure:"fast_rate"`  // 高速时的发送间隔 (秒)
+	SlowSpeed float64 `mapstructure:"slow_speed"` // 低速门限 (km/h)
+	SlowRate  int     `mapstructure:"slow_rate"`  // 低速时的发送间隔 (秒)
+	TurnAngle float64 `mapstructure:"turn_angle"` // 最小转弯角度 (度)
+	TurnSlope float64 `mapstructure:"turn_slope"` // 转弯斜率 (度·km/h)
+	TurnTime  int     `mapstructure:"turn_time"`  // 两次转弯信标之间的最短时间 (秒)
+}
+
+// GetFastRate 获取高速时的发送间隔
+func (sb SmartBeaconingConfig) GetFastRate() time.Duration {
+	return time.Duration(sb.FastRate) * time.Second
+}
+
+// GetSlowRate 获取低速时的发送间隔
+func (sb SmartBeaconingConfig) GetSlowRate() time.Duration {
+	return time.Duration(sb.SlowRate) * time.Second
+}
+
+// GetTurnTime 获取两次转弯信标之间的最短时间
+func (sb SmartBeaconingConfig) GetTurnTime() time.Duration {
+	return time.Duration(sb.TurnTime) * time.Second
+}
+
 // SystemConfig 系统配置
 type SystemConfig struct {
 	LogLevel             string `mapstructure:"log_level"`
@@ -72,6 +339,9 @@ func LoadConfig(filename string) (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
+	applyChannelDefaults(&config)
+	applyBeaconDefaults(&config)
+	config.GPS.Type = strings.ToLower(config.GPS.Type)
 
 	// 验证配置
 	if err := validateConfig(&config); err != nil {
@@ -101,6 +371,62 @@ func setDefaults() {
 	viper.SetDefault("audio.processing.auto_gain_control", true)
 	viper.SetDefault("audio.processing.format", "int16")
 
+	// 发射默认值
+	viper.SetDefault("audio.transmit.txdelay", 300)
+	viper.SetDefault("audio.transmit.txtail", 50)
+	viper.SetDefault("audio.transmit.persist", 63)
+	viper.SetDefault("audio.transmit.slottime", 100)
+	viper.SetDefault("audio.transmit.fullduplex", false)
+	viper.SetDefault("audio.transmit.max_tx_time", 10)
+	viper.SetDefault("audio.transmit.duty_cycle", 25)
+	viper.SetDefault("audio.transmit.duty_window", 600)
+
+	// KISS服务默认值
+	viper.SetDefault("kiss.enabled", true)
+	viper.SetDefault("kiss.port", 8001)
+	viper.SetDefault("kiss.pty_enabled", false)
+	viper.SetDefault("kiss.pty_link", "/tmp/kisstnc")
+
+	// AGWPE服务默认值
+	viper.SetDefault("agw.enabled", true)
+	viper.SetDefault("agw.port", 8000)
+
+	// iGate默认值
+	viper.SetDefault("igate.enabled", false)
+	viper.SetDefault("igate.server", "rotate.aprs2.net")
+	viper.SetDefault("igate.port", 14580)
+	viper.SetDefault("igate.callsign", "")
+	viper.SetDefault("igate.passcode", -1)
+	viper.SetDefault("igate.filter", "")
+	viper.SetDefault("igate.tx_enabled", false)
+	viper.SetDefault("igate.tx_path", "WIDE1-1")
+	viper.SetDefault("igate.heard_minutes", 30)
+	viper.SetDefault("igate.tx_limit_minute", 6)
+	viper.SetDefault("igate.tx_limit_station", 3)
+
+	// 中继器默认值
+	viper.SetDefault("digipeater.enabled", false)
+	viper.SetDefault("digipeater.callsign", "")
+	viper.SetDefault("digipeater.aliases", "")
+	viper.SetDefault("digipeater.fill_in", false)
+	viper.SetDefault("digipeater.max_hops", 2)
+	viper.SetDefault("digipeater.dedupe_seconds", 30)
+	viper.SetDefault("digipeater.preempt", "off")
+
+	// GPS默认值
+	viper.SetDefault("gps.type", GPSNone)
+	viper.SetDefault("gps.baud", 4800)
+	viper.SetDefault("gps.address", "localhost:2947")
+
+	// SmartBeaconing默认值 (HamHUD默认值换算为km/h)
+	viper.SetDefault("smartbeaconing.fast_speed", 90)
+	viper.SetDefault("smartbeaconing.fast_rate", 180)
+	viper.SetDefault("smartbeaconing.slow_speed", 5)
+	viper.SetDefault("smartbeaconing.slow_rate", 1800)
+	viper.SetDefault("smartbeaconing.turn_angle", 28)
+	viper.SetDefault("smartbeaconing.turn_slope", 410)
+	viper.SetDefault("smartbeaconing.turn_time", 15)
+
 	// 系统默认值
 	viper.SetDefault("system.log_level", "info")
 	viper.SetDefault("system.list_devices_on_startup", true)
@@ -148,9 +474,500 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("音频格式必须是 'int16' 或 'float32'")
 	}
 
+	// 验证发射时间
+	transmit := config.Audio.Transmit
+	if err := validateTxTimings(transmit.TxDelay, transmit.TxTail, transmit.Persist, transmit.SlotTime); err != nil {
+		return err
+	}
+	if transmit.MaxTxTime < 0 || transmit.MaxTxTime > 600 {
+		return fmt.Errorf("最长发射时间必须在0-600秒之间")
+	}
+	if transmit.DutyCycle < 0 || transmit.DutyCycle > 100 {
+		return fmt.Errorf("发射占空比必须在0-100之间")
+	}
+	if transmit.DutyCycle > 0 && (transmit.DutyWindow <= 0 || transmit.DutyWindow > 86400) {
+		return fmt.Errorf("占空比统计窗口必须在1-86400秒之间")
+	}
+
+	// 验证通道配置
+	if err := validateChannels(config); err != nil {
+		return err
+	}
+
+	// 验证本站位置和GPS配置
+	if config.Station.Latitude < -90 || config.Station.Latitude > 90 || config.Station.Longitude < -180 || config.Station.Longitude > 180 {
+		return fmt.Errorf("本站经纬度超出范围")
+	}
+	switch config.GPS.Type {
+	case "", GPSNone:
+	case GPSNMEA:
+		if config.GPS.Device == "" {
+			return fmt.Errorf("NMEA GPS必须设置串口设备")
+		}
+		switch config.GPS.Baud {
+		case 4800, 9600, 19200, 38400, 57600, 115200:
+		default:
+			return fmt.Errorf("不支持的GPS串口波特率: %d", config.GPS.Baud)
+		}
+	case GPSD:
+		if _, _, err := net.SplitHostPort(config.GPS.Address); err != nil {
+			return fmt.Errorf("gpsd地址无效: %w", err)
+		}
+	default:
+		return fmt.Errorf("不支持的GPS数据源: %s (可选 none, nmea, gpsd)", config.GPS.Type)
+	}
+
+	// 验证信标配置
+	if err := validateBeacons(config); err != nil {
+		return err
+	}
+
+	// 验证KISS服务端口
+	if config.KISS.Enabled && (config.KISS.Port <= 0 || config.KISS.Port > 65535) {
+		return fmt.Errorf("KISS端口必须在1-65535之间")
+	}
+	if config.KISS.PtyEnabled && config.KISS.PtyLink == "" {
+		return fmt.Errorf("KISS伪终端符号链接路径不能为空")
+	}
+
+	// 验证AGWPE服务端口
+	if config.AGW.Enabled && (config.AGW.Port <= 0 || config.AGW.Port > 65535) {
+		return fmt.Errorf("AGW端口必须在1-65535之间")
+	}
+	if config.AGW.Enabled && config.KISS.Enabled && config.AGW.Port == config.KISS.Port {
+		return fmt.Errorf("AGW端口不能与KISS端口相同")
+	}
+
+	// 验证iGate配置
+	if config.IGate.Enabled {
+		if config.IGate.Server == "" {
+			return fmt.Errorf("iGate服务器地址不能为空")
+		}
+		if config.IGate.Port <= 0 || config.IGate.Port > 65535 {
+			return fmt.Errorf("iGate端口必须在1-65535之间")
+		}
+		if config.IGate.Callsign == "" {
+			return fmt.Errorf("启用iGate时必须设置呼号")
+		}
+		if config.IGate.Passcode < -1 || config.IGate.Passcode > 32767 {
+			return fmt.Errorf("iGate验证码必须在0-32767之间，或为-1表示自动计算")
+		}
+		if config.IGate.TxEnabled {
+			if config.IGate.HeardMinutes <= 0 {
+				return fmt.Errorf("iGate本地站点时间窗口必须大于0")
+			}
+			if config.IGate.TxLimitMinute <= 0 || config.IGate.TxLimitStation <= 0 {
+				return fmt.Errorf("iGate发射速率限制必须大于0")
+			}
+		}
+	}
+
+	// 验证中继器配置
+	if config.Digipeater.Enabled {
+		if config.Digipeater.Callsign == "" {
+			return fmt.Errorf("启用中继器时必须设置呼号")
+		}
+		if config.Digipeater.MaxHops < 1 || config.Digipeater.MaxHops > 7 {
+			return fmt.Errorf("中继器最大跳数必须在1-7之间")
+		}
+		if config.Digipeater.DedupeSeconds <= 0 {
+			return fmt.Errorf("中继器重复包检测时间窗口必须大于0")
+		}
+		switch strings.ToLower(config.Digipeater.Preempt) {
+		case "", "off", "drop", "mark", "trace":
+		default:
+			return fmt.Errorf("无效的抢先中继模式: %s (可选 off, drop, mark, trace)", config.Digipeater.Preempt)
+		}
+		for name, route := range config.Digipeater.Routes {
+			if route.From < 0 || route.To < 0 {
+				return fmt.Errorf("中继路由 %s 的通道号不能为负数", name)
+			}
+			if n := len(config.Channels); n > 0 && (route.From >= n || route.To >= n) {
+				return fmt.Errorf("中继路由 %s 的通道不存在", name)
+			}
+			if route.SourceRegex != "" {
+				if _, err := regexp.Compile(route.SourceRegex); err != nil {
+					return fmt.Errorf("中继路由 %s 的源呼号正则表达式无效: %w", name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateTxTimings 验证发射时间参数
+func validateTxTimings(txDelay, txTail, persist, slotTime int) error {
+	if txDelay < 0 || txDelay > 2000 {
+		return fmt.Errorf("发射前导时间必须在0-2000毫秒之间")
+	}
+	if txTail < 0 || txTail > 2000 {
+		return fmt.Errorf("发射尾部时间必须在0-2000毫秒之间")
+	}
+	if persist < 0 || persist > 255 {
+		return fmt.Errorf("persist参数必须在0-255之间")
+	}
+	if slotTime < 0 || slotTime > 2550 {
+		return fmt.Errorf("时隙必须在0-2550毫秒之间")
+	}
+	return nil
+}
+
+// validateChannels 验证通道配置：编号从0开始连续，同一设备的声道不能重叠
+func validateChannels(config *Config) error {
+	names := make([]string, 0, len(config.Channels))
+	for name := range config.Channels {
+		names = append(names, name)
+	}
+	sortNames(names)
+
+	// 每个设备已使用的声道
+	used := make(map[string]map[string]string)
+
+	for i, name := range names {
+		if n, err := strconv.Atoi(name); err != nil || n != i {
+			return fmt.Errorf("通道编号必须从0开始连续，无效的通道: channel.%s", name)
+		}
+		ch := config.Channels[name]
+
+		switch ch.Side {
+		case SideMono:
+		case SideLeft, SideRight:
+			if config.Audio.Input.Channels != 2 {
+				return fmt.Errorf("通道 %s: 使用左/右声道时输入声道数必须为2", name)
+			}
+			if config.Audio.Output.Channels != 2 {
+				return fmt.Errorf("通道 %s: 使用左/右声道时输出声道数必须为2", name)
+			}
+		default:
+			return fmt.Errorf("通道 %s: 声道必须是 mono、left 或 right", name)
+		}
+
+		if ch.Modem != "afsk" {
+			return fmt.Errorf("通道 %s: 不支持的调制方式: %s", name, ch.Modem)
+		}
+		if ch.Baud != 1200 {
+			return fmt.Errorf("通道 %s: afsk只支持1200波特", name)
+		}
+
+		if ch.Callsign != "" {
+			if _, err := ax25.ParseAddress(ch.Callsign); err != nil {
+				return fmt.Errorf("通道 %s: 呼号无效: %w", name, err)
+			}
+		}
+
+		switch ch.PTT {
+		case "none":
+		case "serial":
+			if ch.PTTDevice == "" {
+				return fmt.Errorf("通道 %s: 串口PTT必须配置 ptt_device", name)
+			}
+			switch ch.PTTLine {
+			case "", "rts", "dtr":
+			default:
+				return fmt.Errorf("通道 %s: 串口PTT控制线必须是 rts 或 dtr", name)
+			}
+		case "cm108":
+			// 未配置 ptt_device 时查找与音频设备属于同一USB设备的hidraw节点
+			if ch.PTTGPIO < 0 || ch.PTTGPIO > 8 {
+				return fmt.Errorf("通道 %s: CM108 GPIO必须在1到8之间", name)
+			}
+		case "gpio":
+			// 未配置 ptt_device 时使用 /dev/gpiochip0
+			if ch.PTTGPIO < 0 {
+				return fmt.Errorf("通道 %s: GPIO线偏移不能为负数", name)
+			}
+		case "rigctld":
+			// 未配置 ptt_device 时使用 localhost:4532
+			if ch.PTTDevice != "" {
+				if _, _, err := net.SplitHostPort(ch.PTTDevice); err != nil {
+					return fmt.Errorf("通道 %s: rigctld地址无效: %w", name, err)
+				}
+			}
+		default:
+			return fmt.Errorf("通道 %s: 不支持的PTT方式: %s", name, ch.PTT)
+		}
+
+		switch ch.DCD {
+		case "", DCDDemod, DCDLevel:
+		default:
+			return fmt.Errorf("通道 %s: 不支持的载波检测方式: %s", name, ch.DCD)
+		}
+		if ch.DCDThreshold < -96 || ch.DCDThreshold > 0 {
+			return fmt.Errorf("通道 %s: 载波检测门限必须在-96到0dBFS之间", name)
+		}
+
+		if err := validateTxTimings(ch.TxDelay, ch.TxTail, ch.Persist, ch.SlotTime); err != nil {
+			return fmt.Errorf("通道 %s: %w", name, err)
+		}
+
+		// 单声道占用整个设备，左右声道可以分给两个通道
+		device := DeviceKey(ch.Device)
+		sides := used[device]
+		if sides == nil {
+			sides = make(map[string]string)
+			used[device] = sides
+		}
+		for side, other := range sides {
+			if side == ch.Side || side == SideMono || ch.Side == SideMono {
+				return fmt.Errorf("通道 %s 与通道 %s 使用了同一设备 %q 的相同声道", name, other, ch.Device)
+			}
+		}
+		sides[ch.Side] = name
+	}
 	return nil
 }
 
+// applyChannelDefaults 通道中未设置的项使用 [audio.*] 中的值，没有通道配置时由 [audio.*] 生成通道0
+func applyChannelDefaults(config *Config) {
+	if len(config.Channels) == 0 {
+		config.Channels = map[string]ChannelConfig{"0": {}}
+	}
+
+	transmit := config.Audio.Transmit
+	for name, ch := range config.Channels {
+		prefix := "channel." + name + "."
+		if !viper.IsSet(prefix + "device") {
+			ch.Device = config.Audio.Input.DeviceName
+		}
+		if ch.Side == "" {
+			ch.Side = SideMono
+		}
+		if ch.Modem == "" {
+			ch.Modem = "afsk"
+		}
+		if ch.Baud == 0 {
+			ch.Baud = 1200
+		}
+		if ch.PTT == "" {
+			ch.PTT = "none"
+		}
+		if !viper.IsSet(prefix + "txdelay") {
+			ch.TxDelay = transmit.TxDelay
+		}
+		if !viper.IsSet(prefix + "txtail") {
+			ch.TxTail = transmit.TxTail
+		}
+		if !viper.IsSet(prefix + "persist") {
+			ch.Persist = transmit.Persist
+		}
+		if !viper.IsSet(prefix + "slottime") {
+			ch.SlotTime = transmit.SlotTime
+		}
+		if !viper.IsSet(prefix + "fullduplex") {
+			ch.FullDuplex = transmit.FullDuplex
+		}
+		ch.Side = strings.ToLower(ch.Side)
+		ch.Modem = strings.ToLower(ch.Modem)
+		ch.PTT = strings.ToLower(ch.PTT)
+		ch.PTTLine = strings.ToLower(ch.PTTLine)
+		ch.DCD = strings.ToLower(ch.DCD)
+		config.Channels[name] = ch
+	}
+}
+
+// applyBeaconDefaults 设置信标未配置项的默认值
+func applyBeaconDefaults(config *Config) {
+	for name, b := range config.Beacons {
+		prefix := "beacon." + name + "."
+		if b.Type == "" {
+			b.Type = BeaconPosition
+		}
+		if b.Interval == 0 {
+			b.Interval = 1800
+		}
+		if !viper.IsSet(prefix + "delay") {
+			b.Delay = 60
+		}
+		if b.Symbol == "" {
+			b.Symbol = "/-"
+		}
+		// 未设置位置时使用本站位置
+		if !viper.IsSet(prefix+"lat") && !viper.IsSet(prefix+"lon") {
+			b.Latitude, b.Longitude = config.Station.Latitude, config.Station.Longitude
+			if !viper.IsSet(prefix + "altitude") {
+				b.Altitude = config.Station.Altitude
+			}
+		}
+		// SmartBeaconing按GPS速度和航向发送
+		if b.Smart {
+			b.GPS = true
+		}
+		b.Type = strings.ToLower(b.Type)
+		config.Beacons[name] = b
+	}
+}
+
+// validateBeacons 验证信标配置
+func validateBeacons(config *Config) error {
+	channels := channelConfigsOrDefault(config)
+	for name, b := range config.Beacons {
+		if b.Smart {
+			if b.Type != BeaconPosition {
+				return fmt.Errorf("信标 %s: SmartBeaconing只能用于位置信标", name)
+			}
+			if err := validateSmartBeaconing(config.SmartBeaconing); err != nil {
+				return fmt.Errorf("信标 %s: %w", name, err)
+			}
+		}
+
+		switch b.Type {
+		case BeaconPosition, BeaconObject, BeaconRaw:
+		default:
+			return fmt.Errorf("信标 %s: 不支持的类型: %s", name, b.Type)
+		}
+
+		if b.Interval < 60 || b.Interval > 86400 {
+			return fmt.Errorf("信标 %s: 发送间隔必须在60-86400秒之间", name)
+		}
+		if b.Delay < 0 {
+			return fmt.Errorf("信标 %s: 发送延迟不能为负数", name)
+		}
+		slot, hasSlot, err := b.GetSlot()
+		if err != nil {
+			return fmt.Errorf("信标 %s: %w", name, err)
+		}
+		if hasSlot && slot >= b.GetInterval() {
+			return fmt.Errorf("信标 %s: 时隙必须小于发送间隔", name)
+		}
+
+		// 发送目标和源呼号
+		callsign := b.Callsign
+		if b.APRSIS {
+			if !config.IGate.Enabled {
+				return fmt.Errorf("信标 %s: 发送到APRS-IS需要启用iGate", name)
+			}
+			if callsign == "" {
+				callsign = config.IGate.Callsign
+			}
+		} else {
+			if b.Channel < 0 || b.Channel >= len(channels) {
+				return fmt.Errorf("信标 %s: 通道 %d 不存在", name, b.Channel)
+			}
+			if callsign == "" {
+				callsign = channels[b.Channel].Callsign
+			}
+		}
+
+		if b.Type == BeaconRaw {
+			if b.Raw == "" {
+				return fmt.Errorf("信标 %s: raw类型必须设置 raw", name)
+			}
+			// 发送到射频的数据包必须是有效的AX.25帧，APRS-IS允许非AX.25路径
+			if !b.APRSIS {
+				if _, err := ax25.ParseTNC2(b.Raw); err != nil {
+					return fmt.Errorf("信标 %s: raw数据包无效: %w", name, err)
+				}
+			} else if !strings.Contains(b.Raw, ">") || !strings.Contains(b.Raw, ":") {
+				return fmt.Errorf("信标 %s: raw数据包必须是TNC2格式", name)
+			}
+			continue
+		}
+
+		if callsign == "" {
+			return fmt.Errorf("信标 %s: 必须设置呼号", name)
+		}
+		if _, err := ax25.ParseAddress(callsign); err != nil && !b.APRSIS {
+			return fmt.Errorf("信标 %s: 呼号无效: %w", name, err)
+		}
+		for _, digi := range b.GetPath() {
+			if _, err := ax25.ParseAddress(digi); err != nil {
+				return fmt.Errorf("信标 %s: 中继路径无效: %w", name, err)
+			}
+		}
+
+		if len(b.Symbol) != 2 {
+			return fmt.Errorf("信标 %s: 符号必须是符号表和符号代码两个字符", name)
+		}
+		if b.GPS && (config.GPS.Type == "" || config.GPS.Type == GPSNone) {
+			return fmt.Errorf("信标 %s: 使用GPS位置或SmartBeaconing时必须在 [gps] 中设置数据源", name)
+		}
+		if !b.GPS && !b.HasLocation() {
+			return fmt.Errorf("信标 %s: 必须设置 lat/lon、在 [station] 中设置本站位置或使用GPS", name)
+		}
+		if !b.GPS && (b.Latitude < -90 || b.Latitude > 90 || b.Longitude < -180 || b.Longitude > 180) {
+			return fmt.Errorf("信标 %s: 经纬度超出范围", name)
+		}
+		if b.Power < 0 || b.Height < 0 || b.Gain < 0 || b.Gain > 9 || b.Dir < 0 || b.Dir >= 360 {
+			return fmt.Errorf("信标 %s: PHG参数无效", name)
+		}
+		if b.Type == BeaconObject && (b.ObjectName == "" || len(b.ObjectName) > 9) {
+			return fmt.Errorf("信标 %s: 对象名称长度必须为1-9个字符", name)
+		}
+	}
+	return nil
+}
+
+// validateSmartBeaconing 验证SmartBeaconing参数
+func validateSmartBeaconing(sb SmartBeaconingConfig) error {
+	if sb.SlowSpeed <= 0 || sb.FastSpeed <= sb.SlowSpeed {
+		return fmt.Errorf("SmartBeaconing高速门限必须大于低速门限，低速门限必须大于0")
+	}
+	if sb.FastRate <= 0 || sb.SlowRate < sb.FastRate {
+		return fmt.Errorf("SmartBeaconing低速发送间隔不能小于高速发送间隔，高速发送间隔必须大于0")
+	}
+	if sb.TurnAngle < 0 || sb.TurnAngle > 180 {
+		return fmt.Errorf("SmartBeaconing最小转弯角度必须在0-180度之间")
+	}
+	if sb.TurnSlope < 0 || sb.TurnTime < 0 {
+		return fmt.Errorf("SmartBeaconing转弯斜率和转弯时间不能为负数")
+	}
+	return nil
+}
+
+// channelConfigsOrDefault 获取通道配置，没有通道配置时视为只有通道0
+func channelConfigsOrDefault(config *Config) []ChannelConfig {
+	if channels := config.GetChannelConfigs(); len(channels) > 0 {
+		return channels
+	}
+	return []ChannelConfig{{}}
+}
+
+// GetBeaconConfigs 获取按编号排序的信标配置
+func (c *Config) GetBeaconConfigs() []BeaconConfig {
+	names := make([]string, 0, len(c.Beacons))
+	for name := range c.Beacons {
+		names = append(names, name)
+	}
+	sortNames(names)
+
+	beacons := make([]BeaconConfig, 0, len(names))
+	for _, name := range names {
+		beacons = append(beacons, c.Beacons[name])
+	}
+	return beacons
+}
+
+// GetChannelConfigs 获取按编号排序的通道配置
+func (c *Config) GetChannelConfigs() []ChannelConfig {
+	names := make([]string, 0, len(c.Channels))
+	for name := range c.Channels {
+		names = append(names, name)
+	}
+	sortNames(names)
+
+	channels := make([]ChannelConfig, 0, len(names))
+	for _, name := range names {
+		channels = append(channels, c.Channels[name])
+	}
+	return channels
+}
+
+// GetTxDelay 获取通道发射前导时间
+func (ch ChannelConfig) GetTxDelay() time.Duration {
+	return time.Duration(ch.TxDelay) * time.Millisecond
+}
+
+// GetTxTail 获取通道发射尾部时间
+func (ch ChannelConfig) GetTxTail() time.Duration {
+	return time.Duration(ch.TxTail) * time.Millisecond
+}
+
+// GetSlotTime 获取通道CSMA时隙
+func (ch ChannelConfig) GetSlotTime() time.Duration {
+	return time.Duration(ch.SlotTime) * time.Millisecond
+}
+
 // GetString 获取字符串配置值
 func (c *Config) GetString(key string) string {
 	return viper.GetString(key)
@@ -226,6 +1043,90 @@ func (c *Config) IsAutoGainControlEnabled() bool {
 	return c.Audio.Processing.AutoGainControl
 }
 
+// GetTxDelay 获取发射前导时间
+func (c *Config) GetTxDelay() time.Duration {
+	return time.Duration(c.Audio.Transmit.TxDelay) * time.Millisecond
+}
+
+// GetTxTail 获取发射尾部时间
+func (c *Config) GetTxTail() time.Duration {
+	return time.Duration(c.Audio.Transmit.TxTail) * time.Millisecond
+}
+
+// GetIGateTxPath 获取iGate转发到射频使用的中继路径
+func (c *Config) GetIGateTxPath() []string {
+	return splitList(c.IGate.TxPath)
+}
+
+// GetDigipeaterAliases 获取中继器别名
+func (c *Config) GetDigipeaterAliases() []string {
+	return splitList(c.Digipeater.Aliases)
+}
+
+// GetDedupeWindow 获取中继器重复包检测时间窗口
+func (c *Config) GetDedupeWindow() time.Duration {
+	return time.Duration(c.Digipeater.DedupeSeconds) * time.Second
+}
+
+// GetDigipeaterRoutes 获取中继路由规则，按规则名排序（数字名按数值）
+func (c *Config) GetDigipeaterRoutes() []RouteConfig {
+	names := make([]string, 0, len(c.Digipeater.Routes))
+	for name := range c.Digipeater.Routes {
+		names = append(names, name)
+	}
+	sortNames(names)
+
+	routes := make([]RouteConfig, 0, len(names))
+	for _, name := range names {
+		routes = append(routes, c.Digipeater.Routes[name])
+	}
+	return routes
+}
+
+// sortNames 排序配置节名称，数字名按数值排在前面
+func sortNames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		a, errA := strconv.Atoi(names[i])
+		b, errB := strconv.Atoi(names[j])
+		switch {
+		case errA == nil && errB == nil:
+			return a < b
+		case errA == nil:
+			return true
+		case errB == nil:
+			return false
+		default:
+			return names[i] < names[j]
+		}
+	})
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetSlotTime 获取CSMA时隙
+func (c *Config) GetSlotTime() time.Duration {
+	return time.Duration(c.Audio.Transmit.SlotTime) * time.Millisecond
+}
+
+// GetMaxTxTime 获取单次连续发射的最长时间，为0时不限制
+func (c *Config) GetMaxTxTime() time.Duration {
+	return time.Duration(c.Audio.Transmit.MaxTxTime) * time.Second
+}
+
+// GetDutyWindow 获取占空比统计窗口
+func (c *Config) GetDutyWindow() time.Duration {
+	return time.Duration(c.Audio.Transmit.DutyWindow) * time.Second
+}
+
 // GetLogLevel 获取日志级别
 func (c *Config) GetLogLevel() string {
 	return c.System.LogLevel
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -261,6 +262,35 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "占空比缺少统计窗口",
+			config: &Config{
+				Audio: AudioConfig{
+					Input: InputConfig{
+						SampleRate: 44100,
+						Channels:   2,
+						BufferSize: 1024,
+						Gain:       1.0,
+						Format:     "int16",
+					},
+					Output: OutputConfig{
+						SampleRate: 44100,
+						Channels:   2,
+						BufferSize: 1024,
+						Volume:     0.8,
+						Format:     "int16",
+					},
+					Processing: ProcessingConfig{
+						Format: "int16",
+					},
+					Transmit: TransmitConfig{
+						MaxTxTime: 10,
+						DutyCycle: 25,
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -353,3 +383,441 @@ func TestConfigGetters(t *testing.T) {
 		t.Errorf("GetStreamTimeout() = %v, want %v", got, 4000)
 	}
 }
+
+func TestDigipeaterRoutes(t *testing.T) {
+	configContent := `[audio.input]
+channels = 2
+
+[audio.output]
+channels = 2
+
+[channel.0]
+side = "left"
+
+[channel.1]
+side = "right"
+
+[digipeater]
+enabled = true
+callsign = "BG0DIG"
+
+[digipeater.route.10]
+from = 1
+to = 1
+
+[digipeater.route.2]
+from = 0
+to = 1
+path_contains = "WIDE2"
+source_regex = "^B[A-Z]"
+`
+
+	tmpFile, err := os.CreateTemp("", "test_config_*.conf")
+	if err != nil {
+		t.Fatalf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.WriteString(configContent)
+	tmpFile.Close()
+
+	cfg, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+
+	routes := cfg.GetDigipeaterRoutes()
+	want := []RouteConfig{
+		{From: 0, To: 1, PathContains: "WIDE2", SourceRegex: "^B[A-Z]"},
+		{From: 1, To: 1},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("期望 %d 条路由规则，实际为 %d", len(want), len(routes))
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("路由规则 %d = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+
+	// 无效的正则表达式
+	cfg.Digipeater.Routes["2"] = RouteConfig{From: 0, To: 1, SourceRegex: "("}
+	if err := validateConfig(cfg); err == nil {
+		t.Error("期望正则表达式验证错误")
+	}
+
+	// 不存在的通道
+	cfg.Digipeater.Routes["2"] = RouteConfig{From: 0, To: 2}
+	if err := validateConfig(cfg); err == nil {
+		t.Error("期望通道不存在错误")
+	}
+}
+
+// loadTestConfig 从字符串加载配置
+func loadTestConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	tmpFile, err := os.CreateTemp("", "test_config_*.conf")
+	if err != nil {
+		t.Fatalf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.WriteString(content)
+	tmpFile.Close()
+	return LoadConfig(tmpFile.Name())
+}
+
+func TestChannelConfig(t *testing.T) {
+	// 没有通道配置时由 [audio.*] 生成通道0
+	cfg, err := loadTestConfig(t, `[audio.input]
+device_name = "USB Audio"
+
+[audio.transmit]
+txdelay = 400
+`)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	channels := cfg.GetChannelConfigs()
+	want := ChannelConfig{
+		Device: "USB Audio", Side: SideMono, Modem: "afsk", Baud: 1200, PTT: "none",
+		TxDelay: 400, TxTail: 50, Persist: 63, SlotTime: 100,
+	}
+	if len(channels) != 1 || channels[0] != want {
+		t.Errorf("通道配置 = %+v, want %+v", channels, want)
+	}
+
+	// 立体声声卡的左右声道分给两个通道，未设置的项使用 [audio.*] 中的值
+	cfg, err = loadTestConfig(t, `[audio.input]
+device_name = "USB Audio"
+channels = 2
+
+[audio.output]
+channels = 2
+
+[audio.transmit]
+txdelay = 400
+fullduplex = true
+
+[channel.0]
+side = "left"
+callsign = "BG0ABC-1"
+
+[channel.1]
+side = "Right"
+callsign = "BG0ABC-2"
+txdelay = 0
+fullduplex = false
+`)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	channels = cfg.GetChannelConfigs()
+	if len(channels) != 2 {
+		t.Fatalf("期望2个通道，实际为 %d", len(channels))
+	}
+	if ch := channels[0]; ch.Device != "USB Audio" || ch.Side != SideLeft || ch.TxDelay != 400 || !ch.FullDuplex || ch.Callsign != "BG0ABC-1" {
+		t.Errorf("通道0配置错误: %+v", ch)
+	}
+	if ch := channels[1]; ch.Side != SideRight || ch.TxDelay != 0 || ch.FullDuplex || ch.GetTxTail() != 50*time.Millisecond {
+		t.Errorf("通道1配置错误: %+v", ch)
+	}
+}
+
+func TestValidateChannels(t *testing.T) {
+	channel := func(device, side string) ChannelConfig {
+		return ChannelConfig{Device: device, Side: side, Modem: "afsk", Baud: 1200, PTT: "none", Persist: 63, SlotTime: 100}
+	}
+
+	tests := []struct {
+		name     string
+		channels map[string]ChannelConfig
+		wantErr  bool
+	}{
+		{"左右声道", map[string]ChannelConfig{"0": channel("usb", SideLeft), "1": channel("usb", SideRight)}, false},
+		{"不同设备", map[string]ChannelConfig{"0": channel("usb", SideMono), "1": channel("hdmi", SideMono)}, false},
+		{"相同声道", map[string]ChannelConfig{"0": channel("usb", SideLeft), "1": channel("USB", SideLeft)}, true},
+		{"单声道与左声道", map[string]ChannelConfig{"0": channel("usb", SideMono), "1": channel("usb", SideLeft)}, true},
+		{"编号不连续", map[string]ChannelConfig{"0": channel("usb", SideLeft), "2": channel("usb", SideRight)}, true},
+		{"编号不是数字", map[string]ChannelConfig{"a": channel("usb", SideMono)}, true},
+		{"无效声道", map[string]ChannelConfig{"0": channel("usb", "center")}, true},
+		{"无效波特率", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.Baud = 9600; return ch }()}, true},
+		{"无效呼号", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.Callsign = "TOOLONGCALL"; return ch }()}, true},
+		{"无效PTT", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.PTT = "magic"; return ch }()}, true},
+		{"串口PTT", map[string]ChannelConfig{"0": func() ChannelConfig {
+			ch := channel("usb", SideMono)
+			ch.PTT, ch.PTTDevice, ch.PTTLine = "serial", "/dev/ttyUSB0", "dtr"
+			return ch
+		}()}, false},
+		{"CM108 PTT", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.PTT = "cm108"; return ch }()}, false},
+		{"CM108无效GPIO", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.PTT, ch.PTTGPIO = "cm108", 9; return ch }()}, true},
+		{"GPIO PTT", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.PTT, ch.PTTGPIO = "gpio", 17; return ch }()}, false},
+		{"GPIO无效线偏移", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.PTT, ch.PTTGPIO = "gpio", -1; return ch }()}, true},
+		{"rigctld PTT", map[string]ChannelConfig{"0": func() ChannelConfig {
+			ch := channel("usb", SideMono)
+			ch.PTT, ch.PTTDevice = "rigctld", "localhost:4532"
+			return ch
+		}()}, false},
+		{"rigctld无效地址", map[string]ChannelConfig{"0": func() ChannelConfig {
+			ch := channel("usb", SideMono)
+			ch.PTT, ch.PTTDevice = "rigctld", "localhost"
+			return ch
+		}()}, true},
+		{"串口PTT未配置设备", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.PTT = "serial"; return ch }()}, true},
+		{"串口PTT无效控制线", map[string]ChannelConfig{"0": func() ChannelConfig {
+			ch := channel("usb", SideMono)
+			ch.PTT, ch.PTTDevice, ch.PTTLine = "serial", "/dev/ttyUSB0", "cts"
+			return ch
+		}()}, true},
+		{"无效时隙", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.SlotTime = 5000; return ch }()}, true},
+		{"电平载波检测", map[string]ChannelConfig{"0": func() ChannelConfig {
+			ch := channel("usb", SideMono)
+			ch.DCD, ch.DCDThreshold = DCDLevel, -35
+			return ch
+		}()}, false},
+		{"无效载波检测方式", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.DCD = "squelch"; return ch }()}, true},
+		{"无效载波检测门限", map[string]ChannelConfig{"0": func() ChannelConfig { ch := channel("usb", SideMono); ch.DCD, ch.DCDThreshold = DCDLevel, 6; return ch }()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Audio: AudioConfig{Input: InputConfig{Channels: 2}, Output: OutputConfig{Channels: 2}}, Channels: tt.channels}
+			err := validateChannels(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateChannels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 单声道输入不能使用左右声道
+	cfg := &Config{Audio: AudioConfig{Input: InputConfig{Channels: 1}, Output: OutputConfig{Channels: 2}}, Channels: map[string]ChannelConfig{"0": channel("usb", SideLeft)}}
+	if err := validateChannels(cfg); err == nil {
+		t.Error("单声道输入使用左声道应验证失败")
+	}
+
+	// 单声道输出不能使用左右声道
+	cfg = &Config{Audio: AudioConfig{Input: InputConfig{DeviceName: "USB", Channels: 2}, Output: OutputConfig{Channels: 1}}, Channels: map[string]ChannelConfig{"0": channel("usb", SideRight)}}
+	if err := validateChannels(cfg); err == nil {
+		t.Error("单声道输出使用右声道应验证失败")
+	}
+}
+
+func TestBeaconConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, `[channel.0]
+callsign = "BG0ABC-10"
+
+[beacon.1]
+type = "Object"
+object_name = "EVENT"
+lat = 39.9
+lon = 116.4
+delay = 0
+
+[beacon.0]
+lat = 39.9
+lon = 116.4
+slot = "03:30"
+path = "WIDE1-1, WIDE2-1"
+
+[beacon.2]
+smart = true
+symbol = "/>"
+
+[smartbeaconing]
+fast_rate = 120
+
+[gps]
+type = "gpsd"
+`)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	beacons := cfg.GetBeaconConfigs()
+	if len(beacons) != 3 {
+		t.Fatalf("期望3个信标，实际为 %d", len(beacons))
+	}
+	if b := beacons[0]; b.Type != BeaconPosition || b.GetInterval() != 30*time.Minute || b.GetDelay() != time.Minute || b.Symbol != "/-" {
+		t.Errorf("信标0默认值错误: %+v", b)
+	}
+	if slot, ok, err := beacons[0].GetSlot(); err != nil || !ok || slot != 3*time.Minute+30*time.Second {
+		t.Errorf("GetSlot() = %v, %v, %v", slot, ok, err)
+	}
+	if path := beacons[0].GetPath(); len(path) != 2 || path[1] != "WIDE2-1" {
+		t.Errorf("GetPath() = %v", path)
+	}
+	if b := beacons[1]; b.Type != BeaconObject || b.Delay != 0 {
+		t.Errorf("信标1配置错误: %+v", b)
+	}
+	if b := beacons[2]; !b.Smart || !b.GPS {
+		t.Errorf("SmartBeaconing信标应使用GPS位置: %+v", b)
+	}
+	want := SmartBeaconingConfig{FastSpeed: 90, FastRate: 120, SlowSpeed: 5, SlowRate: 1800, TurnAngle: 28, TurnSlope: 410, TurnTime: 15}
+	if cfg.SmartBeaconing != want {
+		t.Errorf("SmartBeaconing配置 = %+v, want %+v", cfg.SmartBeaconing, want)
+	}
+}
+
+func TestValidateBeacons(t *testing.T) {
+	beacon := func(mutate func(b *BeaconConfig)) map[string]BeaconConfig {
+		b := BeaconConfig{Type: BeaconPosition, Callsign: "BG0ABC-10", Interval: 1800, Delay: 60, Symbol: "/-", Latitude: 39.9, Longitude: 116.4}
+		if mutate != nil {
+			mutate(&b)
+		}
+		return map[string]BeaconConfig{"0": b}
+	}
+
+	tests := []struct {
+		name    string
+		beacons map[string]BeaconConfig
+		igate   bool
+		wantErr bool
+	}{
+		{"位置信标", beacon(nil), false, false},
+		{"无效类型", beacon(func(b *BeaconConfig) { b.Type = "status" }), false, true},
+		{"间隔太短", beacon(func(b *BeaconConfig) { b.Interval = 30 }), false, true},
+		{"时隙", beacon(func(b *BeaconConfig) { b.Slot = "12:05" }), false, false},
+		{"时隙超出间隔", beacon(func(b *BeaconConfig) { b.Interval = 600; b.Slot = "10:00" }), false, true},
+		{"无效时隙", beacon(func(b *BeaconConfig) { b.Slot = "1:75" }), false, true},
+		{"通道不存在", beacon(func(b *BeaconConfig) { b.Channel = 1 }), false, true},
+		{"未设置呼号", beacon(func(b *BeaconConfig) { b.Callsign = "" }), false, true},
+		{"APRS-IS未启用iGate", beacon(func(b *BeaconConfig) { b.APRSIS = true }), false, true},
+		{"APRS-IS使用iGate呼号", beacon(func(b *BeaconConfig) { b.APRSIS, b.Callsign = true, "" }), true, false},
+		{"无效路径", beacon(func(b *BeaconConfig) { b.Path = "WIDE2-1,TOOLONGCALL" }), false, true},
+		{"无效符号", beacon(func(b *BeaconConfig) { b.Symbol = "-" }), false, true},
+		{"纬度超出范围", beacon(func(b *BeaconConfig) { b.Latitude = 91 }), false, true},
+		{"未设置位置", beacon(func(b *BeaconConfig) { b.Latitude, b.Longitude = 0, 0 }), false, true},
+		{"对象未设置位置", beacon(func(b *BeaconConfig) { b.Type, b.ObjectName, b.Latitude, b.Longitude = BeaconObject, "EVENT", 0, 0 }), false, true},
+		{"GPS位置", beacon(func(b *BeaconConfig) { b.GPS, b.Latitude, b.Longitude = true, 0, 0 }), false, false},
+		{"无效PHG", beacon(func(b *BeaconConfig) { b.Power, b.Gain = 25, 12 }), false, true},
+		{"对象名称太长", beacon(func(b *BeaconConfig) { b.Type, b.ObjectName = BeaconObject, "TOOLONGNAME" }), false, true},
+		{"raw信标", beacon(func(b *BeaconConfig) { b.Type, b.Raw = BeaconRaw, "BG0ABC>APRS,WIDE2-1:>QRV" }), false, false},
+		{"无效raw信标", beacon(func(b *BeaconConfig) { b.Type, b.Raw = BeaconRaw, ">QRV" }), false, true},
+		{"SmartBeaconing参数无效", beacon(func(b *BeaconConfig) { b.Smart, b.GPS = true, true }), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Channels: map[string]ChannelConfig{"0": {}},
+				IGate:    IGateConfig{Enabled: tt.igate, Callsign: "BG0ABC-10"},
+				Beacons:  tt.beacons,
+				GPS:      GPSConfig{Type: GPSD},
+			}
+			err := validateBeacons(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBeacons() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 未设置呼号时使用通道呼号
+	cfg := &Config{
+		Channels: map[string]ChannelConfig{"0": {Callsign: "BG0ABC-1"}},
+		Beacons:  beacon(func(b *BeaconConfig) { b.Callsign = "" }),
+	}
+	if err := validateBeacons(cfg); err != nil {
+		t.Errorf("使用通道呼号应验证通过: %v", err)
+	}
+
+	// GPS信标必须配置GPS数据源
+	cfg = &Config{
+		Channels: map[string]ChannelConfig{"0": {}},
+		Beacons:  beacon(func(b *BeaconConfig) { b.GPS = true }),
+		GPS:      GPSConfig{Type: GPSNone},
+	}
+	if err := validateBeacons(cfg); err == nil {
+		t.Error("没有GPS数据源时GPS信标应验证失败")
+	}
+
+	// SmartBeaconing
+	smart := SmartBeaconingConfig{FastSpeed: 90, FastRate: 180, SlowSpeed: 5, SlowRate: 1800, TurnAngle: 28, TurnSlope: 410, TurnTime: 15}
+	cfg = &Config{
+		Channels:       map[string]ChannelConfig{"0": {}},
+		Beacons:        beacon(func(b *BeaconConfig) { b.Smart, b.GPS = true, true }),
+		SmartBeaconing: smart,
+		GPS:            GPSConfig{Type: GPSNMEA},
+	}
+	if err := validateBeacons(cfg); err != nil {
+		t.Errorf("SmartBeaconing信标应验证通过: %v", err)
+	}
+	cfg.Beacons = beacon(func(b *BeaconConfig) { b.Smart, b.Type, b.ObjectName = true, BeaconObject, "EVENT" })
+	if err := validateBeacons(cfg); err == nil {
+		t.Error("对象信标不能使用SmartBeaconing")
+	}
+	cfg.Beacons = beacon(func(b *BeaconConfig) { b.Smart = true })
+	cfg.SmartBeaconing.SlowRate = 60
+	if err := validateBeacons(cfg); err == nil {
+		t.Error("低速发送间隔小于高速发送间隔应验证失败")
+	}
+}
+
+func TestStationAndGPS(t *testing.T) {
+	cfg, err := loadTestConfig(t, `[station]
+lat = 39.9042
+lon = 116.4074
+altitude = 50
+
+[gps]
+type = "GPSD"
+
+[channel.0]
+callsign = "BG0ABC-10"
+
+[beacon.0]
+comment = "本站位置"
+
+[beacon.1]
+lat = 40.0
+lon = 116.0
+`)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.GPS.Type != GPSD || cfg.GPS.Address != "localhost:2947" || cfg.GPS.Baud != 4800 {
+		t.Errorf("GPS配置错误: %+v", cfg.GPS)
+	}
+	if !cfg.Station.HasLocation() {
+		t.Error("应设置了本站位置")
+	}
+	beacons := cfg.GetBeaconConfigs()
+	if b := beacons[0]; b.Latitude != 39.9042 || b.Longitude != 116.4074 || b.Altitude != 50 {
+		t.Errorf("未设置位置的信标应使用本站位置: %+v", b)
+	}
+	if b := beacons[1]; b.Latitude != 40 || b.Longitude != 116 || b.Altitude != 0 {
+		t.Errorf("信标1位置错误: %+v", b)
+	}
+
+	// 信标和 [station] 都没有设置位置
+	if _, err := loadTestConfig(t, `[channel.0]
+callsign = "BG0ABC-10"
+
+[beacon.0]
+comment = "没有位置"
+`); err == nil {
+		t.Error("没有位置的信标应验证失败")
+	}
+
+	tests := []struct {
+		name    string
+		gps     GPSConfig
+		station StationConfig
+		wantErr bool
+	}{
+		{"未启用", GPSConfig{Type: GPSNone}, StationConfig{}, false},
+		{"NMEA", GPSConfig{Type: GPSNMEA, Device: "/dev/ttyACM0", Baud: 9600}, StationConfig{}, false},
+		{"NMEA未设置设备", GPSConfig{Type: GPSNMEA, Baud: 4800}, StationConfig{}, true},
+		{"NMEA无效波特率", GPSConfig{Type: GPSNMEA, Device: "/dev/ttyACM0", Baud: 1200}, StationConfig{}, true},
+		{"gpsd", GPSConfig{Type: GPSD, Address: "192.168.1.2:2947"}, StationConfig{}, false},
+		{"gpsd无效地址", GPSConfig{Type: GPSD, Address: "localhost"}, StationConfig{}, true},
+		{"无效数据源", GPSConfig{Type: "garmin"}, StationConfig{}, true},
+		{"本站位置超出范围", GPSConfig{Type: GPSNone}, StationConfig{Latitude: 39.9, Longitude: 190}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid := *cfg
+			valid.GPS, valid.Station = tt.gps, tt.station
+			err := validateConfig(&valid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Package csma 发射信道接入控制
+// 待发射的帧按优先级排队，信道空闲时按p-persistence算法决定是否发射
+package csma
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultMaxQueue 发射队列的默认最大帧数
+const DefaultMaxQueue = 64
+
+// pollInterval 等待信道空闲和输出队列有空位的轮询间隔
+const pollInterval = 10 * time.Millisecond
+
+// Priority 发射优先级，数值大的先发射，同优先级按入队顺序
+type Priority int
+
+const (
+	PriorityLow    Priority = -1 // 信标等可延后的数据
+	PriorityNormal Priority = 0  // 客户端和iGate发出的数据
+	PriorityHigh   Priority = 1  // 中继转发，需要及时发出
+)
+
+// Params 信道接入参数
+type Params struct {
+	Persistence int           // p-persistence参数 (0-255)，信道空闲时以 (P+1)/256 的概率发射
+	SlotTime    time.Duration // 不发射时等待的时隙
+	FullDuplex  bool          // 全双工，不检测信道直接发射
+}
+
+// Config 调度器配置
+type Config struct {
+	Send     func(data []byte) error // 发射一帧，如 Transmitter.SendFrame
+	DCD      func() bool             // 检测到载波时返回true
+	Busy     func() bool             // 输出队列没有空位时返回true，为nil时不等待
+	Params   func() Params           // 获取当前的信道接入参数
+	MaxQueue int                     // 队列最大帧数，为0时使用DefaultMaxQueue
+
+	// 连续发射即将超过MaxTxTime时结束本次发射，等待PTT松开后重新竞争信道，
+	// 避免触发发射超时保护；MaxTxTime为0或Keyed、Airtime为nil时不限制
+	Keyed     func() bool                     // 发射机正在发射 (PTT按下) 时返回true
+	Airtime   func(data []byte) time.Duration // 估算一帧的发射时间
+	MaxTxTime time.Duration                   // 单次连续发射的最长时间
+}
+
+// Stats 调度器统计信息
+type Stats struct {
+	Sent     uint64 // 已发射的帧数
+	Failed   uint64 // 发射失败的帧数
+	Dropped  uint64 // 队列已满而丢弃的帧数
+	Deferred uint64 // 因p-persistence或信道忙等待的时隙数
+}
+
+// Scheduler p-persistence CSMA发射调度器
+// 信道空闲并获得发射机会后连续发射队列中的帧；输出需要等待时帧留在队列中，等待结束后重新检测信道
+type Scheduler struct {
+	cfg   Config
+	rand  func() int // 返回0-255的随机数，测试时可替换
+	mu    sync.Mutex
+	queue frameQueue
+	seq   uint64
+	stats Stats
+
+	// 本次连续发射的开始时间和预计的音频播放结束时间，只在调度goroutine中访问
+	burstStart time.Time
+	burstEnd   time.Time
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建调度器
+func New(cfg Config) *Scheduler {
+	if cfg.MaxQueue <= 0 {
+		cfg.MaxQueue = DefaultMaxQueue
+	}
+	return &Scheduler{
+		cfg:  cfg,
+		rand: func() int { return rand.Intn(256) },
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Enqueue 将帧加入发射队列
+// 队列已满时丢弃优先级最低的帧，新帧的优先级不高于队列中所有帧时返回错误
+func (s *Scheduler) Enqueue(data []byte, priority Priority) error {
+	s.mu.Lock()
+	if s.queue.Len() >= s.cfg.MaxQueue {
+		lowest := s.queue.lowest()
+		if s.queue[lowest].priority >= priority {
+			s.stats.Dropped++
+			s.mu.Unlock()
+			return fmt.Errorf("发射队列已满")
+		}
+		heap.Remove(&s.queue, lowest)
+		s.stats.Dropped++
+	}
+	s.seq++
+	heap.Push(&s.queue, &queuedFrame{data: data, priority: priority, seq: s.seq})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Len 获取队列中等待发射的帧数
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queue.Len()
+}
+
+// Clear 清空发射队列，返回丢弃的帧数
+func (s *Scheduler) Clear() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.queue.Len()
+	s.queue = s.queue[:0]
+	return n
+}
+
+// GetStats 获取统计信息
+func (s *Scheduler) GetStats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Start 开始调度，ctx取消时停止
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Stop 停止调度，队列中的帧保留
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		s.wg.Wait()
+	}
+}
+
+// run 调度循环
+func (s *Scheduler) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		if s.Len() == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+
+		// 输出队列已满或占空比达到上限时，帧留在队列中等待，之后重新检测信道
+		if s.busy() {
+			if !sleep(ctx, pollInterval) {
+				return
+			}
+			continue
+		}
+
+		params := s.cfg.Params()
+		if !params.FullDuplex && !s.waitForChannel(ctx, params) {
+			return
+		}
+		if s.burst() {
+			continue
+		}
+
+		// 连续发射时间已到上限，等待PTT松开后重新检测信道
+		for s.cfg.Keyed() {
+			if !sleep(ctx, pollInterval) {
+				return
+			}
+		}
+	}
+}
+
+// burst 获得发射机会后连续发射队列中的帧，直到队列为空或输出需要等待
+// 下一帧会使连续发射超过MaxTxTime时返回false，该帧留在队列中
+func (s *Scheduler) burst() bool {
+	if s.limited() && !s.cfg.Keyed() {
+		s.burstStart = time.Time{}
+	}
+
+	for !s.busy() {
+		frame, ok := s.pop(s.fits)
+		if !ok {
+			return false
+		}
+		if frame == nil {
+			return true
+		}
+		s.addAirtime(frame.data)
+
+		if err := s.cfg.Send(frame.data); err != nil {
+			log.Printf("[CSMA] 发射失败: %v", err)
+			s.mu.Lock()
+			s.stats.Failed++
+			s.mu.Unlock()
+			continue
+		}
+		s.mu.Lock()
+		s.stats.Sent++
+		s.mu.Unlock()
+	}
+	return true
+}
+
+// limited 是否限制连续发射时间
+func (s *Scheduler) limited() bool {
+	return s.cfg.MaxTxTime > 0 && s.cfg.Keyed != nil && s.cfg.Airtime != nil
+}
+
+// fits 发射该帧后连续发射时间是否仍在上限之内，本次发射的第一帧总是允许发射
+func (s *Scheduler) fits(data []byte) bool {
+	if !s.limited() || s.burstStart.IsZero() {
+		return true
+	}
+	return s.playEnd(data).Sub(s.burstStart) <= s.cfg.MaxTxTime
+}
+
+// addAirtime 记录发射的帧，更新预计的播放结束时间
+func (s *Scheduler) addAirtime(data []byte) {
+	if !s.limited() {
+		return
+	}
+	if s.burstStart.IsZero() {
+		s.burstStart = time.Now()
+	}
+	s.burstEnd = s.playEnd(data)
+}
+
+// playEnd 该帧入队后预计的音频播放结束时间，前面的音频已播放完时从现在开始播放
+func (s *Scheduler) playEnd(data []byte) time.Time {
+	start := s.burstEnd
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	return start.Add(s.cfg.Airtime(data))
+}
+
+// busy 输出是否需要暂缓发射
+func (s *Scheduler) busy() bool {
+	return s.cfg.Busy != nil && s.cfg.Busy()
+}
+
+// waitForChannel 等待信道空闲并按p-persistence获得发射机会，ctx取消时返回false
+func (s *Scheduler) waitForChannel(ctx context.Context, params Params) bool {
+	for {
+		for s.cfg.DCD != nil && s.cfg.DCD() {
+			if !sleep(ctx, pollInterval) {
+				return false
+			}
+		}
+
+		if s.rand() <= params.Persistence {
+			return true
+		}
+
+		s.mu.Lock()
+		s.stats.Deferred++
+		s.mu.Unlock()
+		if !sleep(ctx, params.SlotTime) {
+			return false
+		}
+	}
+}
+
+// pop 取出优先级最高的帧，队列为空时返回nil
+// fits对该帧返回false时不取出，返回false
+func (s *Scheduler) pop(fits func(data []byte) bool) (*queuedFrame, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue.Len() == 0 {
+		return nil, true
+	}
+	if !fits(s.queue[0].data) {
+		return nil, false
+	}
+	return heap.Pop(&s.queue).(*queuedFrame), true
+}
+
+// sleep 等待一段时间，ctx取消时返回false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// queuedFrame 队列中的帧
+type queuedFrame struct {
+	data     []byte
+	priority Priority
+	seq      uint64 // 入队序号，同优先级先入先出
+}
+
+// frameQueue 按优先级和入队顺序排列的堆
+type frameQueue []*queuedFrame
+
+func (q frameQueue) Len() int { return len(q) }
+
+func (q frameQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q frameQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *frameQueue) Push(x any) { *q = append(*q, x.(*queuedFrame)) }
+
+func (q *frameQueue) Pop() any {
+	old := *q
+	n := len(old)
+	frame := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return frame
+}
+
+// lowest 优先级最低且最晚入队的帧的下标
+func (q frameQueue) lowest() int {
+	lowest := 0
+	for i := range q {
+		if q.Less(lowest, i) {
+			lowest = i
+		}
+	}
+	return lowest
+}
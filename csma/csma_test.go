@@ -0,0 +1,307 @@
+package csma
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeChannel 模拟信道和发射机，记录发射的帧
+type fakeChannel struct {
+	mu     sync.Mutex
+	sent   []string
+	busy   atomic.Bool // 检测到载波
+	full   atomic.Bool // 输出队列已满
+	params Params
+}
+
+func (c *fakeChannel) send(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if string(data) == "bad" {
+		return errors.New("发射机错误")
+	}
+	c.sent = append(c.sent, string(data))
+	return nil
+}
+
+func (c *fakeChannel) getSent() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+func (c *fakeChannel) getParams() Params {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.params
+}
+
+func (c *fakeChannel) scheduler(maxQueue int) *Scheduler {
+	return New(Config{
+		Send:     c.send,
+		DCD:      c.busy.Load,
+		Busy:     c.full.Load,
+		Params:   c.getParams,
+		MaxQueue: maxQueue,
+	})
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPriority(t *testing.T) {
+	ch := &fakeChannel{params: Params{Persistence: 255}}
+	s := ch.scheduler(0)
+
+	// 启动前入队，按优先级和入队顺序发射
+	s.Enqueue([]byte("beacon"), PriorityLow)
+	s.Enqueue([]byte("a"), PriorityNormal)
+	s.Enqueue([]byte("digi"), PriorityHigh)
+	s.Enqueue([]byte("b"), PriorityNormal)
+	if s.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", s.Len())
+	}
+
+	s.Start(context.Background())
+	defer s.Stop()
+	waitFor(t, func() bool { return len(ch.getSent()) == 4 })
+
+	want := []string{"digi", "a", "b", "beacon"}
+	if got := ch.getSent(); !equal(got, want) {
+		t.Errorf("发射顺序 = %v, want %v", got, want)
+	}
+	if stats := s.GetStats(); stats.Sent != 4 {
+		t.Errorf("Sent = %d, want 4", stats.Sent)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	ch := &fakeChannel{}
+	s := ch.scheduler(2)
+
+	tests := []struct {
+		name     string
+		data     string
+		priority Priority
+		wantErr  bool
+	}{
+		{"入队", "a", PriorityLow, false},
+		{"入队", "b", PriorityNormal, false},
+		{"已满且优先级不更高", "c", PriorityLow, true},
+		{"已满时替换最低优先级", "d", PriorityHigh, false},
+		{"已满且优先级相同", "e", PriorityNormal, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Enqueue([]byte(tt.data), tt.priority)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Enqueue(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+		})
+	}
+
+	if stats := s.GetStats(); stats.Dropped != 3 {
+		t.Errorf("Dropped = %d, want 3", stats.Dropped)
+	}
+	if n := s.Clear(); n != 2 || s.Len() != 0 {
+		t.Errorf("Clear() = %d, Len() = %d, want 2, 0", n, s.Len())
+	}
+}
+
+func TestCarrierDetect(t *testing.T) {
+	ch := &fakeChannel{params: Params{Persistence: 255, SlotTime: time.Millisecond}}
+	ch.busy.Store(true)
+	s := ch.scheduler(0)
+	s.Start(context.Background())
+	defer s.Stop()
+
+	// 信道忙时不发射
+	s.Enqueue([]byte("a"), PriorityNormal)
+	time.Sleep(50 * time.Millisecond)
+	if got := ch.getSent(); len(got) != 0 {
+		t.Fatalf("信道忙时发射了 %v", got)
+	}
+
+	// 信道空闲后发射
+	ch.busy.Store(false)
+	waitFor(t, func() bool { return len(ch.getSent()) == 1 })
+
+	// 全双工时忽略载波检测
+	ch.mu.Lock()
+	ch.params.FullDuplex = true
+	ch.mu.Unlock()
+	ch.busy.Store(true)
+	s.Enqueue([]byte("b"), PriorityNormal)
+	waitFor(t, func() bool { return len(ch.getSent()) == 2 })
+}
+
+func TestPersistence(t *testing.T) {
+	ch := &fakeChannel{params: Params{Persistence: 63, SlotTime: time.Millisecond}}
+	s := ch.scheduler(0)
+
+	// 随机数依次为 200, 100, 63，前两次大于P而等待时隙
+	draws := []int{200, 100, 63}
+	var n int
+	s.rand = func() int {
+		v := draws[n%len(draws)]
+		n++
+		return v
+	}
+
+	s.Enqueue([]byte("a"), PriorityNormal)
+	s.Start(context.Background())
+	defer s.Stop()
+	waitFor(t, func() bool { return len(ch.getSent()) == 1 })
+
+	if stats := s.GetStats(); stats.Deferred != 2 {
+		t.Errorf("Deferred = %d, want 2", stats.Deferred)
+	}
+}
+
+func TestOutputBusy(t *testing.T) {
+	ch := &fakeChannel{params: Params{Persistence: 255}}
+	ch.full.Store(true)
+	s := ch.scheduler(0)
+	s.Start(context.Background())
+
+	// 输出队列已满时帧留在队列中等待，而不是发射失败
+	s.Enqueue([]byte("a"), PriorityNormal)
+	time.Sleep(50 * time.Millisecond)
+	if got := ch.getSent(); len(got) != 0 {
+		t.Fatalf("输出队列已满时发射了 %v", got)
+	}
+
+	ch.full.Store(false)
+	waitFor(t, func() bool { return len(ch.getSent()) == 1 })
+
+	// 发射失败计数后继续发射下一帧
+	s.Enqueue([]byte("bad"), PriorityNormal)
+	s.Enqueue([]byte("c"), PriorityNormal)
+	waitFor(t, func() bool { return len(ch.getSent()) == 2 })
+	if stats := s.GetStats(); stats.Failed != 1 || stats.Sent != 2 {
+		t.Errorf("Failed = %d, Sent = %d, want 1, 2", stats.Failed, stats.Sent)
+	}
+
+	// 停止后入队的帧保留到下次启动
+	s.Stop()
+	s.Enqueue([]byte("d"), PriorityNormal)
+	time.Sleep(20 * time.Millisecond)
+	if s.Len() != 1 {
+		t.Errorf("停止后Len() = %d, want 1", s.Len())
+	}
+	s.Start(context.Background())
+	defer s.Stop()
+	waitFor(t, func() bool { return len(ch.getSent()) == 3 })
+}
+
+func TestHoldRechecksChannel(t *testing.T) {
+	ch := &fakeChannel{params: Params{Persistence: 255, SlotTime: time.Millisecond}}
+	ch.full.Store(true)
+	s := ch.scheduler(0)
+	s.Start(context.Background())
+
+	// 等待输出期间帧不出队，停止时保留在队列中
+	s.Enqueue([]byte("a"), PriorityNormal)
+	time.Sleep(30 * time.Millisecond)
+	s.Stop()
+	if s.Len() != 1 {
+		t.Fatalf("等待输出时停止，Len() = %d, want 1", s.Len())
+	}
+
+	// 等待结束后重新检测信道，信道忙时不发射
+	s.Start(context.Background())
+	defer s.Stop()
+	ch.busy.Store(true)
+	ch.full.Store(false)
+	time.Sleep(30 * time.Millisecond)
+	if got := ch.getSent(); len(got) != 0 {
+		t.Fatalf("信道忙时发射了 %v", got)
+	}
+
+	ch.busy.Store(false)
+	waitFor(t, func() bool { return len(ch.getSent()) == 1 })
+}
+
+// fakeTransmitter 模拟按帧播放音频的发射机，音频播放完毕时松开PTT
+type fakeTransmitter struct {
+	mu       sync.Mutex
+	airtime  time.Duration // 每帧的发射时间
+	keyedAt  time.Time
+	end      time.Time     // 音频播放结束时间
+	longest  time.Duration // 最长的连续发射时间
+	sent     int
+	keyCount int
+}
+
+func (tx *fakeTransmitter) send(data []byte) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	now := time.Now()
+	if !now.Before(tx.end) {
+		tx.keyedAt, tx.end = now, now
+		tx.keyCount++
+	}
+	tx.end = tx.end.Add(tx.airtime)
+	tx.longest = max(tx.longest, tx.end.Sub(tx.keyedAt))
+	tx.sent++
+	return nil
+}
+
+func (tx *fakeTransmitter) keyed() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return time.Now().Before(tx.end)
+}
+
+func TestMaxTxTime(t *testing.T) {
+	tx := &fakeTransmitter{airtime: 30 * time.Millisecond}
+	s := New(Config{
+		Send:      tx.send,
+		Params:    func() Params { return Params{Persistence: 255} },
+		Keyed:     tx.keyed,
+		Airtime:   func([]byte) time.Duration { return tx.airtime },
+		MaxTxTime: 100 * time.Millisecond,
+	})
+
+	// 10帧共300ms，超过最长发射时间，分为多次发射，每次最多3帧
+	for i := 0; i < 10; i++ {
+		s.Enqueue([]byte{byte(i)}, PriorityNormal)
+	}
+	s.Start(context.Background())
+	defer s.Stop()
+	waitFor(t, func() bool { return s.GetStats().Sent == 10 })
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.longest > 100*time.Millisecond {
+		t.Errorf("最长连续发射 %v，超过上限", tx.longest)
+	}
+	if tx.keyCount < 4 {
+		t.Errorf("发射次数 = %d, want >= 4", tx.keyCount)
+	}
+}
@@ -0,0 +1,338 @@
+package digi
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aprs_agent/ax25"
+	"aprs_agent/csma"
+	"aprs_agent/modem"
+)
+
+// DefaultDedupeWindow 默认的重复包检测时间窗口
+const DefaultDedupeWindow = 30 * time.Second
+
+// DefaultMaxHops WIDEn-N中n的默认最大值
+const DefaultMaxHops = 2
+
+// nowFunc 获取当前时间，测试时可替换
+var nowFunc = time.Now
+
+// Preempt 抢先中继模式：路径中本站呼号或别名之前还有未使用的中继地址时的处理方式
+type Preempt int
+
+const (
+	PreemptOff   Preempt = iota // 不抢先中继
+	PreemptDrop                 // 删除本站之前的所有中继地址
+	PreemptMark                 // 将本站之前未使用的中继地址标记为已转发
+	PreemptTrace                // 删除本站之前未使用的中继地址，保留已转发的
+)
+
+// String 返回模式名称
+func (p Preempt) String() string {
+	switch p {
+	case PreemptDrop:
+		return "drop"
+	case PreemptMark:
+		return "mark"
+	case PreemptTrace:
+		return "trace"
+	default:
+		return "off"
+	}
+}
+
+// ParsePreempt 解析抢先中继模式名称
+func ParsePreempt(s string) (Preempt, error) {
+	switch strings.ToLower(s) {
+	case "", "off":
+		return PreemptOff, nil
+	case "drop":
+		return PreemptDrop, nil
+	case "mark":
+		return PreemptMark, nil
+	case "trace":
+		return PreemptTrace, nil
+	default:
+		return PreemptOff, fmt.Errorf("无效的抢先中继模式: %s", s)
+	}
+}
+
+// Modem 中继器使用的调制解调器接口，由audio.Manager实现
+type Modem interface {
+	AddFrameHandler(handler func(modem.Frame))
+	Transmit(channel int, data []byte) error
+}
+
+// priorityModem 支持发射优先级的调制解调器，中继的帧优先于队列中的其他帧发射
+type priorityModem interface {
+	TransmitPriority(channel int, data []byte, priority csma.Priority) error
+}
+
+// Config 中继器配置
+type Config struct {
+	MyCall       ax25.Address  // 本站呼号，转发时替换别名并记录在路径中
+	Aliases      []string      // 别名，如 RELAY，不含WIDEn-N
+	FillIn       bool          // 补点中继，只处理WIDE1-1
+	MaxHops      int           // WIDEn-N中n和N的最大值，超过的数据包不转发
+	DedupeWindow time.Duration // 重复包检测时间窗口
+	Preempt      Preempt       // 抢先中继模式
+	Routes       []Route       // 路由规则，为空时在接收通道上转发
+}
+
+// Stats 中继器统计信息
+type Stats struct {
+	Digipeated uint64 // 已转发的帧数
+	Duplicates uint64 // 重复而未转发的帧数
+}
+
+// Digipeater APRS中继器，按New-N规则转发接收到的UI帧
+// 配置了路由规则时，一个帧可以按多条规则转发到不同的通道
+type Digipeater struct {
+	modem  Modem
+	cfg    Config
+	mu     sync.Mutex
+	recent map[string]time.Time // 最近转发的数据包，用于去重
+	stats  Stats
+}
+
+// New 创建中继器，注册帧处理函数
+func New(m Modem, cfg Config) *Digipeater {
+	if cfg.MaxHops <= 0 {
+		cfg.MaxHops = DefaultMaxHops
+	}
+	if cfg.DedupeWindow <= 0 {
+		cfg.DedupeWindow = DefaultDedupeWindow
+	}
+	aliases := make([]string, len(cfg.Aliases))
+	for i, alias := range cfg.Aliases {
+		aliases[i] = strings.ToUpper(alias)
+	}
+	cfg.Aliases = aliases
+
+	d := &Digipeater{
+		modem:  m,
+		cfg:    cfg,
+		recent: make(map[string]time.Time),
+	}
+	m.AddFrameHandler(d.onFrame)
+	return d
+}
+
+// GetStats 获取统计信息
+func (d *Digipeater) GetStats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// onFrame 处理接收到的帧，按路由规则在对应通道上转发
+func (d *Digipeater) onFrame(f modem.Frame) {
+	frame, err := ax25.Decode(f.Data)
+	if err != nil || !frame.IsUI() {
+		return
+	}
+
+	out := d.Process(frame)
+	if out == nil {
+		return
+	}
+
+	var targets []int
+	if len(d.cfg.Routes) == 0 {
+		targets = []int{f.Channel}
+	}
+	for _, route := range d.cfg.Routes {
+		if route.From == f.Channel && route.Match(frame) {
+			targets = append(targets, route.To)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	data, err := out.Encode()
+	if err != nil {
+		log.Printf("[中继] 编码帧失败: %v", err)
+		return
+	}
+
+	now := nowFunc()
+	for _, channel := range targets {
+		d.mu.Lock()
+		if d.isDuplicate(channel, frame, now) {
+			d.stats.Duplicates++
+			d.mu.Unlock()
+			continue
+		}
+		d.mu.Unlock()
+
+		if err := d.transmit(channel, data); err != nil {
+			log.Printf("[中继] 通道 %d 发送失败: %v", channel, err)
+			continue
+		}
+
+		d.mu.Lock()
+		d.stats.Digipeated++
+		d.mu.Unlock()
+		if channel == f.Channel {
+			log.Printf("[中继] 通道 %d: %s", channel, out)
+		} else {
+			log.Printf("[中继] 通道 %d -> 通道 %d: %s", f.Channel, channel, out)
+		}
+	}
+}
+
+// transmit 发射中继的帧，调制解调器支持时使用高优先级
+func (d *Digipeater) transmit(channel int, data []byte) error {
+	if m, ok := d.modem.(priorityModem); ok {
+		return m.TransmitPriority(channel, data, csma.PriorityHigh)
+	}
+	return d.modem.Transmit(channel, data)
+}
+
+// isDuplicate 检查数据包是否在时间窗口内在该通道上转发过，并记录本次转发，调用时需持有d.mu
+// 以源地址、目的地址和信息字段判断，忽略路径
+func (d *Digipeater) isDuplicate(channel int, frame *ax25.Frame, now time.Time) bool {
+	for key, at := range d.recent {
+		if now.Sub(at) > d.cfg.DedupeWindow {
+			delete(d.recent, key)
+		}
+	}
+
+	key := fmt.Sprintf("%d|%s>%s:%s", channel, frame.Src, frame.Dest, frame.Info)
+	if _, ok := d.recent[key]; ok {
+		return true
+	}
+	d.recent[key] = now
+	return false
+}
+
+// Process 按中继规则处理帧，需要转发时返回修改路径后的新帧，否则返回nil
+// 不检查重复
+func (d *Digipeater) Process(frame *ax25.Frame) *ax25.Frame {
+	// 不转发本站发出的帧
+	if frame.Src.Equal(d.cfg.MyCall) {
+		return nil
+	}
+
+	// 第一个未使用的中继地址
+	next := -1
+	for i, digi := range frame.Path {
+		if !digi.H {
+			next = i
+			break
+		}
+	}
+	if next < 0 {
+		return nil
+	}
+
+	// 本站呼号或别名
+	if d.isMine(frame.Path[next]) {
+		return d.repeat(frame, next, next)
+	}
+
+	// 抢先中继：后面未使用的地址中有本站呼号或别名
+	if d.cfg.Preempt != PreemptOff {
+		for j := next + 1; j < len(frame.Path); j++ {
+			if d.isMine(frame.Path[j]) {
+				return d.repeat(frame, next, j)
+			}
+		}
+	}
+
+	// WIDEn-N
+	n, ok := parseWide(frame.Path[next].Call)
+	if !ok {
+		return nil
+	}
+	hops := frame.Path[next].SSID
+	if hops < 1 || hops > n || n > d.cfg.MaxHops {
+		return nil
+	}
+	if d.cfg.FillIn && (n != 1 || hops != 1) {
+		return nil
+	}
+
+	out := copyFrame(frame)
+	myCall := d.cfg.MyCall
+	myCall.H = true
+	if hops == 1 {
+		// WIDEn-1 → 本站*
+		out.Path[next] = myCall
+		return out
+	}
+
+	// WIDEn-N → 本站*,WIDEn-(N-1)，路径已满时只递减
+	out.Path[next].SSID--
+	if len(out.Path) < ax25.MaxDigis {
+		path := make([]ax25.Address, 0, len(out.Path)+1)
+		path = append(path, out.Path[:next]...)
+		path = append(path, myCall)
+		path = append(path, out.Path[next:]...)
+		out.Path = path
+	}
+	return out
+}
+
+// repeat 用本站呼号替换位置match的地址并标记为已转发
+// match在first之后时为抢先中继，按模式处理两者之间的地址
+func (d *Digipeater) repeat(frame *ax25.Frame, first, match int) *ax25.Frame {
+	out := copyFrame(frame)
+	myCall := d.cfg.MyCall
+	myCall.H = true
+	out.Path[match] = myCall
+
+	if match == first {
+		return out
+	}
+
+	switch d.cfg.Preempt {
+	case PreemptDrop:
+		out.Path = out.Path[match:]
+	case PreemptMark:
+		for i := first; i < match; i++ {
+			out.Path[i].H = true
+		}
+	case PreemptTrace:
+		out.Path = append(out.Path[:first], out.Path[match:]...)
+	}
+	return out
+}
+
+// isMine 地址是否为本站呼号或别名
+func (d *Digipeater) isMine(addr ax25.Address) bool {
+	if addr.Equal(d.cfg.MyCall) {
+		return true
+	}
+	for _, alias := range d.cfg.Aliases {
+		if addr.String() == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWide 解析 "WIDEn"，返回n (1-7)
+func parseWide(call string) (int, bool) {
+	if len(call) != 5 || !strings.HasPrefix(call, "WIDE") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(call[4:])
+	if err != nil || n < 1 || n > 7 {
+		return 0, false
+	}
+	return n, true
+}
+
+// copyFrame 复制帧，路径使用新的切片
+func copyFrame(frame *ax25.Frame) *ax25.Frame {
+	out := *frame
+	out.Path = append([]ax25.Address(nil), frame.Path...)
+	return &out
+}
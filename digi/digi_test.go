@@ -0,0 +1,234 @@
+package digi
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"aprs_agent/ax25"
+	"aprs_agent/modem"
+)
+
+// sentFrame 记录发送的帧
+type sentFrame struct {
+	channel int
+	tnc2    string
+}
+
+// fakeModem 记录帧处理函数和发送的帧
+type fakeModem struct {
+	mu       sync.Mutex
+	handlers []func(modem.Frame)
+	sent     []sentFrame
+}
+
+func (m *fakeModem) AddFrameHandler(handler func(modem.Frame)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+func (m *fakeModem) Transmit(channel int, data []byte) error {
+	frame, err := ax25.Decode(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentFrame{channel: channel, tnc2: frame.String()})
+	return nil
+}
+
+func (m *fakeModem) receive(channel int, tnc2 string) {
+	frame, err := ax25.ParseTNC2(tnc2)
+	if err != nil {
+		panic(err)
+	}
+	data, err := frame.Encode()
+	if err != nil {
+		panic(err)
+	}
+
+	m.mu.Lock()
+	handlers := m.handlers
+	m.mu.Unlock()
+	for _, h := range handlers {
+		h(modem.Frame{Channel: channel, Data: data})
+	}
+}
+
+// takeSent 取出已发送的帧
+func (m *fakeModem) takeSent() []sentFrame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := m.sent
+	m.sent = nil
+	return sent
+}
+
+func TestProcess(t *testing.T) {
+	base := Config{
+		MyCall:  ax25.MustParseAddress("BG0DIG-1"),
+		Aliases: []string{"relay"},
+		MaxHops: 2,
+	}
+	fillIn := base
+	fillIn.FillIn = true
+	drop := base
+	drop.Preempt = PreemptDrop
+	mark := base
+	mark.Preempt = PreemptMark
+	trace := base
+	trace.Preempt = PreemptTrace
+
+	tests := []struct {
+		name string
+		cfg  Config
+		in   string
+		want string // 为空表示不转发
+	}{
+		{"WIDE1-1", base, "BG0ABC>APRS,WIDE1-1:>x", "BG0ABC>APRS,BG0DIG-1*:>x"},
+		{"WIDE2-2", base, "BG0ABC>APRS,WIDE2-2:>x", "BG0ABC>APRS,BG0DIG-1*,WIDE2-1:>x"},
+		{"WIDE2-1", base, "BG0ABC>APRS,BG0XYZ*,WIDE2-1:>x", "BG0ABC>APRS,BG0XYZ,BG0DIG-1*:>x"},
+		{"第二跳", base, "BG0ABC>APRS,WIDE1-1,WIDE2-1:>x", "BG0ABC>APRS,BG0DIG-1*,WIDE2-1:>x"},
+		{"已用完", base, "BG0ABC>APRS,BG0XYZ,WIDE2*:>x", ""},
+		{"无路径", base, "BG0ABC>APRS:>x", ""},
+		{"超过最大跳数", base, "BG0ABC>APRS,WIDE3-3:>x", ""},
+		{"N大于n", base, "BG0ABC>APRS,WIDE1-2:>x", ""},
+		{"WIDE2-0未标记", base, "BG0ABC>APRS,WIDE2:>x", ""},
+		{"本站呼号", base, "BG0ABC>APRS,BG0DIG-1,WIDE2-1:>x", "BG0ABC>APRS,BG0DIG-1*,WIDE2-1:>x"},
+		{"别名", base, "BG0ABC>APRS,RELAY:>x", "BG0ABC>APRS,BG0DIG-1*:>x"},
+		{"本站发出", base, "BG0DIG-1>APRS,WIDE1-1:>x", ""},
+		{"其他呼号", base, "BG0ABC>APRS,BG0XYZ:>x", ""},
+		{"路径已满只递减", base, "BG0ABC>APRS,A1,A2,A3,A4,A5,A6,A7*,WIDE2-2:>x", "BG0ABC>APRS,A1,A2,A3,A4,A5,A6,A7*,WIDE2-1:>x"},
+		{"补点中继WIDE1-1", fillIn, "BG0ABC>APRS,WIDE1-1,WIDE2-1:>x", "BG0ABC>APRS,BG0DIG-1*,WIDE2-1:>x"},
+		{"补点中继不处理WIDE2", fillIn, "BG0ABC>APRS,WIDE2-2:>x", ""},
+		{"补点中继仍处理别名", fillIn, "BG0ABC>APRS,RELAY:>x", "BG0ABC>APRS,BG0DIG-1*:>x"},
+		{"不抢先", base, "BG0ABC>APRS,BG0XYZ,BG0DIG-1:>x", ""},
+		{"抢先drop", drop, "BG0ABC>APRS,BG0USE*,BG0XYZ,BG0DIG-1,WIDE2-1:>x", "BG0ABC>APRS,BG0DIG-1*,WIDE2-1:>x"},
+		{"抢先mark", mark, "BG0ABC>APRS,BG0USE*,BG0XYZ,BG0DIG-1,WIDE2-1:>x", "BG0ABC>APRS,BG0USE,BG0XYZ,BG0DIG-1*,WIDE2-1:>x"},
+		{"抢先trace", trace, "BG0ABC>APRS,BG0USE*,BG0XYZ,BG0DIG-1,WIDE2-1:>x", "BG0ABC>APRS,BG0USE,BG0DIG-1*,WIDE2-1:>x"},
+		{"抢先别名", trace, "BG0ABC>APRS,BG0XYZ,RELAY:>x", "BG0ABC>APRS,BG0DIG-1*:>x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(&fakeModem{}, tt.cfg)
+			frame, err := ax25.ParseTNC2(tt.in)
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+
+			before := frame.String()
+			out := d.Process(frame)
+			if tt.want == "" {
+				if out != nil {
+					t.Errorf("不应转发，得到 %s", out)
+				}
+				return
+			}
+			if out == nil {
+				t.Fatalf("应转发为 %s", tt.want)
+			}
+			if out.String() != tt.want {
+				t.Errorf("得到 %s, want %s", out, tt.want)
+			}
+			if frame.String() != before {
+				t.Errorf("原帧被修改: %s", frame)
+			}
+		})
+	}
+}
+
+func TestDigipeater(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	m := &fakeModem{}
+	d := New(m, Config{MyCall: ax25.MustParseAddress("BG0DIG")})
+
+	// 在接收通道上转发
+	m.receive(1, "BG0ABC>APRS,WIDE1-1,WIDE2-1:>x")
+	sent := m.takeSent()
+	if len(sent) != 1 || sent[0].channel != 1 || sent[0].tnc2 != "BG0ABC>APRS,BG0DIG*,WIDE2-1:>x" {
+		t.Fatalf("转发错误: %+v", sent)
+	}
+
+	// 30秒内经其他中继转发的同一数据包不再转发
+	now = now.Add(10 * time.Second)
+	m.receive(1, "BG0ABC>APRS,BG0XYZ*,WIDE2-1:>x")
+	if sent := m.takeSent(); len(sent) != 0 {
+		t.Errorf("重复的数据包不应转发: %+v", sent)
+	}
+
+	// 超过时间窗口后再次转发
+	now = now.Add(DefaultDedupeWindow)
+	m.receive(1, "BG0ABC>APRS,BG0XYZ*,WIDE2-1:>x")
+	if sent := m.takeSent(); len(sent) != 1 || sent[0].tnc2 != "BG0ABC>APRS,BG0XYZ,BG0DIG*:>x" {
+		t.Errorf("转发错误: %+v", sent)
+	}
+
+	if stats := d.GetStats(); stats.Digipeated != 2 || stats.Duplicates != 1 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+}
+
+func TestParsePreempt(t *testing.T) {
+	for _, p := range []Preempt{PreemptOff, PreemptDrop, PreemptMark, PreemptTrace} {
+		got, err := ParsePreempt(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParsePreempt(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+	if _, err := ParsePreempt("bad"); err == nil {
+		t.Error("期望解析错误")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	toBand, err := NewRoute(0, 1, "wide2", "")
+	if err != nil {
+		t.Fatalf("创建规则失败: %v", err)
+	}
+	local, _ := NewRoute(1, 1, "", "")
+	sameBand, _ := NewRoute(0, 0, "", "^BG")
+	if _, err := NewRoute(0, 1, "", "("); err == nil {
+		t.Error("期望正则表达式错误")
+	}
+
+	m := &fakeModem{}
+	New(m, Config{
+		MyCall: ax25.MustParseAddress("BG0DIG"),
+		Routes: []Route{toBand, local, sameBand},
+	})
+
+	tests := []struct {
+		channel int
+		in      string
+		want    []sentFrame
+	}{
+		{0, "BG0ABC>APRS,WIDE2-2:>a", []sentFrame{
+			{1, "BG0ABC>APRS,BG0DIG*,WIDE2-1:>a"},
+			{0, "BG0ABC>APRS,BG0DIG*,WIDE2-1:>a"},
+		}},
+		{0, "N0ABC>APRS,WIDE1-1:>b", nil},
+		{1, "N0ABC>APRS,WIDE1-1:>c", []sentFrame{{1, "N0ABC>APRS,BG0DIG*:>c"}}},
+		{0, "N0ABC>APRS,WIDE1-1,WIDE2-1:>d", []sentFrame{{1, "N0ABC>APRS,BG0DIG*,WIDE2-1:>d"}}},
+		// 同一数据包已在通道1上转发过
+		{1, "BG0ABC>APRS,BG0XYZ*,WIDE2-1:>a", nil},
+	}
+	for _, tt := range tests {
+		m.receive(tt.channel, tt.in)
+		sent := m.takeSent()
+		if len(sent) != len(tt.want) {
+			t.Errorf("%s: 发送 %+v, want %+v", tt.in, sent, tt.want)
+			continue
+		}
+		for i := range sent {
+			if sent[i] != tt.want[i] {
+				t.Errorf("%s: 帧 %d = %+v, want %+v", tt.in, i, sent[i], tt.want[i])
+			}
+		}
+	}
+}
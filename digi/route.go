@@ -0,0 +1,62 @@
+package digi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"aprs_agent/ax25"
+)
+
+// Route 中继路由规则：从From通道收到并满足条件的帧经To通道转发
+type Route struct {
+	From         int
+	To           int
+	PathContains string         // 非空时只转发路径中有地址包含该文本的帧，如 WIDE2
+	Source       *regexp.Regexp // 非nil时只转发源呼号匹配的帧
+}
+
+// NewRoute 创建路由规则，sourceRegex为空表示不限制源呼号
+func NewRoute(from, to int, pathContains, sourceRegex string) (Route, error) {
+	route := Route{
+		From:         from,
+		To:           to,
+		PathContains: strings.ToUpper(pathContains),
+	}
+	if sourceRegex != "" {
+		re, err := regexp.Compile(sourceRegex)
+		if err != nil {
+			return Route{}, fmt.Errorf("源呼号正则表达式无效: %w", err)
+		}
+		route.Source = re
+	}
+	return route, nil
+}
+
+// Match 检查帧是否满足路由条件，不检查通道
+func (r Route) Match(frame *ax25.Frame) bool {
+	if r.Source != nil && !r.Source.MatchString(frame.Src.String()) {
+		return false
+	}
+	if r.PathContains == "" {
+		return true
+	}
+	for _, digi := range frame.Path {
+		if strings.Contains(digi.String(), r.PathContains) {
+			return true
+		}
+	}
+	return false
+}
+
+// String 返回规则描述
+func (r Route) String() string {
+	s := fmt.Sprintf("通道 %d -> 通道 %d", r.From, r.To)
+	if r.PathContains != "" {
+		s += fmt.Sprintf(" 路径包含 %s", r.PathContains)
+	}
+	if r.Source != nil {
+		s += fmt.Sprintf(" 源呼号匹配 %s", r.Source)
+	}
+	return s
+}
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/gen2brain/malgo v0.11.10
 	github.com/spf13/viper v1.17.0
+	golang.org/x/sys v0.13.0
 )
 
 require (
@@ -23,7 +24,6 @@ require (
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
-	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -0,0 +1,208 @@
+// Package gps 从串口GPS或gpsd获取定位
+// 串口GPS按NMEA 0183 (GGA、RMC、VTG) 解码，gpsd使用TCP JSON WATCH协议；
+// 定位结果发布给订阅者，断开后按指数退避自动重连
+package gps
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 重连和定位有效期参数
+const (
+	DefaultMinBackoff = 2 * time.Second
+	DefaultMaxBackoff = time.Minute
+	readTimeout       = 30 * time.Second // 超过该时间没有数据视为连接失效
+)
+
+// fixTimeout 超过该时间没有新的定位时视为失去定位，测试时可替换
+var fixTimeout = 10 * time.Second
+
+// Fix 定位结果
+type Fix struct {
+	Time      time.Time // GPS时间 (UTC)
+	Valid     bool      // 是否已定位
+	Latitude  float64   // 纬度，北纬为正
+	Longitude float64   // 经度，东经为正
+
+	HasAltitude bool
+	Altitude    float64 // 海拔 (米)
+
+	HasCourseSpeed bool
+	Course         float64 // 航向 (度，0-360，正北为0)
+	Speed          float64 // 速度 (km/h)
+
+	Satellites int // 使用的卫星数，未知时为0
+}
+
+// decoder 将一行数据解码为定位结果，update为false时该行不包含新的定位
+type decoder interface {
+	decode(line string) (fix Fix, update bool, err error)
+}
+
+// Receiver GPS接收器
+type Receiver struct {
+	name   string                                                // 日志中显示的数据源
+	open   func(ctx context.Context) (io.ReadWriteCloser, error) // 打开数据源
+	newDec func() decoder                                        // 每次连接使用新的解码器
+
+	mu       sync.Mutex
+	handlers []func(Fix)
+	fix      Fix
+	updated  time.Time // 最近一次收到有效定位的时间
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// AddFixHandler 添加定位处理函数，每次收到有效定位时调用
+func (r *Receiver) AddFixHandler(handler func(Fix)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// Fix 获取最近的有效定位，没有定位或定位已过期时返回false
+func (r *Receiver) Fix() (Fix, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.fix.Valid || time.Since(r.updated) > fixTimeout {
+		return Fix{}, false
+	}
+	return r.fix, true
+}
+
+// Start 开始读取定位，ctx取消时自动停止
+func (r *Receiver) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		return fmt.Errorf("GPS接收器已在运行")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go r.run(ctx)
+	return nil
+}
+
+// Stop 停止读取定位
+func (r *Receiver) Stop() {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.cancel = nil
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		r.wg.Wait()
+	}
+}
+
+// run 连接循环
+func (r *Receiver) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	backoff := DefaultMinBackoff
+	for {
+		received, err := r.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if received {
+			backoff = DefaultMinBackoff
+		}
+		log.Printf("[GPS] %s 读取失败: %v，%v后重试", r.name, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, DefaultMaxBackoff)
+	}
+}
+
+// session 打开一次数据源并读取直到出错，返回是否收到过数据
+func (r *Receiver) session(ctx context.Context) (bool, error) {
+	conn, err := r.open(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer conn.Close()
+
+	log.Printf("[GPS] 已连接: %s", r.name)
+
+	dec := r.newDec()
+	received := false
+	reader := bufio.NewReader(conn)
+	for {
+		if d, ok := conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+			d.SetReadDeadline(time.Now().Add(readTimeout))
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return received, err
+		}
+		received = true
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fix, update, err := dec.decode(line)
+		if err != nil {
+			log.Printf("[GPS] %v", err)
+			continue
+		}
+		if update {
+			r.publish(fix)
+		}
+	}
+}
+
+// publish 保存定位并通知订阅者，定位状态变化时记录日志
+func (r *Receiver) publish(fix Fix) {
+	r.mu.Lock()
+	wasValid := r.fix.Valid && time.Since(r.updated) <= fixTimeout
+	r.fix = fix
+	if fix.Valid {
+		r.updated = time.Now()
+	}
+	handlers := make([]func(Fix), len(r.handlers))
+	copy(handlers, r.handlers)
+	r.mu.Unlock()
+
+	if fix.Valid != wasValid {
+		if fix.Valid {
+			log.Printf("[GPS] 已定位: %.5f,%.5f", fix.Latitude, fix.Longitude)
+		} else {
+			log.Printf("[GPS] 失去定位")
+		}
+	}
+	if !fix.Valid {
+		return
+	}
+	for _, handler := range handlers {
+		handler(fix)
+	}
+}
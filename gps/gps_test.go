@@ -0,0 +1,225 @@
+package gps
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentence 为NMEA语句添加$和校验和
+func sentence(body string) string {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestSplitNMEA(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr bool
+	}{
+		{"标准示例", "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A", false},
+		{"GGA", "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", false},
+		{"没有校验和", "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K", false},
+		{"校验和错误", "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6B", true},
+		{"不是NMEA", "GPRMC,123519", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := splitNMEA(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitNMEA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNMEADecoder(t *testing.T) {
+	d := &nmeaDecoder{}
+
+	// 未定位
+	fix, update, err := d.decode(sentence("GPRMC,235947,V,,,,,,,091026,,"))
+	if err != nil || !update || fix.Valid {
+		t.Fatalf("未定位的RMC: %+v, %v, %v", fix, update, err)
+	}
+
+	// RMC提供日期、速度和航向
+	fix, update, err = d.decode(sentence("GNRMC,123519.50,A,4807.038,N,01131.000,E,022.4,084.4,091026,003.1,W"))
+	if err != nil || !update || !fix.Valid {
+		t.Fatalf("RMC解码失败: %+v, %v, %v", fix, update, err)
+	}
+	if !near(fix.Latitude, 48.1173) || !near(fix.Longitude, 11.516666666) {
+		t.Errorf("位置 = %v,%v", fix.Latitude, fix.Longitude)
+	}
+	if !fix.HasCourseSpeed || fix.Course != 84.4 || !near(fix.Speed, 22.4*1.852) {
+		t.Errorf("航向速度 = %v %v %v", fix.HasCourseSpeed, fix.Course, fix.Speed)
+	}
+	if want := time.Date(2026, 10, 9, 12, 35, 19, 500e6, time.UTC); !fix.Time.Equal(want) {
+		t.Errorf("时间 = %v, want %v", fix.Time, want)
+	}
+	if fix.HasAltitude {
+		t.Error("RMC不应有海拔")
+	}
+
+	// GGA提供海拔和卫星数，日期沿用RMC
+	fix, update, err = d.decode(sentence("GPGGA,123520,3330.000,S,07030.000,W,1,08,0.9,545.4,M,46.9,M,,"))
+	if err != nil || !update || !fix.Valid {
+		t.Fatalf("GGA解码失败: %+v, %v, %v", fix, update, err)
+	}
+	if !near(fix.Latitude, -33.5) || !near(fix.Longitude, -70.5) || !fix.HasAltitude || fix.Altitude != 545.4 || fix.Satellites != 8 {
+		t.Errorf("GGA定位 = %+v", fix)
+	}
+	if want := time.Date(2026, 10, 9, 12, 35, 20, 0, time.UTC); !fix.Time.Equal(want) {
+		t.Errorf("时间 = %v, want %v", fix.Time, want)
+	}
+
+	// VTG只更新航向和速度
+	_, update, err = d.decode(sentence("GPVTG,054.7,T,034.4,M,005.5,N,010.2,K,A"))
+	if err != nil || update {
+		t.Fatalf("VTG不应发布定位: %v, %v", update, err)
+	}
+	fix, _, _ = d.decode(sentence("GPGGA,123521,3330.000,S,07030.000,W,1,08,0.9,545.4,M,46.9,M,,"))
+	if fix.Course != 54.7 || fix.Speed != 10.2 {
+		t.Errorf("VTG航向速度 = %v %v", fix.Course, fix.Speed)
+	}
+
+	// 静止时RMC不输出航向，不报告航向和速度
+	fix, _, _ = d.decode(sentence("GNRMC,123522,A,4807.038,N,01131.000,E,000.0,,091026,003.1,W"))
+	if fix.HasCourseSpeed {
+		t.Errorf("没有航向时不应报告航向速度: %v %v", fix.Course, fix.Speed)
+	}
+
+	// GGA定位质量为0表示失去定位
+	fix, update, _ = d.decode(sentence("GPGGA,123522,,,,,0,00,,,M,,M,,"))
+	if !update || fix.Valid {
+		t.Errorf("定位质量为0时应失去定位: %+v", fix)
+	}
+
+	// 不支持的语句和无效语句
+	if _, update, err := d.decode(sentence("GPGSV,3,1,11,03,03,111,00")); update || err != nil {
+		t.Errorf("GSV应被忽略: %v, %v", update, err)
+	}
+	if _, _, err := d.decode(sentence("GPRMC,123519,A,4807.038,X,01131.000,E,022.4,084.4,091026,,")); err == nil {
+		t.Error("无效的纬度方向应解码失败")
+	}
+}
+
+func TestGPSDDecoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		update bool
+		want   Fix
+	}{
+		{"版本", `{"class":"VERSION","release":"3.25","proto_major":3,"proto_minor":15}`, false, Fix{}},
+		{"未定位", `{"class":"TPV","device":"/dev/ttyACM0","mode":1,"time":"2026-10-09T12:35:19.000Z"}`, true,
+			Fix{Time: time.Date(2026, 10, 9, 12, 35, 19, 0, time.UTC)}},
+		{"二维定位", `{"class":"TPV","mode":2,"time":"2026-10-09T12:35:19.000Z","lat":39.9042,"lon":116.4074,"alt":50,"track":90.5,"speed":10}`, true,
+			Fix{Time: time.Date(2026, 10, 9, 12, 35, 19, 0, time.UTC), Valid: true, Latitude: 39.9042, Longitude: 116.4074,
+				HasCourseSpeed: true, Course: 90.5, Speed: 36}},
+		{"三维定位", `{"class":"TPV","mode":3,"time":"2026-10-09T12:35:20.000Z","lat":-33.5,"lon":-70.5,"alt":590.1,"altMSL":545.4,"speed":0}`, true,
+			Fix{Time: time.Date(2026, 10, 9, 12, 35, 20, 0, time.UTC), Valid: true, Latitude: -33.5, Longitude: -70.5,
+				HasAltitude: true, Altitude: 545.4}},
+		{"有航向没有速度", `{"class":"TPV","mode":2,"lat":39.9042,"lon":116.4074,"track":90.5}`, true,
+			Fix{Valid: true, Latitude: 39.9042, Longitude: 116.4074}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fix, update, err := gpsdDecoder{}.decode(tt.line)
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			if update != tt.update || fix != tt.want {
+				t.Errorf("decode() = %+v, %v, want %+v, %v", fix, update, tt.want, tt.update)
+			}
+		})
+	}
+
+	if _, _, err := (gpsdDecoder{}).decode("{"); err == nil {
+		t.Error("无效的JSON应解码失败")
+	}
+}
+
+// fakeGPSD 模拟gpsd：收到WATCH命令后发送版本和报告
+func fakeGPSD(t *testing.T, reports []string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(conn, "%s\n", `{"class":"VERSION","release":"3.25","proto_major":3,"proto_minor":15}`)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || !strings.HasPrefix(line, `?WATCH={"enable":true`) {
+			t.Errorf("WATCH命令 = %q, %v", line, err)
+			return
+		}
+		for _, report := range reports {
+			fmt.Fprintf(conn, "%s\n", report)
+		}
+		// 保持连接直到客户端断开
+		conn.Read(make([]byte, 1))
+	}()
+	return ln.Addr().String()
+}
+
+func TestGPSD(t *testing.T) {
+	addr := fakeGPSD(t, []string{
+		`{"class":"DEVICES","devices":[{"class":"DEVICE","path":"/dev/ttyACM0"}]}`,
+		`{"class":"TPV","mode":1}`,
+		`{"class":"TPV","mode":3,"time":"2026-10-09T12:35:19.000Z","lat":39.9042,"lon":116.4074,"altMSL":50,"track":270,"speed":25}`,
+	})
+
+	r := NewGPSD(addr)
+	fixes := make(chan Fix, 4)
+	r.AddFixHandler(func(fix Fix) { fixes <- fix })
+	if _, ok := r.Fix(); ok {
+		t.Error("启动前不应有定位")
+	}
+
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	defer r.Stop()
+
+	select {
+	case fix := <-fixes:
+		if fix.Latitude != 39.9042 || fix.Longitude != 116.4074 || fix.Course != 270 || fix.Speed != 90 || fix.Altitude != 50 {
+			t.Errorf("定位 = %+v", fix)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待定位超时")
+	}
+	if fix, ok := r.Fix(); !ok || fix.Latitude != 39.9042 {
+		t.Errorf("Fix() = %+v, %v", fix, ok)
+	}
+
+	// 长时间没有新的定位时视为失去定位
+	saved := fixTimeout
+	fixTimeout = 0
+	defer func() { fixTimeout = saved }()
+	time.Sleep(time.Millisecond)
+	if _, ok := r.Fix(); ok {
+		t.Error("定位过期后应返回false")
+	}
+}
@@ -0,0 +1,96 @@
+package gps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// DefaultGPSDAddress gpsd的默认地址
+const DefaultGPSDAddress = "localhost:2947"
+
+// gpsd连接参数
+const (
+	dialTimeout  = 5 * time.Second
+	watchCommand = `?WATCH={"enable":true,"json":true};` + "\n"
+)
+
+// NewGPSD 创建从gpsd读取定位的GPS接收器，addr为空时使用DefaultGPSDAddress
+func NewGPSD(addr string) *Receiver {
+	if addr == "" {
+		addr = DefaultGPSDAddress
+	}
+	return &Receiver{
+		name: "gpsd " + addr,
+		open: func(ctx context.Context) (io.ReadWriteCloser, error) {
+			return dialGPSD(ctx, addr)
+		},
+		newDec: func() decoder { return gpsdDecoder{} },
+	}
+}
+
+// dialGPSD 连接gpsd并开始接收JSON报告
+func dialGPSD(ctx context.Context, addr string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接gpsd %s 失败: %w", addr, err)
+	}
+	conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write([]byte(watchCommand)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("发送WATCH命令失败: %w", err)
+	}
+	return conn, nil
+}
+
+// tpv gpsd的TPV (时间、位置、速度) 报告，未知的字段不出现
+type tpv struct {
+	Class  string   `json:"class"`
+	Mode   int      `json:"mode"` // 0-1 未定位，2 二维定位，3 三维定位
+	Time   string   `json:"time"`
+	Lat    *float64 `json:"lat"`
+	Lon    *float64 `json:"lon"`
+	Alt    *float64 `json:"alt"`    // 旧版本gpsd的海拔
+	AltMSL *float64 `json:"altMSL"` // 平均海平面以上的海拔
+	Track  *float64 `json:"track"`  // 航向 (度)
+	Speed  *float64 `json:"speed"`  // 速度 (米/秒)
+}
+
+// gpsdDecoder 解码gpsd的JSON报告，只处理TPV
+type gpsdDecoder struct{}
+
+func (gpsdDecoder) decode(line string) (Fix, bool, error) {
+	var report tpv
+	if err := json.Unmarshal([]byte(line), &report); err != nil {
+		return Fix{}, false, fmt.Errorf("无效的gpsd报告 %q: %w", line, err)
+	}
+	if report.Class != "TPV" {
+		return Fix{}, false, nil
+	}
+
+	var fix Fix
+	if t, err := time.Parse(time.RFC3339Nano, report.Time); err == nil {
+		fix.Time = t.UTC()
+	}
+	if report.Mode < 2 || report.Lat == nil || report.Lon == nil {
+		return fix, true, nil
+	}
+
+	fix.Valid = true
+	fix.Latitude, fix.Longitude = *report.Lat, *report.Lon
+	if alt := report.AltMSL; alt != nil && report.Mode == 3 {
+		fix.HasAltitude, fix.Altitude = true, *alt
+	} else if alt := report.Alt; alt != nil && report.Mode == 3 {
+		fix.HasAltitude, fix.Altitude = true, *alt
+	}
+	// 静止时gpsd通常不输出航向，此时不报告航向和速度，避免信标发出虚假的正北航向
+	if report.Speed != nil && report.Track != nil {
+		fix.HasCourseSpeed = true
+		fix.Course, fix.Speed = *report.Track, *report.Speed*3.6
+	}
+	return fix, true, nil
+}
@@ -0,0 +1,247 @@
+package gps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaud 串口GPS的默认波特率 (NMEA 0183标准)
+const DefaultBaud = 4800
+
+// knotsToKmh 节转换为km/h
+const knotsToKmh = 1.852
+
+// NewSerial 创建从串口或pty读取NMEA语句的GPS接收器，baud为0时使用DefaultBaud
+func NewSerial(device string, baud int) *Receiver {
+	if baud <= 0 {
+		baud = DefaultBaud
+	}
+	return &Receiver{
+		name: device,
+		open: func(ctx context.Context) (io.ReadWriteCloser, error) {
+			return openSerial(device, baud)
+		},
+		newDec: func() decoder { return &nmeaDecoder{} },
+	}
+}
+
+// nmeaDecoder 解码NMEA 0183语句
+// 每个定位周期GPS依次输出多条语句，各语句的字段合并为一个定位；GGA和RMC更新位置后发布
+type nmeaDecoder struct {
+	fix  Fix
+	date time.Time // RMC中的日期，GGA只有时间
+}
+
+// decode 解码一条NMEA语句，不支持的语句忽略
+func (d *nmeaDecoder) decode(line string) (Fix, bool, error) {
+	fields, err := splitNMEA(line)
+	if err != nil {
+		return Fix{}, false, err
+	}
+
+	// 地址字段为两个字符的发送方 (GP、GN、GL等) 加语句类型
+	if len(fields[0]) != 5 {
+		return Fix{}, false, nil
+	}
+	sentence := fields[0][2:]
+	update := true
+	switch sentence {
+	case "GGA":
+		err = d.gga(fields)
+	case "RMC":
+		err = d.rmc(fields)
+	case "VTG":
+		err = d.vtg(fields)
+		update = false
+	default:
+		return Fix{}, false, nil
+	}
+	if err != nil {
+		return Fix{}, false, fmt.Errorf("无效的%s语句 %q: %w", sentence, line, err)
+	}
+	return d.fix, update, nil
+}
+
+// gga 解码GGA语句：时间、位置、定位质量、卫星数和海拔
+// $GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47
+func (d *nmeaDecoder) gga(fields []string) error {
+	if len(fields) < 10 {
+		return fmt.Errorf("字段数不足")
+	}
+	quality, _ := strconv.Atoi(fields[6])
+	if quality == 0 {
+		d.fix.Valid = false
+		return nil
+	}
+
+	lat, lon, err := parseLatLon(fields[2], fields[3], fields[4], fields[5])
+	if err != nil {
+		return err
+	}
+	d.fix.Valid = true
+	d.fix.Latitude, d.fix.Longitude = lat, lon
+	d.fix.Satellites, _ = strconv.Atoi(fields[7])
+	if alt, err := strconv.ParseFloat(fields[9], 64); err == nil {
+		d.fix.HasAltitude, d.fix.Altitude = true, alt
+	} else {
+		d.fix.HasAltitude = false
+	}
+	if t, ok := d.parseTime(fields[1]); ok {
+		d.fix.Time = t
+	}
+	return nil
+}
+
+// rmc 解码RMC语句：时间、状态、位置、速度、航向和日期
+// $GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A
+func (d *nmeaDecoder) rmc(fields []string) error {
+	if len(fields) < 10 {
+		return fmt.Errorf("字段数不足")
+	}
+	if date, err := time.Parse("020106", fields[9]); err == nil {
+		d.date = date
+	}
+	if fields[2] != "A" {
+		d.fix.Valid = false
+		return nil
+	}
+
+	lat, lon, err := parseLatLon(fields[3], fields[4], fields[5], fields[6])
+	if err != nil {
+		return err
+	}
+	d.fix.Valid = true
+	d.fix.Latitude, d.fix.Longitude = lat, lon
+	d.setCourseSpeed(fields[8], fields[7], knotsToKmh)
+	if t, ok := d.parseTime(fields[1]); ok {
+		d.fix.Time = t
+	}
+	return nil
+}
+
+// vtg 解码VTG语句：航向和速度，只更新下一次发布的定位
+// $GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48
+func (d *nmeaDecoder) vtg(fields []string) error {
+	if len(fields) < 9 {
+		return fmt.Errorf("字段数不足")
+	}
+	// NMEA 2.3的模式字段为N表示数据无效
+	if len(fields) > 9 && fields[9] == "N" {
+		return nil
+	}
+	if fields[7] != "" {
+		d.setCourseSpeed(fields[1], fields[7], 1)
+	} else {
+		d.setCourseSpeed(fields[1], fields[5], knotsToKmh)
+	}
+	return nil
+}
+
+// setCourseSpeed 设置航向和速度，速度乘以scale换算为km/h
+// 静止时GPS通常不输出航向，航向或速度缺失时不报告航向和速度
+func (d *nmeaDecoder) setCourseSpeed(course, speed string, scale float64) {
+	v, err := strconv.ParseFloat(speed, 64)
+	c, cerr := strconv.ParseFloat(course, 64)
+	if err != nil || cerr != nil {
+		d.fix.HasCourseSpeed = false
+		return
+	}
+	d.fix.HasCourseSpeed = true
+	d.fix.Course, d.fix.Speed = c, v*scale
+}
+
+// parseTime 解析hhmmss.ss格式的UTC时间，日期取自RMC，没有RMC时使用当天日期
+func (d *nmeaDecoder) parseTime(s string) (time.Time, bool) {
+	if len(s) < 6 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("150405", s[:6])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var frac float64
+	if len(s) > 6 {
+		frac, _ = strconv.ParseFloat("0"+s[6:], 64)
+	}
+
+	date := d.date
+	if date.IsZero() {
+		date = time.Now().UTC()
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(),
+		int(frac*float64(time.Second)), time.UTC), true
+}
+
+// splitNMEA 校验并拆分NMEA语句，返回逗号分隔的字段 (第一个字段不含$)
+func splitNMEA(line string) ([]string, error) {
+	if len(line) < 6 || line[0] != '$' {
+		return nil, fmt.Errorf("不是NMEA语句: %q", line)
+	}
+	body := line[1:]
+	if i := strings.IndexByte(body, '*'); i >= 0 {
+		want, err := strconv.ParseUint(body[i+1:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("NMEA校验和无效: %q", line)
+		}
+		body = body[:i]
+		var sum byte
+		for j := 0; j < len(body); j++ {
+			sum ^= body[j]
+		}
+		if sum != byte(want) {
+			return nil, fmt.Errorf("NMEA校验和错误: %q", line)
+		}
+	}
+	return strings.Split(body, ","), nil
+}
+
+// parseLatLon 解析 ddmm.mmmm,N,dddmm.mmmm,E 格式的经纬度
+func parseLatLon(lat, ns, lon, ew string) (float64, float64, error) {
+	latitude, err := parseDegrees(lat, 2)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的纬度: %w", err)
+	}
+	longitude, err := parseDegrees(lon, 3)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的经度: %w", err)
+	}
+
+	switch ns {
+	case "N":
+	case "S":
+		latitude = -latitude
+	default:
+		return 0, 0, fmt.Errorf("无效的纬度方向: %q", ns)
+	}
+	switch ew {
+	case "E":
+	case "W":
+		longitude = -longitude
+	default:
+		return 0, 0, fmt.Errorf("无效的经度方向: %q", ew)
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return 0, 0, fmt.Errorf("经纬度超出范围")
+	}
+	return latitude, longitude, nil
+}
+
+// parseDegrees 解析度数占digits位的度分格式
+func parseDegrees(s string, digits int) (float64, error) {
+	if len(s) < digits+2 {
+		return 0, fmt.Errorf("%q", s)
+	}
+	deg, err := strconv.Atoi(s[:digits])
+	if err != nil {
+		return 0, fmt.Errorf("%q", s)
+	}
+	minutes, err := strconv.ParseFloat(s[digits:], 64)
+	if err != nil || minutes < 0 || minutes >= 60 {
+		return 0, fmt.Errorf("%q", s)
+	}
+	return float64(deg) + minutes/60, nil
+}
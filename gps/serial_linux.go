@@ -0,0 +1,64 @@
+//go:build linux
+
+package gps
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// baudRates 支持的串口波特率
+var baudRates = map[int]uint32{
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+}
+
+// openSerial 以原始模式打开串口，非阻塞打开以便关闭时中断读取
+func openSerial(device string, baud int) (*os.File, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("不支持的波特率: %d", baud)
+	}
+
+	file, err := os.OpenFile(device, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开GPS串口失败: %w", err)
+	}
+
+	conn, err := file.SyscallConn()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	var ioctlErr error
+	err = conn.Control(func(fd uintptr) {
+		var termios *unix.Termios
+		if termios, ioctlErr = unix.IoctlGetTermios(int(fd), unix.TCGETS); ioctlErr != nil {
+			return
+		}
+		// 8N1，忽略调制解调器控制线，关闭行规程处理
+		termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+		termios.Oflag &^= unix.OPOST
+		termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		termios.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CBAUD
+		termios.Cflag |= unix.CS8 | unix.CLOCAL | unix.CREAD | speed
+		termios.Ispeed, termios.Ospeed = speed, speed
+		termios.Cc[unix.VMIN], termios.Cc[unix.VTIME] = 1, 0
+		ioctlErr = unix.IoctlSetTermios(int(fd), unix.TCSETS, termios)
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("设置GPS串口失败: %w", err)
+	}
+	return file, nil
+}
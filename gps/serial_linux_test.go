@@ -0,0 +1,71 @@
+//go:build linux
+
+package gps
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTestPty 创建pty对，返回主设备和从设备路径
+func openTestPty(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("无法打开/dev/ptmx: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	if err := unix.IoctlSetPointerInt(int(master.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatalf("解锁pty失败: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(master.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		t.Fatalf("获取pty编号失败: %v", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestSerial(t *testing.T) {
+	master, slave := openTestPty(t)
+
+	if _, err := openSerial(slave, 1200); err == nil {
+		t.Error("不支持的波特率应打开失败")
+	}
+
+	r := NewSerial(slave, 0)
+	fixes := make(chan Fix, 4)
+	r.AddFixHandler(func(fix Fix) { fixes <- fix })
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+
+	// 等待串口打开后写入NMEA语句，第一行模拟从语句中间开始读取
+	deadline := time.Now().Add(2 * time.Second)
+	lines := "38.0,M,,*47\r\n" +
+		sentence("GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,091026,003.1,W") + "\r\n" +
+		sentence("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,") + "\r\n"
+	for {
+		time.Sleep(20 * time.Millisecond)
+		master.Write([]byte(lines))
+		select {
+		case fix := <-fixes:
+			if !near(fix.Latitude, 48.1173) || !near(fix.Longitude, 11.516666666) {
+				t.Errorf("定位 = %+v", fix)
+			}
+			r.Stop()
+			return
+		default:
+		}
+		if time.Now().After(deadline) {
+			r.Stop()
+			t.Fatal("等待定位超时")
+		}
+	}
+}
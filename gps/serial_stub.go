@@ -0,0 +1,13 @@
+//go:build !linux
+
+package gps
+
+import (
+	"fmt"
+	"os"
+)
+
+// openSerial 打开串口，仅在Linux上可用
+func openSerial(device string, baud int) (*os.File, error) {
+	return nil, fmt.Errorf("串口GPS仅在Linux系统上可用")
+}
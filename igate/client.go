@@ -0,0 +1,300 @@
+package igate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 登录时报告的软件名称和版本
+const (
+	softwareName    = "aprs_agent"
+	softwareVersion = "1.0"
+)
+
+// 连接参数默认值
+const (
+	DefaultKeepAlive  = 2 * time.Minute // 发送保活注释的间隔
+	DefaultTimeout    = 3 * time.Minute // 服务器每20秒发送一次保活，超过该时间无数据视为连接失效
+	DefaultMinBackoff = 5 * time.Second
+	DefaultMaxBackoff = 5 * time.Minute
+	dialTimeout       = 15 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+// ClientConfig APRS-IS连接配置
+type ClientConfig struct {
+	Server     string        // 服务器地址 host:port
+	Callsign   string        // 登录呼号
+	Passcode   int           // 验证码，小于0时根据呼号计算
+	Filter     string        // 服务器端过滤器，如 "m/50"
+	KeepAlive  time.Duration // 为0时使用DefaultKeepAlive
+	Timeout    time.Duration // 为0时使用DefaultTimeout
+	MinBackoff time.Duration // 为0时使用DefaultMinBackoff
+	MaxBackoff time.Duration // 为0时使用DefaultMaxBackoff
+}
+
+// Client APRS-IS客户端
+// 断开后按指数退避自动重连
+type Client struct {
+	cfg         ClientConfig
+	mu          sync.Mutex
+	conn        net.Conn
+	loggedIn    bool
+	verified    bool
+	lineHandler func(line string)
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// NewClient 创建APRS-IS客户端
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.Passcode < 0 {
+		cfg.Passcode = Passcode(cfg.Callsign)
+	}
+	if cfg.KeepAlive <= 0 {
+		cfg.KeepAlive = DefaultKeepAlive
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.MinBackoff)
+	}
+	return &Client{cfg: cfg}
+}
+
+// SetLineHandler 设置服务器发来的数据包处理函数（不含注释行和行尾）
+func (c *Client) SetLineHandler(handler func(line string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lineHandler = handler
+}
+
+// Start 开始连接服务器，ctx取消时自动停止
+func (c *Client) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		return fmt.Errorf("APRS-IS客户端已在运行")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.run(ctx)
+	return nil
+}
+
+// Stop 断开连接并停止重连
+func (c *Client) Stop() error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	c.wg.Wait()
+	return nil
+}
+
+// IsConnected 是否已连接并登录
+func (c *Client) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loggedIn
+}
+
+// IsVerified 服务器是否已验证登录（未验证时服务器会丢弃上传的数据包）
+func (c *Client) IsVerified() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.verified
+}
+
+// Callsign 获取登录呼号
+func (c *Client) Callsign() string {
+	return c.cfg.Callsign
+}
+
+// Send 发送一行数据，行尾自动添加CRLF
+func (c *Client) Send(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || !c.loggedIn {
+		return fmt.Errorf("APRS-IS未连接")
+	}
+	return c.writeLocked(line)
+}
+
+// writeLocked 写入一行，调用时需持有c.mu
+func (c *Client) writeLocked(line string) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write([]byte(line + "\r\n")); err != nil {
+		return fmt.Errorf("APRS-IS发送失败: %w", err)
+	}
+	return nil
+}
+
+// run 连接循环
+func (c *Client) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	backoff := c.cfg.MinBackoff
+	for {
+		received, err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 收到过服务器数据说明连接曾经正常，重新开始退避
+		if received {
+			backoff = c.cfg.MinBackoff
+		}
+		log.Printf("APRS-IS连接断开: %v，%v后重连", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, c.cfg.MaxBackoff)
+	}
+}
+
+// session 建立一次连接并读取数据直到断开，返回是否收到过服务器数据
+func (c *Client) session(ctx context.Context) (bool, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.cfg.Server)
+	if err != nil {
+		return false, fmt.Errorf("连接 %s 失败: %w", c.cfg.Server, err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	c.mu.Lock()
+	c.conn = conn
+	err = c.writeLocked(c.loginLine())
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.loggedIn = false
+		c.verified = false
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	if err != nil {
+		return false, err
+	}
+	log.Printf("APRS-IS已连接: %s", c.cfg.Server)
+
+	go c.keepAlive(conn, done)
+
+	received := false
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(c.cfg.Timeout))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return received, err
+		}
+		received = true
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			c.handleComment(line)
+			continue
+		}
+
+		c.mu.Lock()
+		handler := c.lineHandler
+		c.mu.Unlock()
+		if handler != nil {
+			handler(line)
+		}
+	}
+}
+
+// loginLine 生成登录命令
+func (c *Client) loginLine() string {
+	line := fmt.Sprintf("user %s pass %d vers %s %s", c.cfg.Callsign, c.cfg.Passcode, softwareName, softwareVersion)
+	if c.cfg.Filter != "" {
+		line += " filter " + c.cfg.Filter
+	}
+	return line
+}
+
+// handleComment 处理服务器注释行，从logresp中获取登录结果
+// 格式: "# logresp N0CALL verified, server T2TEST"
+func (c *Client) handleComment(line string) {
+	fields := strings.Fields(strings.TrimPrefix(line, "#"))
+	if len(fields) < 3 || fields[0] != "logresp" {
+		return
+	}
+
+	verified := strings.TrimSuffix(fields[2], ",") == "verified"
+	server := ""
+	if len(fields) >= 5 && fields[3] == "server" {
+		server = fields[4]
+	}
+
+	c.mu.Lock()
+	c.loggedIn = true
+	c.verified = verified
+	c.mu.Unlock()
+
+	if verified {
+		log.Printf("APRS-IS登录成功: %s (服务器 %s)", fields[1], server)
+	} else {
+		log.Printf("APRS-IS登录未验证: %s，上传的数据包将被服务器丢弃，请检查验证码", fields[1])
+	}
+}
+
+// keepAlive 定期发送保活注释，防止连接因空闲被中间设备断开
+func (c *Client) keepAlive(conn net.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(c.cfg.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			if c.conn == conn {
+				if err := c.writeLocked("#keepalive"); err != nil {
+					conn.Close()
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
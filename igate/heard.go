@@ -0,0 +1,52 @@
+package igate
+
+import (
+	"sync"
+	"time"
+)
+
+// heardStation 直接收听到的站点
+type heardStation struct {
+	channel int
+	last    time.Time
+}
+
+// heardTable 射频上直接收听到的站点表
+// 发给这些站点的APRS-IS消息才会转发到射频
+type heardTable struct {
+	mu       sync.Mutex
+	window   time.Duration
+	stations map[string]heardStation
+}
+
+func newHeardTable(window time.Duration) *heardTable {
+	return &heardTable{
+		window:   window,
+		stations: make(map[string]heardStation),
+	}
+}
+
+// record 记录站点
+func (t *heardTable) record(call string, channel int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for c, st := range t.stations {
+		if now.Sub(st.last) > t.window {
+			delete(t.stations, c)
+		}
+	}
+	t.stations[call] = heardStation{channel: channel, last: now}
+}
+
+// lookup 查询站点是否在时间窗口内被直接收听到，返回收听的通道
+func (t *heardTable) lookup(call string, now time.Time) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st, ok := t.stations[call]
+	if !ok || now.Sub(st.last) > t.window {
+		return 0, false
+	}
+	return st.channel, true
+}
@@ -0,0 +1,277 @@
+package igate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"aprs_agent/ax25"
+	"aprs_agent/modem"
+)
+
+// dedupeWindow 重复包检测时间窗口，同一数据包经多个中继转发时只上传一次
+const dedupeWindow = 30 * time.Second
+
+// sendQueueSize 等待上传到APRS-IS的最大数据包数
+const sendQueueSize = 64
+
+// noGateCalls 路径中包含这些地址的数据包不上传到APRS-IS
+var noGateCalls = []string{"TCPIP", "TCPXX", "NOGATE", "RFONLY"}
+
+// nowFunc 获取当前时间，测试时可替换
+var nowFunc = time.Now
+
+// Modem iGate使用的调制解调器接口，由audio.Manager实现
+type Modem interface {
+	AddFrameHandler(handler func(modem.Frame))
+	Transmit(channel int, data []byte) error
+}
+
+// Config iGate配置
+type Config struct {
+	TxEnabled     bool          // 是否将APRS-IS消息转发到射频，从收听到接收站的通道发射
+	TxPath        []string      // 转发到射频使用的中继路径
+	HeardWindow   time.Duration // 站点最后一次被直接收听后视为本地站点的时间
+	MaxPerMinute  int           // 每分钟转发到射频的最大数据包数
+	MaxPerStation int           // 每个站点每分钟转发到射频的最大消息数
+}
+
+// 转发到射频的默认参数
+const (
+	DefaultHeardWindow   = 30 * time.Minute
+	DefaultMaxPerMinute  = 6
+	DefaultMaxPerStation = 3
+)
+
+// Stats iGate统计信息
+type Stats struct {
+	RFToIS      uint64 // 已上传到APRS-IS的数据包数
+	ISToRF      uint64 // 已转发到射频的数据包数
+	Duplicates  uint64 // 重复而未上传的数据包数
+	Rejected    uint64 // 按iGate规则不上传的数据包数
+	Dropped     uint64 // 因APRS-IS未连接或发送失败而丢弃的数据包数
+	QueueFull   uint64 // 上传队列已满而丢弃的数据包数
+	RateLimited uint64 // 超过发射速率限制而未转发到射频的数据包数
+}
+
+// IGate 将射频接收的数据包上传到APRS-IS，并将发给本地站点的APRS-IS消息转发到射频
+// 射频接收的数据包先进入上传队列，由上传协程发送，避免网络阻塞音频采集回调中的帧处理
+type IGate struct {
+	modem     Modem
+	client    *Client
+	cfg       Config
+	sendQ     chan string // 等待上传到APRS-IS的数据包
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	recent    map[string]time.Time // 最近上传的数据包，用于去重
+	recentIS  map[string]time.Time // 最近转发到射频的数据包，用于去重
+	heard     *heardTable
+	positions map[string]isPacket  // APRS-IS上各站点的最新位置
+	posSent   map[string]time.Time // 最近转发过位置的站点
+	txTimes   []time.Time          // 最近一分钟转发到射频的时间
+	stationTx map[string][]time.Time
+	stats     Stats
+}
+
+// New 创建iGate，注册帧处理函数和APRS-IS数据包处理函数
+func New(m Modem, client *Client, cfg Config) *IGate {
+	if cfg.HeardWindow <= 0 {
+		cfg.HeardWindow = DefaultHeardWindow
+	}
+	if cfg.MaxPerMinute <= 0 {
+		cfg.MaxPerMinute = DefaultMaxPerMinute
+	}
+	if cfg.MaxPerStation <= 0 {
+		cfg.MaxPerStation = DefaultMaxPerStation
+	}
+
+	g := &IGate{
+		modem:     m,
+		client:    client,
+		cfg:       cfg,
+		sendQ:     make(chan string, sendQueueSize),
+		recent:    make(map[string]time.Time),
+		recentIS:  make(map[string]time.Time),
+		heard:     newHeardTable(cfg.HeardWindow),
+		positions: make(map[string]isPacket),
+		posSent:   make(map[string]time.Time),
+		stationTx: make(map[string][]time.Time),
+	}
+	m.AddFrameHandler(g.onFrame)
+	client.SetLineHandler(g.onLine)
+	return g
+}
+
+// Start 启动上传协程，ctx取消时自动停止
+func (g *IGate) Start(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cancel != nil {
+		return fmt.Errorf("iGate已在运行")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	g.cancel = cancel
+
+	g.wg.Add(1)
+	go g.sendLoop(ctx)
+	return nil
+}
+
+// Stop 停止上传协程，队列中未上传的数据包保留到下次启动
+func (g *IGate) Stop() error {
+	g.mu.Lock()
+	cancel := g.cancel
+	g.cancel = nil
+	g.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	g.wg.Wait()
+	return nil
+}
+
+// GetStats 获取统计信息
+func (g *IGate) GetStats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// onFrame 处理接收到的帧
+func (g *IGate) onFrame(f modem.Frame) {
+	frame, err := ax25.Decode(f.Data)
+	if err != nil {
+		return
+	}
+
+	// 未经中继直接收到的站点
+	direct := true
+	for _, digi := range frame.Path {
+		if digi.H {
+			direct = false
+			break
+		}
+	}
+	if direct {
+		g.heard.record(frame.Src.String(), f.Channel, nowFunc())
+	}
+
+	g.gateToIS(frame)
+}
+
+// gateToIS 按iGate规则将帧上传到APRS-IS
+func (g *IGate) gateToIS(frame *ax25.Frame) {
+	line, err := RFToIS(frame, g.client.Callsign())
+
+	g.mu.Lock()
+	if err != nil {
+		g.stats.Rejected++
+		g.mu.Unlock()
+		return
+	}
+	key := frame.Src.String() + ">" + frame.Dest.String() + ":" + string(frame.Info)
+	if isDuplicate(g.recent, key, nowFunc()) {
+		g.stats.Duplicates++
+		g.mu.Unlock()
+		return
+	}
+	g.mu.Unlock()
+
+	// 上传可能因网络阻塞直到写超时，这里只入队，队列已满时丢弃
+	select {
+	case g.sendQ <- line:
+	default:
+		g.mu.Lock()
+		g.stats.QueueFull++
+		g.mu.Unlock()
+		log.Printf("[iGate] 上传队列已满，丢弃: %s", line)
+	}
+}
+
+// sendLoop 上传协程，按顺序将队列中的数据包发送到APRS-IS
+func (g *IGate) sendLoop(ctx context.Context) {
+	defer g.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line := <-g.sendQ:
+			if err := g.client.Send(line); err != nil {
+				g.mu.Lock()
+				g.stats.Dropped++
+				g.mu.Unlock()
+				continue
+			}
+
+			g.mu.Lock()
+			g.stats.RFToIS++
+			g.mu.Unlock()
+			log.Printf("[iGate] RF->IS: %s", line)
+		}
+	}
+}
+
+// isDuplicate 检查数据包是否在去重窗口内出现过，并记录本次出现，调用时需持有g.mu
+// key由源地址、目的地址和信息字段组成，忽略路径
+func isDuplicate(recent map[string]time.Time, key string, now time.Time) bool {
+	for k, at := range recent {
+		if now.Sub(at) > dedupeWindow {
+			delete(recent, k)
+		}
+	}
+
+	if _, ok := recent[key]; ok {
+		return true
+	}
+	recent[key] = now
+	return false
+}
+
+// RFToIS 将射频接收的帧转换为APRS-IS的TNC2格式，路径末尾加上 "qAR,iGate呼号"
+// 按标准iGate规则，以下数据包不上传:
+//   - 非APRS UI帧
+//   - 路径中包含TCPIP、TCPXX、NOGATE、RFONLY
+//   - 第三方数据包 ('}') 和通用查询 ('?')
+//
+// 信息字段在第一个CR或LF处截断
+func RFToIS(frame *ax25.Frame, igateCall string) (string, error) {
+	if !frame.IsUI() || frame.PID != ax25.PIDNoLayer3 {
+		return "", fmt.Errorf("不是APRS UI帧")
+	}
+
+	for _, digi := range frame.Path {
+		for _, call := range noGateCalls {
+			if digi.Call == call {
+				return "", fmt.Errorf("路径包含 %s", call)
+			}
+		}
+	}
+
+	info := string(frame.Info)
+	if i := strings.IndexAny(info, "\r\n"); i >= 0 {
+		info = info[:i]
+	}
+	if info == "" {
+		return "", fmt.Errorf("信息字段为空")
+	}
+	switch info[0] {
+	case '}':
+		return "", fmt.Errorf("第三方数据包")
+	case '?':
+		return "", fmt.Errorf("通用查询")
+	}
+
+	// 源地址和目的地址中不会出现 ':'，第一个 ':' 即为分隔符
+	tnc2 := frame.String()
+	header := tnc2[:strings.IndexByte(tnc2, ':')]
+	return fmt.Sprintf("%s,qAR,%s:%s", header, igateCall, info), nil
+}
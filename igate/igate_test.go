@@ -0,0 +1,418 @@
+package igate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aprs_agent/ax25"
+	"aprs_agent/modem"
+)
+
+// sentFrame 记录发送的帧
+type sentFrame struct {
+	channel int
+	tnc2    string
+}
+
+// fakeModem 记录帧处理函数和发送的帧
+type fakeModem struct {
+	mu       sync.Mutex
+	handlers []func(modem.Frame)
+	sent     []sentFrame
+}
+
+func (m *fakeModem) Transmit(channel int, data []byte) error {
+	frame, err := ax25.Decode(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentFrame{channel: channel, tnc2: frame.String()})
+	return nil
+}
+
+// takeSent 取出已发送的帧
+func (m *fakeModem) takeSent() []sentFrame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := m.sent
+	m.sent = nil
+	return sent
+}
+
+func (m *fakeModem) AddFrameHandler(handler func(modem.Frame)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+func (m *fakeModem) receive(channel int, tnc2 string) {
+	frame, err := ax25.ParseTNC2(tnc2)
+	if err != nil {
+		panic(err)
+	}
+	data, err := frame.Encode()
+	if err != nil {
+		panic(err)
+	}
+
+	m.mu.Lock()
+	handlers := m.handlers
+	m.mu.Unlock()
+	for _, h := range handlers {
+		h(modem.Frame{Channel: channel, Data: data})
+	}
+}
+
+// fakeServer 模拟APRS-IS服务器，每个连接的数据行发送到lines
+type fakeServer struct {
+	listener net.Listener
+	conns    chan net.Conn
+	lines    chan string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	s := &fakeServer{
+		listener: listener,
+		conns:    make(chan net.Conn, 4),
+		lines:    make(chan string, 16),
+	}
+	go s.acceptLoop()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeServer) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("# aprsc 2.1.14\r\n"))
+		s.conns <- conn
+		go func() {
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				line = strings.TrimRight(line, "\r\n")
+				if strings.HasPrefix(line, "user ") {
+					call := strings.Fields(line)[1]
+					conn.Write([]byte("# logresp " + call + " verified, server T2TEST\r\n"))
+				}
+				s.lines <- line
+			}
+		}()
+	}
+}
+
+func (s *fakeServer) expect(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case line := <-s.lines:
+		if line != want {
+			t.Errorf("服务器收到 %q, want %q", line, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("等待超时: %q", want)
+	}
+}
+
+func (s *fakeServer) accept(t *testing.T) net.Conn {
+	t.Helper()
+	select {
+	case conn := <-s.conns:
+		return conn
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待客户端连接超时")
+		return nil
+	}
+}
+
+// waitFor 轮询等待条件满足
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPasscode(t *testing.T) {
+	tests := []struct {
+		call string
+		want int
+	}{
+		{"N0CALL", 13023},
+		{"n0call-10", 13023},
+		{"BG0ABC", Passcode("BG0ABC-7")},
+	}
+	for _, tt := range tests {
+		if got := Passcode(tt.call); got != tt.want {
+			t.Errorf("Passcode(%q) = %d, want %d", tt.call, got, tt.want)
+		}
+	}
+}
+
+func TestRFToIS(t *testing.T) {
+	tests := []struct {
+		tnc2 string
+		want string // 为空表示不上传
+	}{
+		{"BG0ABC-7>APDR16,WIDE1*,WIDE2-1:!3000.00N/12000.00E>", "BG0ABC-7>APDR16,WIDE1*,WIDE2-1,qAR,N0CALL-10:!3000.00N/12000.00E>"},
+		{"BG0ABC>APRS::N0CALL   :hi{1", "BG0ABC>APRS,qAR,N0CALL-10::N0CALL   :hi{1"},
+		{"BG0ABC>APRS:>status\r\ntrailer", "BG0ABC>APRS,qAR,N0CALL-10:>status"},
+		{"BG0ABC>APRS,TCPIP*:>status", ""},
+		{"BG0ABC>APRS,NOGATE:>status", ""},
+		{"BG0ABC>APRS,WIDE1*,RFONLY:>status", ""},
+		{"BG0ABC>APRS,TCPXX*:>status", ""},
+		{"BG0ABC>APRS:}N0CALL>APRS,TCPIP,BG0ABC*::BG0ABC   :hi", ""},
+		{"BG0ABC>APRS:?APRS?", ""},
+		{"BG0ABC>APRS:", ""},
+	}
+	for _, tt := range tests {
+		frame, err := ax25.ParseTNC2(tt.tnc2)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", tt.tnc2, err)
+		}
+		got, err := RFToIS(frame, "N0CALL-10")
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q 不应上传，得到 %q", tt.tnc2, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("RFToIS(%q) = %q, %v, want %q", tt.tnc2, got, err, tt.want)
+		}
+	}
+
+	// 非UI帧
+	frame := ax25.NewUIFrame(ax25.MustParseAddress("BG0ABC"), ax25.MustParseAddress("APRS"), nil, []byte(">x"))
+	frame.Control = ax25.ControlSABM
+	if _, err := RFToIS(frame, "N0CALL"); err == nil {
+		t.Error("非UI帧不应上传")
+	}
+}
+
+func TestIGate(t *testing.T) {
+	server := newFakeServer(t)
+	client := NewClient(ClientConfig{
+		Server:     server.listener.Addr().String(),
+		Callsign:   "N0CALL-10",
+		Passcode:   -1,
+		Filter:     "m/50",
+		MinBackoff: 10 * time.Millisecond,
+	})
+
+	m := &fakeModem{}
+	g := New(m, client, Config{})
+
+	// 替换iGate注册的处理函数，检查客户端收到的数据包
+	var mu sync.Mutex
+	var received []string
+	client.SetLineHandler(func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, line)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	defer client.Stop()
+	if err := g.Start(ctx); err != nil {
+		t.Fatalf("启动iGate失败: %v", err)
+	}
+	defer g.Stop()
+
+	conn := server.accept(t)
+	server.expect(t, "user N0CALL-10 pass 13023 vers aprs_agent 1.0 filter m/50")
+	waitFor(t, "登录", client.IsConnected)
+	if !client.IsVerified() {
+		t.Error("登录应已验证")
+	}
+
+	// 射频接收的数据包上传，经中继重复收到的不再上传
+	m.receive(0, "BG0ABC-7>APDR16,WIDE1-1:!3000.00N/12000.00E>")
+	m.receive(0, "BG0ABC-7>APDR16,BG0XYZ*,WIDE1*:!3000.00N/12000.00E>")
+	m.receive(0, "BG0ABC-7>APDR16,TCPIP*:>not gated")
+	m.receive(0, "BG0ABC-9>APDR16:>second")
+	server.expect(t, "BG0ABC-7>APDR16,WIDE1-1,qAR,N0CALL-10:!3000.00N/12000.00E>")
+	server.expect(t, "BG0ABC-9>APDR16,qAR,N0CALL-10:>second")
+
+	waitFor(t, "上传计数", func() bool { return g.GetStats().RFToIS == 2 })
+	if stats := g.GetStats(); stats.Duplicates != 1 || stats.Rejected != 1 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+
+	// 服务器发来的数据包交给处理函数，注释行被忽略
+	conn.Write([]byte("# server keepalive\r\nBG0XYZ>APRS,TCPIP*,qAC,T2TEST::N0CALL-10:hello{1\r\n"))
+	waitFor(t, "接收数据包", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	})
+	mu.Lock()
+	if received[0] != "BG0XYZ>APRS,TCPIP*,qAC,T2TEST::N0CALL-10:hello{1" {
+		t.Errorf("收到的数据包错误: %q", received[0])
+	}
+	mu.Unlock()
+
+	// 服务器断开后自动重连并重新登录
+	conn.Close()
+	server.accept(t)
+	server.expect(t, "user N0CALL-10 pass 13023 vers aprs_agent 1.0 filter m/50")
+	waitFor(t, "重新登录", client.IsConnected)
+
+	// 停止后不再发送
+	client.Stop()
+	if err := client.Send("BG0ABC>APRS:>x"); err == nil {
+		t.Error("停止后发送应失败")
+	}
+}
+
+func TestSendQueueFull(t *testing.T) {
+	client := NewClient(ClientConfig{Callsign: "N0CALL-10"})
+	m := &fakeModem{}
+	g := New(m, client, Config{})
+
+	// 上传协程未运行时数据包留在队列中，队列已满后丢弃，帧处理不会阻塞
+	for i := 0; i <= sendQueueSize; i++ {
+		m.receive(0, fmt.Sprintf("BG0ABC-7>APDR16:>%d", i))
+	}
+	if stats := g.GetStats(); stats.QueueFull != 1 || stats.RFToIS != 0 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+
+	// 未连接时上传失败计入丢弃
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.Start(ctx)
+	defer g.Stop()
+	waitFor(t, "清空上传队列", func() bool { return g.GetStats().Dropped == sendQueueSize })
+}
+
+func TestKeepAlive(t *testing.T) {
+	server := newFakeServer(t)
+	client := NewClient(ClientConfig{
+		Server:    server.listener.Addr().String(),
+		Callsign:  "N0CALL",
+		Passcode:  12345,
+		KeepAlive: 20 * time.Millisecond,
+		Timeout:   100 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client.Start(ctx)
+	defer client.Stop()
+
+	server.accept(t)
+	server.expect(t, "user N0CALL pass 12345 vers aprs_agent 1.0")
+	server.expect(t, "#keepalive")
+}
+
+func TestISToRF(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	m := &fakeModem{}
+	client := NewClient(ClientConfig{Callsign: "N0CALL-10"})
+	g := New(m, client, Config{
+		TxEnabled:     true,
+		TxPath:        []string{"WIDE1-1"},
+		MaxPerMinute:  5,
+		MaxPerStation: 3,
+	})
+
+	// BG0ABC-7在通道1上直接收到，BG0DIG经中继收到，BG0RF在通道0上直接收到
+	m.receive(1, "BG0ABC-7>APDR16:>hi")
+	m.receive(0, "BG0DIG>APRS,WIDE1*:>via digi")
+	m.receive(0, "BG0RF>APRS:>local")
+
+	g.onLine("BG0XYZ>APRS,TCPIP*,qAC,T2TEST:=3000.00N/12000.00E-home")
+
+	tests := []struct {
+		line string
+		want []string // 期望在通道1上发送的帧
+	}{
+		// 第一次转发消息时附带发送站的位置
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :hello{1", []string{
+			"N0CALL-10>APZAGT,WIDE1-1:}BG0XYZ>APRS,TCPIP,N0CALL-10*::BG0ABC-7 :hello{1",
+			"N0CALL-10>APZAGT,WIDE1-1:}BG0XYZ>APRS,TCPIP,N0CALL-10*:=3000.00N/12000.00E-home",
+		}},
+		// 重复的消息
+		{"BG0XYZ>APRS,TCPIP*,qAS,T2OTHER::BG0ABC-7 :hello{1", nil},
+		// 位置只转发一次
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :again{2", []string{
+			"N0CALL-10>APZAGT,WIDE1-1:}BG0XYZ>APRS,TCPIP,N0CALL-10*::BG0ABC-7 :again{2",
+		}},
+		// 经中继收到的站点、未收到的站点、公告、未验证客户端、发送站也在本地
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0DIG   :hello", nil},
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0FAR   :hello", nil},
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BLN1     :bulletin", nil},
+		{"BG0XYZ>APRS,TCPXX*,qAX,T2TEST::BG0ABC-7 :unverified", nil},
+		{"BG0RF>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :from local", nil},
+		// 每个站点每分钟最多3条
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :third{3", []string{
+			"N0CALL-10>APZAGT,WIDE1-1:}BG0XYZ>APRS,TCPIP,N0CALL-10*::BG0ABC-7 :third{3",
+		}},
+		{"BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :limited{4", nil},
+	}
+	for _, tt := range tests {
+		g.onLine(tt.line)
+		sent := m.takeSent()
+		if len(sent) != len(tt.want) {
+			t.Errorf("%q: 发送 %d 帧, want %d: %+v", tt.line, len(sent), len(tt.want), sent)
+			continue
+		}
+		for i, s := range sent {
+			if s.channel != 1 || s.tnc2 != tt.want[i] {
+				t.Errorf("%q: 帧 %d = %q 通道 %d, want %q", tt.line, i, s.tnc2, s.channel, tt.want[i])
+			}
+		}
+	}
+
+	if stats := g.GetStats(); stats.ISToRF != 4 || stats.RateLimited != 1 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+
+	// 一分钟后恢复，每分钟总数最多5条
+	now = now.Add(time.Minute)
+	m.receive(0, "BG0RF>APRS:>local")
+	for i := 0; i < 6; i++ {
+		g.onLine(fmt.Sprintf("BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0RF    :msg{%d", 10+i))
+		g.onLine(fmt.Sprintf("BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :msg{%d", 20+i))
+	}
+	if sent := m.takeSent(); len(sent) != 5 {
+		t.Errorf("每分钟应最多发送5帧，发送了 %d 帧", len(sent))
+	}
+
+	// 超过收听时间窗口后不再转发
+	now = now.Add(DefaultHeardWindow + time.Minute)
+	g.onLine("BG0XYZ>APRS,TCPIP*,qAC,T2TEST::BG0ABC-7 :late{30")
+	if sent := m.takeSent(); len(sent) != 0 {
+		t.Errorf("超过收听时间窗口不应转发: %+v", sent)
+	}
+}
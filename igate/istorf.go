@@ -0,0 +1,194 @@
+package igate
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"aprs_agent/aprs"
+	"aprs_agent/ax25"
+)
+
+// toCall 转发到射频的帧使用的目的地址 (APZ为实验性软件保留)
+const toCall = "APZAGT"
+
+// rateWindow 发射速率限制的统计窗口
+const rateWindow = time.Minute
+
+// isPacket 从APRS-IS收到的数据包
+type isPacket struct {
+	source string
+	dest   string
+	path   []string
+	info   string
+	at     time.Time
+}
+
+// thirdParty 生成第三方格式的信息字段: "}源>目的,TCPIP,iGate呼号*:信息"
+func (p isPacket) thirdParty(igateCall string) string {
+	return fmt.Sprintf("}%s>%s,TCPIP,%s*:%s", p.source, p.dest, igateCall, p.info)
+}
+
+// onLine 处理APRS-IS发来的数据包
+// 记录各站点的最新位置；发给本地站点的消息转发到射频
+func (g *IGate) onLine(line string) {
+	if !g.cfg.TxEnabled {
+		return
+	}
+
+	pkt, err := aprs.ParseTNC2(line)
+	if err != nil {
+		return
+	}
+
+	// 来自未验证客户端或禁止转发的数据包
+	for _, p := range pkt.Path {
+		switch strings.TrimSuffix(p, "*") {
+		case "TCPXX", "qAX", "NOGATE", "RFONLY":
+			return
+		}
+	}
+
+	now := nowFunc()
+	packet := isPacket{
+		source: pkt.Source,
+		dest:   pkt.Dest,
+		path:   pkt.Path,
+		info:   pkt.Raw,
+		at:     now,
+	}
+
+	if pkt.Position != nil && pkt.Object == nil && pkt.Item == nil {
+		g.recordPosition(packet)
+		return
+	}
+
+	if pkt.Message == nil || pkt.Message.Type == aprs.MessageBulletin {
+		return
+	}
+
+	channel, ok := g.heard.lookup(pkt.Message.Addressee, now)
+	if !ok {
+		return
+	}
+	// 发送站也在射频上，接收站可以直接收到
+	if _, ok := g.heard.lookup(pkt.Source, now); ok {
+		return
+	}
+
+	g.gateToRF(channel, pkt.Message.Addressee, packet)
+}
+
+// recordPosition 记录站点在APRS-IS上的最新位置
+func (g *IGate) recordPosition(packet isPacket) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for call, p := range g.positions {
+		if packet.at.Sub(p.at) > g.cfg.HeardWindow {
+			delete(g.positions, call)
+		}
+	}
+	g.positions[packet.source] = packet
+}
+
+// gateToRF 将消息转发到射频，发送站的位置在时间窗口内只转发一次
+func (g *IGate) gateToRF(channel int, addressee string, msg isPacket) {
+	g.mu.Lock()
+	key := msg.source + ">" + msg.dest + ":" + msg.info
+	if isDuplicate(g.recentIS, key, msg.at) {
+		g.stats.Duplicates++
+		g.mu.Unlock()
+		return
+	}
+	if !g.allowTx(addressee, msg.at) {
+		g.stats.RateLimited++
+		g.mu.Unlock()
+		log.Printf("[iGate] 超过发射速率限制，不转发发给 %s 的消息", addressee)
+		return
+	}
+
+	packets := []isPacket{msg}
+	if pos, ok := g.positions[msg.source]; ok && msg.at.Sub(g.posSent[msg.source]) > g.cfg.HeardWindow {
+		if g.allowTx("", msg.at) {
+			packets = append(packets, pos)
+			g.posSent[msg.source] = msg.at
+		}
+	}
+	for call, at := range g.posSent {
+		if msg.at.Sub(at) > g.cfg.HeardWindow {
+			delete(g.posSent, call)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, p := range packets {
+		if err := g.transmit(channel, p); err != nil {
+			log.Printf("[iGate] IS->RF 发送失败: %v", err)
+			continue
+		}
+		g.mu.Lock()
+		g.stats.ISToRF++
+		g.mu.Unlock()
+		log.Printf("[iGate] IS->RF: %s>%s:%s", p.source, p.dest, p.info)
+	}
+}
+
+// allowTx 检查并记录发射速率，station为空时只检查总速率，调用时需持有g.mu
+func (g *IGate) allowTx(station string, now time.Time) bool {
+	g.txTimes = pruneTimes(g.txTimes, now)
+	if len(g.txTimes) >= g.cfg.MaxPerMinute {
+		return false
+	}
+
+	if station != "" {
+		times := pruneTimes(g.stationTx[station], now)
+		if len(times) >= g.cfg.MaxPerStation {
+			g.stationTx[station] = times
+			return false
+		}
+		g.stationTx[station] = append(times, now)
+	}
+	for call, times := range g.stationTx {
+		if len(pruneTimes(times, now)) == 0 {
+			delete(g.stationTx, call)
+		}
+	}
+
+	g.txTimes = append(g.txTimes, now)
+	return true
+}
+
+// pruneTimes 删除统计窗口之外的时间
+func pruneTimes(times []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= rateWindow {
+		i++
+	}
+	return times[i:]
+}
+
+// transmit 以第三方格式发送数据包
+func (g *IGate) transmit(channel int, p isPacket) error {
+	src, err := ax25.ParseAddress(g.client.Callsign())
+	if err != nil {
+		return fmt.Errorf("iGate呼号无效: %w", err)
+	}
+
+	path := make([]ax25.Address, 0, len(g.cfg.TxPath))
+	for _, s := range g.cfg.TxPath {
+		digi, err := ax25.ParseAddress(s)
+		if err != nil {
+			return fmt.Errorf("中继路径无效: %w", err)
+		}
+		path = append(path, digi)
+	}
+
+	frame := ax25.NewUIFrame(src, ax25.MustParseAddress(toCall), path, []byte(p.thirdParty(g.client.Callsign())))
+	data, err := frame.Encode()
+	if err != nil {
+		return err
+	}
+	return g.modem.Transmit(channel, data)
+}
@@ -0,0 +1,21 @@
+package igate
+
+import "strings"
+
+// Passcode 计算APRS-IS登录验证码
+// 只使用呼号部分（不含SSID），不区分大小写
+func Passcode(callsign string) int {
+	call := strings.ToUpper(callsign)
+	if i := strings.IndexByte(call, '-'); i >= 0 {
+		call = call[:i]
+	}
+
+	hash := 0x73e2
+	for i := 0; i < len(call); i += 2 {
+		hash ^= int(call[i]) << 8
+		if i+1 < len(call) {
+			hash ^= int(call[i+1])
+		}
+	}
+	return hash & 0x7fff
+}
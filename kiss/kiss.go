@@ -0,0 +1,127 @@
+package kiss
+
+// KISS特殊字节
+const (
+	FEND  = 0xC0 // 帧定界符
+	FESC  = 0xDB // 转义符
+	TFEND = 0xDC // 转义后的FEND
+	TFESC = 0xDD // 转义后的FESC
+)
+
+// KISS命令 (类型字节的低4位，高4位为端口号)
+const (
+	CmdData        = 0x00
+	CmdTxDelay     = 0x01 // 单位10毫秒
+	CmdPersistence = 0x02
+	CmdSlotTime    = 0x03 // 单位10毫秒
+	CmdTxTail      = 0x04 // 单位10毫秒
+	CmdFullDuplex  = 0x05
+	CmdSetHardware = 0x06
+	CmdReturn      = 0xFF // 退出KISS模式，整个类型字节为0xFF
+)
+
+// MaxFrameLen 解码器接受的最大帧长度，超过的帧被丢弃
+const MaxFrameLen = 2048
+
+// Frame KISS帧
+type Frame struct {
+	Port    int  // 端口号 (0-15)，对应无线电通道
+	Command byte // 命令
+	Data    []byte
+}
+
+// Encode 将帧编码为带FEND定界和转义的KISS字节流
+func Encode(port int, command byte, data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/8+3)
+	out = append(out, FEND)
+	if command == CmdReturn {
+		out = append(out, CmdReturn)
+	} else {
+		out = append(out, byte(port&0x0F)<<4|command&0x0F)
+	}
+
+	for _, b := range data {
+		switch b {
+		case FEND:
+			out = append(out, FESC, TFEND)
+		case FESC:
+			out = append(out, FESC, TFESC)
+		default:
+			out = append(out, b)
+		}
+	}
+
+	return append(out, FEND)
+}
+
+// Decoder KISS流解码器，处理任意分片的输入
+type Decoder struct {
+	buf     []byte
+	escape  bool
+	discard bool // 当前帧过长，丢弃到下一个FEND
+}
+
+// NewDecoder 创建KISS解码器
+func NewDecoder() *Decoder {
+	return &Decoder{buf: make([]byte, 0, 512)}
+}
+
+// Feed 输入字节流，返回其中完整的帧
+func (d *Decoder) Feed(data []byte) []Frame {
+	var frames []Frame
+
+	for _, b := range data {
+		if b == FEND {
+			if len(d.buf) > 0 && !d.discard {
+				frames = append(frames, d.parse())
+			}
+			d.buf = d.buf[:0]
+			d.escape = false
+			d.discard = false
+			continue
+		}
+
+		if d.discard {
+			continue
+		}
+
+		if d.escape {
+			d.escape = false
+			switch b {
+			case TFEND:
+				b = FEND
+			case TFESC:
+				b = FESC
+			default:
+				// 无效的转义序列，按原样保留
+			}
+		} else if b == FESC {
+			d.escape = true
+			continue
+		}
+
+		if len(d.buf) >= MaxFrameLen {
+			d.discard = true
+			continue
+		}
+		d.buf = append(d.buf, b)
+	}
+
+	return frames
+}
+
+// parse 将缓冲区中的帧内容解析为Frame
+func (d *Decoder) parse() Frame {
+	typ := d.buf[0]
+	data := make([]byte, len(d.buf)-1)
+	copy(data, d.buf[1:])
+
+	if typ == CmdReturn {
+		return Frame{Command: CmdReturn, Data: data}
+	}
+	return Frame{
+		Port:    int(typ >> 4),
+		Command: typ & 0x0F,
+		Data:    data,
+	}
+}
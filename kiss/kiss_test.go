@@ -0,0 +1,203 @@
+package kiss
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"aprs_agent/audio"
+	"aprs_agent/modem"
+)
+
+// fakeModem 记录发送的帧和发射参数
+type fakeModem struct {
+	mu       sync.Mutex
+	channels int
+	handlers []func(modem.Frame)
+	sent     []Frame
+	params   []audio.TxParams
+}
+
+func newFakeModem(channels int) *fakeModem {
+	return &fakeModem{channels: channels, params: make([]audio.TxParams, channels)}
+}
+
+func (m *fakeModem) AddFrameHandler(handler func(modem.Frame)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+func (m *fakeModem) Transmit(channel int, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, Frame{Port: channel, Data: data})
+	return nil
+}
+
+func (m *fakeModem) ChannelCount() int {
+	return m.channels
+}
+
+func (m *fakeModem) GetTxParams(channel int) (audio.TxParams, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.params[channel], nil
+}
+
+func (m *fakeModem) SetTxParams(channel int, params audio.TxParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.params[channel] = params
+	return nil
+}
+
+func (m *fakeModem) receive(frame modem.Frame) {
+	m.mu.Lock()
+	handlers := m.handlers
+	m.mu.Unlock()
+	for _, h := range handlers {
+		h(frame)
+	}
+}
+
+// waitFor 轮询等待条件满足
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	data := []byte{0x01, FEND, 0x02, FESC, 0x03}
+	encoded := Encode(1, CmdData, data)
+
+	want := []byte{FEND, 0x10, 0x01, FESC, TFEND, 0x02, FESC, TFESC, 0x03, FEND}
+	if !bytes.Equal(encoded, want) {
+		t.Fatalf("编码错误: % X", encoded)
+	}
+
+	// 逐字节输入，并在前面加上多余的FEND
+	decoder := NewDecoder()
+	var frames []Frame
+	for _, b := range append([]byte{FEND, FEND}, encoded...) {
+		frames = append(frames, decoder.Feed([]byte{b})...)
+	}
+	if len(frames) != 1 {
+		t.Fatalf("期望1帧，得到 %d", len(frames))
+	}
+	if frames[0].Port != 1 || frames[0].Command != CmdData || !bytes.Equal(frames[0].Data, data) {
+		t.Errorf("解码错误: %+v", frames[0])
+	}
+
+	// 返回命令
+	frames = NewDecoder().Feed([]byte{FEND, CmdReturn, FEND})
+	if len(frames) != 1 || frames[0].Command != CmdReturn {
+		t.Errorf("返回命令解码错误: %+v", frames)
+	}
+
+	// 超长帧被丢弃，后续帧不受影响
+	long := append([]byte{FEND, 0x00}, make([]byte, MaxFrameLen+10)...)
+	long = append(long, Encode(0, CmdData, []byte("ok"))...)
+	frames = NewDecoder().Feed(long)
+	if len(frames) != 1 || string(frames[0].Data) != "ok" {
+		t.Errorf("超长帧处理错误: %d 帧", len(frames))
+	}
+}
+
+func TestServer(t *testing.T) {
+	m := newFakeModem(2)
+	server := NewServer(m, "127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	defer server.Stop()
+
+	var clients []net.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", server.Addr().String())
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer conn.Close()
+		clients = append(clients, conn)
+	}
+	waitFor(t, "客户端连接", func() bool { return server.ClientCount() == 2 })
+
+	// 接收到的帧发送给所有客户端，端口号为通道号
+	m.receive(modem.Frame{Channel: 1, Data: []byte("frame")})
+	for i, conn := range clients {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("客户端 %d 读取失败: %v", i, err)
+		}
+		if want := Encode(1, CmdData, []byte("frame")); !bytes.Equal(buf[:n], want) {
+			t.Errorf("客户端 %d 收到 % X, want % X", i, buf[:n], want)
+		}
+	}
+
+	// 数据帧和参数命令
+	var stream []byte
+	stream = append(stream, Encode(1, CmdData, []byte("tx"))...)
+	stream = append(stream, Encode(1, CmdTxDelay, []byte{50})...)
+	stream = append(stream, Encode(1, CmdPersistence, []byte{127})...)
+	stream = append(stream, Encode(1, CmdSlotTime, []byte{20})...)
+	stream = append(stream, Encode(1, CmdTxTail, []byte{3})...)
+	stream = append(stream, Encode(1, CmdFullDuplex, []byte{1})...)
+	stream = append(stream, Encode(5, CmdData, []byte("bad port"))...)
+	if _, err := clients[0].Write(stream); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+
+	want := audio.TxParams{
+		TxDelay:     500 * time.Millisecond,
+		Persistence: 127,
+		SlotTime:    200 * time.Millisecond,
+		TxTail:      30 * time.Millisecond,
+		FullDuplex:  true,
+	}
+	waitFor(t, "发射参数", func() bool {
+		params, _ := m.GetTxParams(1)
+		return params == want
+	})
+
+	m.mu.Lock()
+	sent := m.sent
+	m.mu.Unlock()
+	if len(sent) != 1 || sent[0].Port != 1 || string(sent[0].Data) != "tx" {
+		t.Errorf("发送的帧错误: %+v", sent)
+	}
+	if params, _ := m.GetTxParams(0); params != (audio.TxParams{}) {
+		t.Errorf("端口0的参数不应改变: %+v", params)
+	}
+
+	// SETHW TNC: 查询
+	clients[1].Write(Encode(0, CmdSetHardware, []byte("TNC:")))
+	clients[1].SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, err := clients[1].Read(buf)
+	if err != nil {
+		t.Fatalf("读取SETHW响应失败: %v", err)
+	}
+	frames := NewDecoder().Feed(buf[:n])
+	if len(frames) != 1 || frames[0].Command != CmdSetHardware || string(frames[0].Data) != "TNC:"+hardwareName {
+		t.Errorf("SETHW响应错误: %+v", frames)
+	}
+
+	// 断开的客户端被移除
+	clients[0].Close()
+	waitFor(t, "客户端断开", func() bool { return server.ClientCount() == 1 })
+}
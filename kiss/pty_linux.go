@@ -0,0 +1,268 @@
+//go:build linux
+
+package kiss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultPtyLink pty从设备的默认符号链接
+const DefaultPtyLink = "/tmp/kisstnc"
+
+// ptyPollInterval 等待客户端打开从设备的轮询间隔
+const ptyPollInterval = 200 * time.Millisecond
+
+// Pty KISS over伪终端
+// 创建pty对，在符号链接处发布从设备路径，供只支持串口TNC的软件使用
+type Pty struct {
+	modem     Modem
+	link      string
+	hub       *hub
+	mu        sync.Mutex
+	master    *os.File
+	slaveName string
+	wg        sync.WaitGroup
+}
+
+// NewPty 创建KISS pty，link为空时使用DefaultPtyLink
+func NewPty(m Modem, link string) *Pty {
+	if link == "" {
+		link = DefaultPtyLink
+	}
+	p := &Pty{
+		modem: m,
+		link:  link,
+		hub:   newHub(),
+	}
+	m.AddFrameHandler(p.hub.broadcast)
+	return p
+}
+
+// Start 创建pty对并开始服务，ctx取消时自动停止
+func (p *Pty) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.master != nil {
+		return fmt.Errorf("KISS pty已在运行")
+	}
+
+	master, slaveName, err := openPty()
+	if err != nil {
+		return err
+	}
+
+	// 替换旧的符号链接，不覆盖普通文件
+	if info, err := os.Lstat(p.link); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			master.Close()
+			return fmt.Errorf("%s 已存在且不是符号链接", p.link)
+		}
+		os.Remove(p.link)
+	}
+	if err := os.Symlink(slaveName, p.link); err != nil {
+		master.Close()
+		return fmt.Errorf("创建符号链接失败: %w", err)
+	}
+
+	p.master = master
+	p.slaveName = slaveName
+	p.hub.open()
+
+	p.wg.Add(1)
+	go p.serve(ctx, master)
+
+	go func() {
+		<-ctx.Done()
+		p.Stop()
+	}()
+
+	log.Printf("KISS pty已创建: %s -> %s", p.link, slaveName)
+	return nil
+}
+
+// Stop 关闭pty并删除符号链接
+func (p *Pty) Stop() error {
+	p.mu.Lock()
+	master := p.master
+	p.master = nil
+	p.mu.Unlock()
+
+	if master == nil {
+		return nil
+	}
+
+	// 只删除仍指向本pty的符号链接
+	if target, err := os.Readlink(p.link); err == nil && target == p.slaveName {
+		os.Remove(p.link)
+	}
+
+	err := master.Close()
+	p.hub.closeAll()
+	p.wg.Wait()
+
+	log.Println("KISS pty已关闭")
+	return err
+}
+
+// SlaveName 获取pty从设备路径
+func (p *Pty) SlaveName() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.slaveName
+}
+
+// Link 获取符号链接路径
+func (p *Pty) Link() string {
+	return p.link
+}
+
+// IsConnected 客户端是否已打开从设备
+func (p *Pty) IsConnected() bool {
+	return p.hub.count() > 0
+}
+
+// serve 等待客户端打开从设备并处理KISS数据，客户端关闭后重新等待
+func (p *Pty) serve(ctx context.Context, master *os.File) {
+	defer p.wg.Done()
+
+	for {
+		if !waitForSlave(ctx, master) {
+			return
+		}
+
+		sess := newSession(p.slaveName, &ptyConn{master: master}, p.modem)
+		if !p.hub.add(sess) {
+			return
+		}
+		log.Printf("KISS pty客户端已连接: %s", p.slaveName)
+		err := sess.run()
+		p.hub.remove(sess)
+
+		if !errors.Is(err, io.EOF) {
+			// 主设备已关闭
+			return
+		}
+		log.Printf("KISS pty客户端已断开: %s", p.slaveName)
+	}
+}
+
+// waitForSlave 等待从设备被打开，ctx取消或主设备关闭时返回false
+// 从设备没有被任何进程打开时，主设备上的poll会返回POLLHUP
+func waitForSlave(ctx context.Context, master *os.File) bool {
+	conn, err := master.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	for {
+		hangup := true
+		err := conn.Control(func(fd uintptr) {
+			fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+			if _, err := unix.Poll(fds, 0); err == nil {
+				hangup = fds[0].Revents&unix.POLLHUP != 0
+			}
+		})
+		if err != nil {
+			return false
+		}
+		if !hangup {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(ptyPollInterval):
+		}
+	}
+}
+
+// ptyConn 将pty主设备包装为会话连接
+// 从设备关闭时读取返回EIO，转换为io.EOF结束会话；关闭会话不关闭主设备
+type ptyConn struct {
+	master *os.File
+}
+
+func (c *ptyConn) Read(b []byte) (int, error) {
+	n, err := c.master.Read(b)
+	if errors.Is(err, syscall.EIO) {
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func (c *ptyConn) Write(b []byte) (int, error) {
+	return c.master.Write(b)
+}
+
+func (c *ptyConn) Close() error {
+	return nil
+}
+
+// openPty 打开pty主设备，设置为原始模式并返回从设备路径
+func openPty() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("打开/dev/ptmx失败: %w", err)
+	}
+
+	conn, err := master.SyscallConn()
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+
+	var slaveName string
+	var ioctlErr error
+	err = conn.Control(func(fd uintptr) {
+		// 解锁从设备
+		if ioctlErr = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); ioctlErr != nil {
+			return
+		}
+
+		var n int
+		if n, ioctlErr = unix.IoctlGetInt(int(fd), unix.TIOCGPTN); ioctlErr != nil {
+			return
+		}
+		slaveName = fmt.Sprintf("/dev/pts/%d", n)
+
+		// KISS是二进制协议，关闭行规程的所有处理
+		var termios *unix.Termios
+		if termios, ioctlErr = unix.IoctlGetTermios(int(fd), unix.TCGETS); ioctlErr != nil {
+			return
+		}
+		makeRaw(termios)
+		ioctlErr = unix.IoctlSetTermios(int(fd), unix.TCSETS, termios)
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		master.Close()
+		return nil, "", fmt.Errorf("设置pty失败: %w", err)
+	}
+
+	return master, slaveName, nil
+}
+
+// makeRaw 设置原始模式，等同于cfmakeraw
+func makeRaw(t *unix.Termios) {
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB
+	t.Cflag |= unix.CS8
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+package kiss
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"aprs_agent/modem"
+)
+
+// readFrame 从从设备读取一个KISS帧
+func readFrame(t *testing.T, f *os.File) Frame {
+	t.Helper()
+	decoder := NewDecoder()
+	buf := make([]byte, 256)
+	f.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			t.Fatalf("读取从设备失败: %v", err)
+		}
+		if frames := decoder.Feed(buf[:n]); len(frames) > 0 {
+			return frames[0]
+		}
+	}
+}
+
+func TestPty(t *testing.T) {
+	m := newFakeModem(1)
+	link := filepath.Join(t.TempDir(), "kisstnc")
+	p := NewPty(m, link)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := p.Start(ctx); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	defer p.Stop()
+
+	if target, err := os.Readlink(link); err != nil || target != p.SlaveName() {
+		t.Fatalf("符号链接错误: %s %v", target, err)
+	}
+
+	// 客户端关闭后重新打开，仍可继续收发
+	for round := 0; round < 2; round++ {
+		slave, err := os.OpenFile(link, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatalf("打开从设备失败: %v", err)
+		}
+		waitFor(t, "客户端连接", p.IsConnected)
+
+		m.receive(modem.Frame{Channel: 0, Data: []byte{0x01, FEND, 0x02}})
+		frame := readFrame(t, slave)
+		if frame.Command != CmdData || !bytes.Equal(frame.Data, []byte{0x01, FEND, 0x02}) {
+			t.Errorf("第 %d 轮收到的帧错误: %+v", round, frame)
+		}
+
+		if _, err := slave.Write(Encode(0, CmdData, []byte("tx"))); err != nil {
+			t.Fatalf("写入从设备失败: %v", err)
+		}
+		waitFor(t, "发送帧", func() bool {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return len(m.sent) == round+1
+		})
+
+		slave.Close()
+		waitFor(t, "客户端断开", func() bool { return !p.IsConnected() })
+	}
+
+	p.Stop()
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("停止后符号链接应被删除")
+	}
+}
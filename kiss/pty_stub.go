@@ -0,0 +1,49 @@
+//go:build !linux
+
+package kiss
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultPtyLink pty从设备的默认符号链接
+const DefaultPtyLink = "/tmp/kisstnc"
+
+// Pty KISS over伪终端，仅在Linux上可用
+type Pty struct {
+	link string
+}
+
+// NewPty 创建KISS pty
+func NewPty(m Modem, link string) *Pty {
+	if link == "" {
+		link = DefaultPtyLink
+	}
+	return &Pty{link: link}
+}
+
+// Start 创建pty对并开始服务
+func (p *Pty) Start(ctx context.Context) error {
+	return fmt.Errorf("KISS pty仅在Linux系统上可用")
+}
+
+// Stop 关闭pty
+func (p *Pty) Stop() error {
+	return nil
+}
+
+// SlaveName 获取pty从设备路径
+func (p *Pty) SlaveName() string {
+	return ""
+}
+
+// Link 获取符号链接路径
+func (p *Pty) Link() string {
+	return p.link
+}
+
+// IsConnected 客户端是否已打开从设备
+func (p *Pty) IsConnected() bool {
+	return false
+}
@@ -0,0 +1,132 @@
+package kiss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+)
+
+// DefaultPort KISS TCP服务默认端口，与Dire Wolf一致
+const DefaultPort = 8001
+
+// Server KISS over TCP服务
+// 每个解码的帧都发送给所有已连接的客户端，客户端发来的数据帧经对应端口的通道发射
+type Server struct {
+	modem    Modem
+	addr     string
+	hub      *hub
+	mu       sync.Mutex
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// NewServer 创建KISS TCP服务
+func NewServer(m Modem, addr string) *Server {
+	s := &Server{
+		modem: m,
+		addr:  addr,
+		hub:   newHub(),
+	}
+	m.AddFrameHandler(s.hub.broadcast)
+	return s
+}
+
+// Start 开始监听，ctx取消时自动停止
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return fmt.Errorf("KISS服务已在运行")
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("KISS服务监听失败: %w", err)
+	}
+	s.listener = listener
+	s.hub.open()
+
+	s.wg.Add(1)
+	go s.acceptLoop(listener)
+
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+
+	log.Printf("KISS TCP服务已启动: %s", listener.Addr())
+	return nil
+}
+
+// Stop 停止监听并断开所有客户端
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	listener := s.listener
+	s.listener = nil
+	s.mu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+
+	err := listener.Close()
+	s.hub.closeAll()
+	s.wg.Wait()
+
+	log.Println("KISS TCP服务已停止")
+	return err
+}
+
+// Addr 获取监听地址，未启动时返回nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// ClientCount 获取已连接的客户端数
+func (s *Server) ClientCount() int {
+	return s.hub.count()
+}
+
+// acceptLoop 接受客户端连接
+func (s *Server) acceptLoop(listener net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("KISS服务接受连接失败: %v", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+// serve 处理一个客户端连接
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+
+	name := conn.RemoteAddr().String()
+	sess := newSession(name, conn, s.modem)
+	if !s.hub.add(sess) {
+		conn.Close()
+		return
+	}
+	defer s.hub.remove(sess)
+
+	log.Printf("KISS客户端已连接: %s", name)
+	sess.run()
+	log.Printf("KISS客户端已断开: %s", name)
+}
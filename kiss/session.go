@@ -0,0 +1,228 @@
+package kiss
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"aprs_agent/audio"
+	"aprs_agent/modem"
+)
+
+// sendQueueSize 每个客户端的发送队列长度，客户端读取过慢时丢弃新帧
+const sendQueueSize = 64
+
+// hardwareName SETHW "TNC:" 查询返回的名称
+const hardwareName = "aprs_agent"
+
+// Modem KISS服务使用的调制解调器接口，由audio.Manager实现
+type Modem interface {
+	AddFrameHandler(handler func(modem.Frame))
+	Transmit(channel int, data []byte) error
+	ChannelCount() int
+	GetTxParams(channel int) (audio.TxParams, error)
+	SetTxParams(channel int, params audio.TxParams) error
+}
+
+// hub 管理所有KISS会话，将接收到的帧分发给每个会话
+type hub struct {
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	closed   bool
+}
+
+func newHub() *hub {
+	return &hub{sessions: make(map[*session]struct{})}
+}
+
+// add 添加会话，hub已关闭时返回false
+func (h *hub) add(s *session) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.sessions[s] = struct{}{}
+	return true
+}
+
+// open 重新允许添加会话
+func (h *hub) open() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = false
+}
+
+func (h *hub) remove(s *session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, s)
+}
+
+func (h *hub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.sessions)
+}
+
+// closeAll 关闭所有会话，之后不再接受新会话
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	h.closed = true
+	sessions := make([]*session, 0, len(h.sessions))
+	for s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+}
+
+// broadcast 将接收到的帧以对应通道的端口号发送给所有会话
+func (h *hub) broadcast(frame modem.Frame) {
+	data := Encode(frame.Channel, CmdData, frame.Data)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.sessions {
+		s.send(data)
+	}
+}
+
+// session 一个KISS连接（TCP客户端或pty）
+type session struct {
+	name      string
+	conn      io.ReadWriteCloser
+	modem     Modem
+	out       chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSession(name string, conn io.ReadWriteCloser, m Modem) *session {
+	return &session{
+		name:  name,
+		conn:  conn,
+		modem: m,
+		out:   make(chan []byte, sendQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// send 将编码后的KISS帧放入发送队列
+func (s *session) send(data []byte) {
+	select {
+	case s.out <- data:
+	case <-s.done:
+	default:
+		log.Printf("KISS %s 发送队列已满，丢弃帧", s.name)
+	}
+}
+
+// close 关闭会话
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+// run 处理会话直到连接关闭，返回读取错误
+func (s *session) run() error {
+	go s.writeLoop()
+	defer s.close()
+
+	decoder := NewDecoder()
+	buf := make([]byte, 1024)
+	for {
+		n, err := s.conn.Read(buf)
+		for _, frame := range decoder.Feed(buf[:n]) {
+			s.handleFrame(frame)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeLoop 将发送队列中的帧写入连接
+func (s *session) writeLoop() {
+	for {
+		select {
+		case data := <-s.out:
+			if _, err := s.conn.Write(data); err != nil {
+				s.close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// handleFrame 处理客户端发来的KISS帧
+func (s *session) handleFrame(frame Frame) {
+	if frame.Command == CmdReturn {
+		return
+	}
+
+	if frame.Port >= s.modem.ChannelCount() {
+		log.Printf("KISS %s 端口 %d 不存在，忽略命令 %d", s.name, frame.Port, frame.Command)
+		return
+	}
+
+	switch frame.Command {
+	case CmdData:
+		if err := s.modem.Transmit(frame.Port, frame.Data); err != nil {
+			log.Printf("KISS %s 端口 %d 发送失败: %v", s.name, frame.Port, err)
+		}
+	case CmdTxDelay, CmdPersistence, CmdSlotTime, CmdTxTail, CmdFullDuplex:
+		if len(frame.Data) < 1 {
+			return
+		}
+		s.setParam(frame.Port, frame.Command, frame.Data[0])
+	case CmdSetHardware:
+		s.setHardware(frame.Port, string(frame.Data))
+	default:
+		log.Printf("KISS %s 未知命令: %d", s.name, frame.Command)
+	}
+}
+
+// setParam 将KISS参数命令映射到通道的发射参数
+func (s *session) setParam(port int, command byte, value byte) {
+	params, err := s.modem.GetTxParams(port)
+	if err != nil {
+		log.Printf("KISS %s 获取端口 %d 发射参数失败: %v", s.name, port, err)
+		return
+	}
+
+	switch command {
+	case CmdTxDelay:
+		params.TxDelay = time.Duration(value) * 10 * time.Millisecond
+	case CmdPersistence:
+		params.Persistence = int(value)
+	case CmdSlotTime:
+		params.SlotTime = time.Duration(value) * 10 * time.Millisecond
+	case CmdTxTail:
+		params.TxTail = time.Duration(value) * 10 * time.Millisecond
+	case CmdFullDuplex:
+		params.FullDuplex = value != 0
+	}
+
+	if err := s.modem.SetTxParams(port, params); err != nil {
+		log.Printf("KISS %s 设置端口 %d 发射参数失败: %v", s.name, port, err)
+	}
+}
+
+// setHardware 处理SETHW命令，目前只支持 "TNC:" 查询
+func (s *session) setHardware(port int, data string) {
+	if strings.HasPrefix(strings.ToUpper(data), "TNC:") {
+		s.send(Encode(port, CmdSetHardware, []byte("TNC:"+hardwareName)))
+		return
+	}
+	log.Printf("KISS %s 不支持的SETHW命令: %q", s.name, data)
+}
@@ -4,12 +4,25 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
+	"aprs_agent/agw"
+	"aprs_agent/aprs"
 	"aprs_agent/audio"
+	"aprs_agent/ax25"
+	"aprs_agent/beacon"
 	"aprs_agent/config"
+	"aprs_agent/digi"
+	"aprs_agent/gps"
+	"aprs_agent/igate"
+	"aprs_agent/kiss"
+	"aprs_agent/modem"
 )
 
 func main() {
@@ -31,6 +44,37 @@ func main() {
 		audioManager.ListDevices()
 	}
 
+	// 记录接收到的数据包
+	audioManager.AddFrameHandler(func(f modem.Frame) {
+		frame, err := ax25.Decode(f.Data)
+		if err != nil {
+			log.Printf("[%d] 无法解析AX.25帧: %v", f.Channel, err)
+			return
+		}
+		meta := fmt.Sprintf("%.1fdB", f.Level)
+		if f.Frequency > 0 {
+			meta += fmt.Sprintf(", %.4fMHz", float64(f.Frequency)/1e6)
+		}
+		if f.HasStrength {
+			meta += fmt.Sprintf(", S表 %+ddB", f.Strength)
+		}
+		log.Printf("[%d] %s (%s)", f.Channel, frame, meta)
+
+		if !frame.IsUI() {
+			return
+		}
+		packet, err := aprs.Parse(frame)
+		if err != nil {
+			log.Printf("[%d] %v", f.Channel, err)
+			return
+		}
+		if packet.Position != nil {
+			log.Printf("[%d] %s %s: %.5f,%.5f %c%c %s", f.Channel, packet.Source, packet.Type,
+				packet.Position.Latitude, packet.Position.Longitude,
+				packet.Position.SymbolTable, packet.Position.SymbolCode, packet.Comment)
+		}
+	})
+
 	// 启动音频流
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -45,6 +89,129 @@ func main() {
 		log.Fatalf("启动音频输出失败: %v", err)
 	}
 
+	// 启动KISS TCP服务
+	if cfg.KISS.Enabled {
+		kissServer := kiss.NewServer(audioManager, fmt.Sprintf(":%d", cfg.KISS.Port))
+		if err := kissServer.Start(ctx); err != nil {
+			log.Fatalf("启动KISS服务失败: %v", err)
+		}
+		defer kissServer.Stop()
+	}
+
+	// 启动KISS伪终端
+	if cfg.KISS.PtyEnabled {
+		kissPty := kiss.NewPty(audioManager, cfg.KISS.PtyLink)
+		if err := kissPty.Start(ctx); err != nil {
+			log.Fatalf("启动KISS伪终端失败: %v", err)
+		}
+		defer kissPty.Stop()
+	}
+
+	// 启动AGWPE服务
+	if cfg.AGW.Enabled {
+		agwServer := agw.NewServer(audioManager, fmt.Sprintf(":%d", cfg.AGW.Port))
+		if err := agwServer.Start(ctx); err != nil {
+			log.Fatalf("启动AGW服务失败: %v", err)
+		}
+		defer agwServer.Stop()
+	}
+
+	// 启动iGate
+	var isClient *igate.Client
+	if cfg.IGate.Enabled {
+		isClient = igate.NewClient(igate.ClientConfig{
+			Server:   net.JoinHostPort(cfg.IGate.Server, strconv.Itoa(cfg.IGate.Port)),
+			Callsign: cfg.IGate.Callsign,
+			Passcode: cfg.IGate.Passcode,
+			Filter:   cfg.IGate.Filter,
+		})
+		ig := igate.New(audioManager, isClient, igate.Config{
+			TxEnabled:     cfg.IGate.TxEnabled,
+			TxPath:        cfg.GetIGateTxPath(),
+			HeardWindow:   time.Duration(cfg.IGate.HeardMinutes) * time.Minute,
+			MaxPerMinute:  cfg.IGate.TxLimitMinute,
+			MaxPerStation: cfg.IGate.TxLimitStation,
+		})
+		if err := ig.Start(ctx); err != nil {
+			log.Fatalf("启动iGate失败: %v", err)
+		}
+		defer ig.Stop()
+		if err := isClient.Start(ctx); err != nil {
+			log.Fatalf("启动iGate失败: %v", err)
+		}
+		defer isClient.Stop()
+	}
+
+	// 启动中继器
+	if cfg.Digipeater.Enabled {
+		myCall, err := ax25.ParseAddress(cfg.Digipeater.Callsign)
+		if err != nil {
+			log.Fatalf("中继器呼号无效: %v", err)
+		}
+		preempt, err := digi.ParsePreempt(cfg.Digipeater.Preempt)
+		if err != nil {
+			log.Fatalf("中继器配置无效: %v", err)
+		}
+		var routes []digi.Route
+		for _, rc := range cfg.GetDigipeaterRoutes() {
+			route, err := digi.NewRoute(rc.From, rc.To, rc.PathContains, rc.SourceRegex)
+			if err != nil {
+				log.Fatalf("中继路由无效: %v", err)
+			}
+			log.Printf("中继路由: %s", route)
+			routes = append(routes, route)
+		}
+		digi.New(audioManager, digi.Config{
+			MyCall:       myCall,
+			Aliases:      cfg.GetDigipeaterAliases(),
+			FillIn:       cfg.Digipeater.FillIn,
+			MaxHops:      cfg.Digipeater.MaxHops,
+			DedupeWindow: cfg.GetDedupeWindow(),
+			Preempt:      preempt,
+			Routes:       routes,
+		})
+		log.Printf("中继器已启用: %s", myCall)
+	}
+
+	// 启动GPS
+	var gpsReceiver *gps.Receiver
+	switch cfg.GPS.Type {
+	case config.GPSNMEA:
+		gpsReceiver = gps.NewSerial(cfg.GPS.Device, cfg.GPS.Baud)
+	case config.GPSD:
+		gpsReceiver = gps.NewGPSD(cfg.GPS.Address)
+	}
+	if gpsReceiver != nil {
+		if err := gpsReceiver.Start(ctx); err != nil {
+			log.Fatalf("启动GPS失败: %v", err)
+		}
+		defer gpsReceiver.Stop()
+		audioManager.SetPositionSource(gpsReceiver.Fix)
+	}
+
+	// 启动信标
+	if beaconConfigs := cfg.GetBeaconConfigs(); len(beaconConfigs) > 0 {
+		beacons, err := newBeacons(cfg, beaconConfigs)
+		if err != nil {
+			log.Fatalf("信标配置无效: %v", err)
+		}
+		// 未启用iGate时传入nil接口，而不是nil指针
+		var client beacon.ISClient
+		if isClient != nil {
+			client = isClient
+		}
+		beaconScheduler := beacon.New(audioManager, client, beacons)
+		if gpsReceiver != nil {
+			beaconScheduler.SetPositionSource(func() (beacon.Fix, bool) {
+				fix, ok := gpsReceiver.Fix()
+				return beaconFix(fix), ok
+			})
+		}
+		beaconScheduler.Start(ctx)
+		defer beaconScheduler.Stop()
+		log.Printf("已启用 %d 个信标", len(beacons))
+	}
+
 	fmt.Println("音频系统已启动，按 Ctrl+C 退出...")
 
 	// 等待中断信号
@@ -55,3 +222,90 @@ func main() {
 	fmt.Println("\n正在关闭音频系统...")
 	cancel()
 }
+
+// newBeacons 将信标配置转换为信标定义
+func newBeacons(cfg *config.Config, configs []config.BeaconConfig) ([]beacon.Beacon, error) {
+	channels := cfg.GetChannelConfigs()
+	beacons := make([]beacon.Beacon, 0, len(configs))
+	for i, bc := range configs {
+		b := beacon.Beacon{
+			Type:      bc.Type,
+			Channel:   bc.Channel,
+			APRSIS:    bc.APRSIS,
+			Source:    bc.Callsign,
+			Interval:  bc.GetInterval(),
+			Delay:     bc.GetDelay(),
+			UseGPS:    bc.GPS,
+			Messaging: bc.Messaging,
+			Comment:   bc.Comment,
+			Object:    bc.ObjectName,
+			Raw:       bc.Raw,
+		}
+		// 未设置呼号时使用通道呼号，发送到APRS-IS时使用iGate呼号
+		if b.Source == "" {
+			if bc.APRSIS {
+				b.Source = cfg.IGate.Callsign
+			} else if bc.Channel < len(channels) {
+				b.Source = channels[bc.Channel].Callsign
+			}
+		}
+
+		slot, hasSlot, err := bc.GetSlot()
+		if err != nil {
+			return nil, fmt.Errorf("信标 %d: %w", i, err)
+		}
+		b.Slot, b.HasSlot = slot, hasSlot
+
+		for _, hop := range bc.GetPath() {
+			addr, err := ax25.ParseAddress(hop)
+			if err != nil {
+				return nil, fmt.Errorf("信标 %d: 中继路径无效: %w", i, err)
+			}
+			b.Path = append(b.Path, addr)
+		}
+
+		if len(bc.Symbol) == 2 {
+			b.Position.SymbolTable, b.Position.SymbolCode = bc.Symbol[0], bc.Symbol[1]
+		}
+		b.Position.Latitude, b.Position.Longitude = bc.Latitude, bc.Longitude
+		b.Position.Compressed = bc.Compressed
+		if bc.Altitude != 0 {
+			b.Position.HasAltitude, b.Position.Altitude = true, bc.Altitude
+		}
+		if bc.Power > 0 {
+			b.Position.PHG = &aprs.PHG{Power: bc.Power, Height: bc.Height, Gain: bc.Gain, Directivity: bc.Dir}
+		}
+		if bc.Smart {
+			sb := cfg.SmartBeaconing
+			b.Smart = &beacon.SmartBeaconing{
+				FastSpeed: sb.FastSpeed,
+				FastRate:  sb.GetFastRate(),
+				SlowSpeed: sb.SlowSpeed,
+				SlowRate:  sb.GetSlowRate(),
+				TurnAngle: sb.TurnAngle,
+				TurnSlope: sb.TurnSlope,
+				TurnTime:  sb.GetTurnTime(),
+			}
+		}
+		beacons = append(beacons, b)
+	}
+	return beacons, nil
+}
+
+// beaconFix 将GPS定位转换为信标使用的定位
+// APRS中航向0表示未知，移动中的正北航向记为360
+func beaconFix(fix gps.Fix) beacon.Fix {
+	course := int(math.Round(fix.Course))
+	if course == 0 && fix.Speed > 0 {
+		course = 360
+	}
+	return beacon.Fix{
+		Latitude:       fix.Latitude,
+		Longitude:      fix.Longitude,
+		HasAltitude:    fix.HasAltitude,
+		Altitude:       fix.Altitude,
+		HasCourseSpeed: fix.HasCourseSpeed,
+		Course:         course,
+		Speed:          fix.Speed,
+	}
+}
@@ -0,0 +1,227 @@
+package modem
+
+import (
+	"encoding/binary"
+	"math"
+	"sync/atomic"
+)
+
+// Bell 202 AFSK参数
+const (
+	MarkFreq  = 1200.0 // 传号频率 (Hz)
+	SpaceFreq = 2200.0 // 空号频率 (Hz)
+	BaudRate  = 1200   // 波特率
+)
+
+// 数字锁相环参数
+const (
+	pllLockedInertia    = 0.74 // 已锁定时的相位惯性
+	pllSearchingInertia = 0.50 // 搜索时的相位惯性
+)
+
+// dcdHoldBits 载波检测的保持时间 (码元数)
+// 经过比特填充的HDLC数据最多连续7个码元没有跳变，超过后认为载波消失
+const dcdHoldBits = 8
+
+// toneDetector 单音正交相关检测器
+// 将输入与本地振荡器混频后在一个码元周期内积分，得到该频率的能量
+type toneDetector struct {
+	step  float64 // 每个采样的相位增量
+	phase float64
+
+	iBuf []float64
+	qBuf []float64
+	iSum float64
+	qSum float64
+	pos  int
+}
+
+// newToneDetector 创建单音检测器
+func newToneDetector(freq float64, sampleRate int, window int) *toneDetector {
+	return &toneDetector{
+		step: 2 * math.Pi * freq / float64(sampleRate),
+		iBuf: make([]float64, window),
+		qBuf: make([]float64, window),
+	}
+}
+
+// process 处理一个采样，返回当前窗口内的能量
+func (t *toneDetector) process(x float64) float64 {
+	i := x * math.Cos(t.phase)
+	q := x * math.Sin(t.phase)
+
+	t.phase += t.step
+	if t.phase >= 2*math.Pi {
+		t.phase -= 2 * math.Pi
+	}
+
+	// 滑动窗口积分
+	t.iSum += i - t.iBuf[t.pos]
+	t.qSum += q - t.qBuf[t.pos]
+	t.iBuf[t.pos] = i
+	t.qBuf[t.pos] = q
+	t.pos++
+	if t.pos == len(t.iBuf) {
+		t.pos = 0
+	}
+
+	return t.iSum*t.iSum + t.qSum*t.qSum
+}
+
+// reset 清除检测器状态
+func (t *toneDetector) reset() {
+	t.phase = 0
+	t.iSum, t.qSum = 0, 0
+	t.pos = 0
+	for j := range t.iBuf {
+		t.iBuf[j] = 0
+		t.qBuf[j] = 0
+	}
+}
+
+// Demodulator Bell 202 AFSK 1200波特解调器
+// 完成音调判别、数字锁相环时钟恢复和NRZI解码，输出比特流
+type Demodulator struct {
+	sampleRate int
+
+	mark  *toneDetector
+	space *toneDetector
+
+	// 时钟恢复
+	pll      int32
+	pllStep  int32
+	level    bool // 当前解调电平 (true=传号)
+	lastBit  bool // 上一个采样时刻的电平，用于NRZI解码
+	inertia  float64
+	transOK  int // 近期落在码元边界附近的跳变计数
+	transBad int
+	quiet    int         // 上次跳变后经过的码元数
+	dcd      atomic.Bool // 载波检测状态，可在其他goroutine读取
+
+	callback func(bit byte)
+}
+
+// NewDemodulator 创建新的解调器
+func NewDemodulator(sampleRate int) *Demodulator {
+	window := int(math.Round(float64(sampleRate) / BaudRate))
+	if window < 1 {
+		window = 1
+	}
+
+	return &Demodulator{
+		sampleRate: sampleRate,
+		mark:       newToneDetector(MarkFreq, sampleRate, window),
+		space:      newToneDetector(SpaceFreq, sampleRate, window),
+		pllStep:    int32(math.Round(float64(BaudRate) / float64(sampleRate) * 4294967296.0)),
+		inertia:    pllSearchingInertia,
+	}
+}
+
+// SetBitCallback 设置比特输出回调，每恢复一个NRZI解码后的比特调用一次
+func (d *Demodulator) SetBitCallback(callback func(bit byte)) {
+	d.callback = callback
+}
+
+// GetSampleRate 获取解调器采样率
+func (d *Demodulator) GetSampleRate() int {
+	return d.sampleRate
+}
+
+// ProcessInt16 处理16位小端单声道PCM数据
+func (d *Demodulator) ProcessInt16(data []byte) {
+	for j := 0; j+1 < len(data); j += 2 {
+		sample := int16(binary.LittleEndian.Uint16(data[j:]))
+		d.processSample(float64(sample) / 32768.0)
+	}
+}
+
+// ProcessSamples 处理归一化到[-1, 1]的采样
+func (d *Demodulator) ProcessSamples(samples []float64) {
+	for _, sample := range samples {
+		d.processSample(sample)
+	}
+}
+
+// processSample 处理单个采样
+func (d *Demodulator) processSample(x float64) {
+	markEnergy := d.mark.process(x)
+	spaceEnergy := d.space.process(x)
+
+	level := markEnergy > spaceEnergy
+
+	// 推进锁相环，相位从正溢出到负时为码元中心
+	prev := d.pll
+	d.pll += d.pllStep
+	if prev > 0 && d.pll < 0 {
+		d.sampleBit()
+	}
+
+	// 电平跳变时向码元边界(相位0)收敛
+	if level != d.level {
+		d.quiet = 0
+		d.trackTransition()
+		d.pll = int32(float64(d.pll) * d.inertia)
+	}
+	d.level = level
+}
+
+// trackTransition 统计跳变位置，用于判断锁相环是否锁定
+func (d *Demodulator) trackTransition() {
+	// 跳变应出现在相位0附近，偏离超过四分之一码元视为不良跳变
+	if d.pll > -(1<<30) && d.pll < (1<<30) {
+		d.transOK++
+	} else {
+		d.transBad++
+	}
+
+	if d.transOK+d.transBad >= 16 {
+		if d.transOK >= 12 {
+			d.inertia = pllLockedInertia
+		} else {
+			d.inertia = pllSearchingInertia
+		}
+		d.transOK, d.transBad = 0, 0
+	}
+}
+
+// sampleBit 在码元中心采样并进行NRZI解码：电平不变为1，电平翻转为0
+func (d *Demodulator) sampleBit() {
+	var bit byte
+	if d.level == d.lastBit {
+		bit = 1
+	}
+	d.lastBit = d.level
+
+	if d.quiet < dcdHoldBits {
+		d.quiet++
+	}
+	d.dcd.Store(d.IsLocked() && d.quiet < dcdHoldBits)
+
+	if d.callback != nil {
+		d.callback(bit)
+	}
+}
+
+// IsLocked 锁相环是否已锁定到码元时钟
+func (d *Demodulator) IsLocked() bool {
+	return d.inertia == pllLockedInertia
+}
+
+// DCD 是否检测到载波：锁相环已锁定且近期有码元跳变
+// 可在处理音频的goroutine之外调用
+func (d *Demodulator) DCD() bool {
+	return d.dcd.Load()
+}
+
+// Reset 重置解调器状态
+func (d *Demodulator) Reset() {
+	d.mark.reset()
+	d.space.reset()
+	d.pll = 0
+	d.level = false
+	d.lastBit = false
+	d.inertia = pllSearchingInertia
+	d.transOK, d.transBad = 0, 0
+	d.quiet = 0
+	d.dcd.Store(false)
+}
@@ -0,0 +1,143 @@
+package modem
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// synthesizeAFSK 生成NRZI编码的AFSK测试信号
+func synthesizeAFSK(bits []byte, sampleRate int, amplitude float64) []float64 {
+	var samples []float64
+	mark := true
+	phase := 0.0
+	samplesPerBit := float64(sampleRate) / BaudRate
+	acc := 0.0
+
+	for _, bit := range bits {
+		// NRZI: 0翻转音调，1保持
+		if bit == 0 {
+			mark = !mark
+		}
+		freq := SpaceFreq
+		if mark {
+			freq = MarkFreq
+		}
+
+		acc += samplesPerBit
+		for acc >= 1 {
+			samples = append(samples, amplitude*math.Sin(phase))
+			phase += 2 * math.Pi * freq / float64(sampleRate)
+			acc--
+		}
+	}
+	return samples
+}
+
+// flagBits 生成指定数量的HDLC标志比特 (0x7E, 低位先发)
+func flagBits(count int) []byte {
+	var bits []byte
+	for i := 0; i < count; i++ {
+		bits = append(bits, 0, 1, 1, 1, 1, 1, 1, 0)
+	}
+	return bits
+}
+
+func bitString(bits []byte) string {
+	var sb strings.Builder
+	for _, b := range bits {
+		sb.WriteByte('0' + b)
+	}
+	return sb.String()
+}
+
+func TestDemodulatorSampleRates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	payload := make([]byte, 400)
+	for i := range payload {
+		payload[i] = byte(rng.Intn(2))
+	}
+
+	var bits []byte
+	bits = append(bits, flagBits(30)...)
+	bits = append(bits, payload...)
+	bits = append(bits, flagBits(4)...)
+
+	for _, sampleRate := range []int{8000, 11025, 22050, 44100, 48000} {
+		t.Run(fmt.Sprintf("%dHz", sampleRate), func(t *testing.T) {
+			var decoded []byte
+			demod := NewDemodulator(sampleRate)
+			demod.SetBitCallback(func(bit byte) {
+				decoded = append(decoded, bit)
+			})
+
+			demod.ProcessSamples(synthesizeAFSK(bits, sampleRate, 0.5))
+
+			if !strings.Contains(bitString(decoded), bitString(payload)) {
+				t.Error("解调结果中未找到发送的比特序列")
+			}
+			if !demod.IsLocked() {
+				t.Error("锁相环未锁定")
+			}
+		})
+	}
+}
+
+func TestDemodulatorInt16(t *testing.T) {
+	payload := []byte{1, 0, 1, 1, 0, 0, 1, 0, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1, 1, 0, 1, 0, 1, 0}
+
+	var bits []byte
+	bits = append(bits, flagBits(20)...)
+	bits = append(bits, payload...)
+	bits = append(bits, flagBits(2)...)
+
+	samples := synthesizeAFSK(bits, 8000, 0.3)
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		v := int16(s * 32767)
+		pcm[2*i] = byte(v)
+		pcm[2*i+1] = byte(v >> 8)
+	}
+
+	var decoded []byte
+	demod := NewDemodulator(8000)
+	demod.SetBitCallback(func(bit byte) {
+		decoded = append(decoded, bit)
+	})
+	demod.ProcessInt16(pcm)
+
+	if !strings.Contains(bitString(decoded), bitString(payload)) {
+		t.Errorf("16位PCM解调结果中未找到发送的比特序列")
+	}
+}
+
+func TestDemodulatorDCD(t *testing.T) {
+	for _, sampleRate := range []int{8000, 44100, 48000} {
+		t.Run(fmt.Sprintf("%dHz", sampleRate), func(t *testing.T) {
+			demod := NewDemodulator(sampleRate)
+			if demod.DCD() {
+				t.Fatal("未收到信号时不应检测到载波")
+			}
+
+			// 标志序列锁定后检测到载波
+			demod.ProcessSamples(synthesizeAFSK(flagBits(20), sampleRate, 0.5))
+			if !demod.DCD() {
+				t.Error("收到标志序列时应检测到载波")
+			}
+
+			// 信号消失超过保持时间后载波消失
+			demod.ProcessSamples(make([]float64, sampleRate/BaudRate*(dcdHoldBits+4)))
+			if demod.DCD() {
+				t.Error("信号消失后不应检测到载波")
+			}
+
+			demod.ProcessSamples(synthesizeAFSK(flagBits(20), sampleRate, 0.5))
+			demod.Reset()
+			if demod.DCD() {
+				t.Error("重置后不应检测到载波")
+			}
+		})
+	}
+}
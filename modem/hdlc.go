@@ -0,0 +1,295 @@
+package modem
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// HDLC帧长度限制（不含FCS）
+const (
+	DefaultMinFrameLen = 15  // 目的地址+源地址+控制字段
+	DefaultMaxFrameLen = 330 // 10个地址+控制+PID+256字节信息字段
+)
+
+// HDLC特殊比特模式
+const (
+	hdlcFlag  = 0x7E // 帧标志 01111110
+	hdlcAbort = 0xFE // 连续7个1表示中止
+)
+
+// Frame 经过FCS校验的原始AX.25帧及其接收元数据
+type Frame struct {
+	Data      []byte    // 帧内容（不含FCS）
+	Channel   int       // 接收通道
+	Level     float64   // 接收时的音频电平 (dB)
+	Timestamp time.Time // 接收时间
+
+	// 通道使用rigctld时由电台读取，否则为零值
+	Frequency   int64 // 接收频率 (Hz)
+	Strength    int   // S表读数，相对S9的dB
+	HasStrength bool  // Strength是否有效
+}
+
+// DeframerStats HDLC解帧统计信息
+type DeframerStats struct {
+	Frames    uint64 // 校验通过的帧数
+	FCSErrors uint64 // FCS校验失败的帧数
+	Aborts    uint64 // 收到中止序列的次数
+	TooShort  uint64 // 长度不足被丢弃的帧数
+	TooLong   uint64 // 超长被丢弃的帧数
+	Unaligned uint64 // 比特数不是8的整数倍的帧数
+}
+
+// Deframer HDLC解帧器
+// 完成标志检测、比特去填充、中止检测和CRC-16-CCITT校验
+type Deframer struct {
+	mu sync.Mutex
+
+	channel int
+	minLen  int
+	maxLen  int
+
+	pattern  byte   // 最近收到的8个比特，用于检测标志和中止
+	acc      byte   // 当前正在组装的字节
+	bitCount int    // 自上一个标志以来接收的有效比特数，-1表示等待标志
+	buf      []byte // 当前帧数据
+	overflow bool   // 当前帧已超长
+
+	levelFunc func() float64
+	callback  func(Frame)
+	stats     DeframerStats
+}
+
+// NewDeframer 创建新的HDLC解帧器
+func NewDeframer(channel int) *Deframer {
+	return &Deframer{
+		channel:  channel,
+		minLen:   DefaultMinFrameLen,
+		maxLen:   DefaultMaxFrameLen,
+		bitCount: -1,
+		buf:      make([]byte, 0, DefaultMaxFrameLen+2),
+	}
+}
+
+// SetCallback 设置帧输出回调
+func (d *Deframer) SetCallback(callback func(Frame)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.callback = callback
+}
+
+// SetLevelFunc 设置获取当前音频电平的函数，用于填充帧元数据
+func (d *Deframer) SetLevelFunc(levelFunc func() float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.levelFunc = levelFunc
+}
+
+// SetFrameLimits 设置帧长度限制（不含FCS）
+func (d *Deframer) SetFrameLimits(minLen, maxLen int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.minLen = minLen
+	d.maxLen = maxLen
+}
+
+// ProcessBit 处理解调器输出的一个比特
+func (d *Deframer) ProcessBit(bit byte) {
+	d.mu.Lock()
+	frame, ok := d.processBit(bit)
+	callback := d.callback
+	d.mu.Unlock()
+
+	if ok && callback != nil {
+		callback(frame)
+	}
+}
+
+// processBit 处理单个比特，完成一帧时返回该帧
+func (d *Deframer) processBit(bit byte) (Frame, bool) {
+	d.pattern >>= 1
+	if bit != 0 {
+		d.pattern |= 0x80
+	}
+
+	switch {
+	case d.pattern == hdlcFlag:
+		// 标志的前7个比特已被当作数据累加，帧恰好结束时比特数余7
+		frame, ok := d.finishFrame()
+		d.startFrame()
+		return frame, ok
+
+	case d.pattern == hdlcAbort:
+		if d.bitCount > 0 && len(d.buf) > 0 {
+			d.stats.Aborts++
+		}
+		d.bitCount = -1
+		d.buf = d.buf[:0]
+		return Frame{}, false
+
+	case d.pattern&0xFC == 0x7C:
+		// 连续5个1后的0为填充比特，丢弃
+		return Frame{}, false
+	}
+
+	if d.bitCount < 0 || d.overflow {
+		return Frame{}, false
+	}
+
+	d.acc >>= 1
+	if bit != 0 {
+		d.acc |= 0x80
+	}
+	d.bitCount++
+
+	if d.bitCount%8 == 0 {
+		if len(d.buf) >= d.maxLen+2 {
+			d.overflow = true
+			d.stats.TooLong++
+			return Frame{}, false
+		}
+		d.buf = append(d.buf, d.acc)
+	}
+
+	return Frame{}, false
+}
+
+// startFrame 在标志之后开始新的帧
+func (d *Deframer) startFrame() {
+	d.bitCount = 0
+	d.acc = 0
+	d.buf = d.buf[:0]
+	d.overflow = false
+}
+
+// finishFrame 在收到结束标志时校验当前帧
+func (d *Deframer) finishFrame() (Frame, bool) {
+	// 连续标志之间没有数据
+	if d.bitCount < 0 || d.overflow || len(d.buf) == 0 {
+		return Frame{}, false
+	}
+
+	if d.bitCount%8 != 7 {
+		d.stats.Unaligned++
+		return Frame{}, false
+	}
+
+	if len(d.buf) < d.minLen+2 {
+		d.stats.TooShort++
+		return Frame{}, false
+	}
+
+	n := len(d.buf) - 2
+	received := uint16(d.buf[n]) | uint16(d.buf[n+1])<<8
+	if ComputeFCS(d.buf[:n]) != received {
+		d.stats.FCSErrors++
+		return Frame{}, false
+	}
+
+	d.stats.Frames++
+
+	data := make([]byte, n)
+	copy(data, d.buf[:n])
+
+	frame := Frame{
+		Data:      data,
+		Channel:   d.channel,
+		Level:     -96.0,
+		Timestamp: time.Now(),
+	}
+	if d.levelFunc != nil {
+		frame.Level = d.levelFunc()
+	}
+	return frame, true
+}
+
+// GetStats 获取解帧统计信息
+func (d *Deframer) GetStats() DeframerStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// ResetStats 重置解帧统计信息
+func (d *Deframer) ResetStats() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats = DeframerStats{}
+}
+
+// ComputeFCS 计算AX.25帧校验序列 (CRC-16-CCITT，反射多项式0x8408)
+func ComputeFCS(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc ^ 0xFFFF
+}
+
+// EncodeHDLC 将帧编码为待发送的比特序列（未经NRZI）
+// 包含前导标志、附加FCS并进行比特填充的帧内容，以及尾部标志
+func EncodeHDLC(data []byte, preambleFlags, tailFlags int) []byte {
+	bits := make([]byte, 0, (preambleFlags+tailFlags+1)*8+(len(data)+2)*10)
+	bits = appendFlags(bits, preambleFlags)
+
+	fcs := ComputeFCS(data)
+	ones := 0
+	appendByte := func(b byte) {
+		for i := 0; i < 8; i++ {
+			bit := (b >> i) & 1
+			bits = append(bits, bit)
+			if bit == 0 {
+				ones = 0
+				continue
+			}
+			// 连续5个1后插入填充比特0
+			ones++
+			if ones == 5 {
+				bits = append(bits, 0)
+				ones = 0
+			}
+		}
+	}
+
+	for _, b := range data {
+		appendByte(b)
+	}
+	appendByte(byte(fcs))
+	appendByte(byte(fcs >> 8))
+
+	// 至少一个结束标志
+	if tailFlags < 1 {
+		tailFlags = 1
+	}
+	return appendFlags(bits, tailFlags)
+}
+
+// appendFlags 追加指定数量的标志比特 (0x7E，低位先发)
+func appendFlags(bits []byte, count int) []byte {
+	for i := 0; i < count; i++ {
+		for j := 0; j < 8; j++ {
+			bits = append(bits, (hdlcFlag>>j)&1)
+		}
+	}
+	return bits
+}
+
+// FrameDuration 估算帧的发射时长：前导标志、按最坏情况比特填充的帧内容和FCS以及一个结束标志
+func FrameDuration(dataLen, preambleFlags int) time.Duration {
+	bits := (preambleFlags+1)*8 + (dataLen+2)*8*6/5
+	return time.Duration(float64(bits) / BaudRate * float64(time.Second))
+}
+
+// FlagsForDuration 计算填充指定时长所需的标志数量
+func FlagsForDuration(d time.Duration) int {
+	bits := d.Seconds() * BaudRate
+	return int(math.Ceil(bits / 8))
+}
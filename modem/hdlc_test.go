@@ -0,0 +1,153 @@
+package modem
+
+import (
+	"bytes"
+	"testing"
+)
+
+// stuffFrameBits 生成带FCS和比特填充的帧比特（不含标志）
+func stuffFrameBits(data []byte, fcsOK bool) []byte {
+	fcs := ComputeFCS(data)
+	if !fcsOK {
+		fcs ^= 0x0001
+	}
+	full := append(append([]byte{}, data...), byte(fcs), byte(fcs>>8))
+
+	var bits []byte
+	ones := 0
+	for _, b := range full {
+		for i := 0; i < 8; i++ {
+			bit := (b >> i) & 1
+			bits = append(bits, bit)
+			if bit == 1 {
+				ones++
+				if ones == 5 {
+					bits = append(bits, 0)
+					ones = 0
+				}
+			} else {
+				ones = 0
+			}
+		}
+	}
+	return bits
+}
+
+func testFrameData() []byte {
+	// N0CALL-9>APRS: >test (地址字段已左移一位)
+	data := []byte{'A' << 1, 'P' << 1, 'R' << 1, 'S' << 1, ' ' << 1, ' ' << 1, 0x60}
+	data = append(data, 'N'<<1, '0'<<1, 'C'<<1, 'A'<<1, 'L'<<1, 'L'<<1, 0x60|(9<<1)|1)
+	data = append(data, 0x03, 0xF0)
+	data = append(data, []byte(">test ~~~~~ \xff\xff")...)
+	return data
+}
+
+func feedBits(d *Deframer, bits []byte) {
+	for _, bit := range bits {
+		d.ProcessBit(bit)
+	}
+}
+
+func TestComputeFCS(t *testing.T) {
+	// CRC-16/X.25 标准校验值
+	if got := ComputeFCS([]byte("123456789")); got != 0x906E {
+		t.Errorf("ComputeFCS() = %04X, want 906E", got)
+	}
+}
+
+func TestDeframerValidFrame(t *testing.T) {
+	data := testFrameData()
+
+	var frames []Frame
+	d := NewDeframer(2)
+	d.SetLevelFunc(func() float64 { return -12.5 })
+	d.SetCallback(func(f Frame) { frames = append(frames, f) })
+
+	feedBits(d, []byte{1, 0, 1, 1, 0})
+	feedBits(d, flagBits(3))
+	feedBits(d, stuffFrameBits(data, true))
+	feedBits(d, flagBits(2))
+
+	if len(frames) != 1 {
+		t.Fatalf("期望解出1帧，实际为 %d", len(frames))
+	}
+	if !bytes.Equal(frames[0].Data, data) {
+		t.Errorf("帧内容不一致: %X", frames[0].Data)
+	}
+	if frames[0].Channel != 2 || frames[0].Level != -12.5 || frames[0].Timestamp.IsZero() {
+		t.Errorf("帧元数据错误: %+v", frames[0])
+	}
+	if stats := d.GetStats(); stats.Frames != 1 || stats.FCSErrors != 0 {
+		t.Errorf("统计信息错误: %+v", stats)
+	}
+}
+
+func TestDeframerErrors(t *testing.T) {
+	data := testFrameData()
+
+	tests := []struct {
+		name  string
+		bits  []byte
+		check func(DeframerStats) bool
+	}{
+		{
+			name:  "FCS错误",
+			bits:  append(append(flagBits(2), stuffFrameBits(data, false)...), flagBits(1)...),
+			check: func(s DeframerStats) bool { return s.FCSErrors == 1 },
+		},
+		{
+			name:  "中止序列",
+			bits:  append(append(flagBits(2), stuffFrameBits(data, true)[:60]...), 1, 1, 1, 1, 1, 1, 1, 1),
+			check: func(s DeframerStats) bool { return s.Aborts == 1 },
+		},
+		{
+			name:  "帧过短",
+			bits:  append(append(flagBits(2), stuffFrameBits(data[:8], true)...), flagBits(1)...),
+			check: func(s DeframerStats) bool { return s.TooShort == 1 },
+		},
+		{
+			name:  "帧过长",
+			bits:  append(append(flagBits(2), stuffFrameBits(bytes.Repeat([]byte{0x55}, 400), true)...), flagBits(1)...),
+			check: func(s DeframerStats) bool { return s.TooLong == 1 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := 0
+			d := NewDeframer(0)
+			d.SetCallback(func(Frame) { received++ })
+			feedBits(d, tt.bits)
+
+			if received != 0 {
+				t.Errorf("不应输出帧，实际输出 %d 帧", received)
+			}
+			if stats := d.GetStats(); !tt.check(stats) {
+				t.Errorf("统计信息错误: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestDemodulatorToDeframer(t *testing.T) {
+	data := testFrameData()
+
+	var bits []byte
+	bits = append(bits, flagBits(25)...)
+	bits = append(bits, stuffFrameBits(data, true)...)
+	bits = append(bits, flagBits(3)...)
+
+	for _, sampleRate := range []int{8000, 44100, 48000} {
+		var frames []Frame
+		d := NewDeframer(0)
+		d.SetCallback(func(f Frame) { frames = append(frames, f) })
+
+		demod := NewDemodulator(sampleRate)
+		demod.SetBitCallback(d.ProcessBit)
+		demod.ProcessSamples(synthesizeAFSK(bits, sampleRate, 0.5))
+
+		if len(frames) != 1 || !bytes.Equal(frames[0].Data, data) {
+			t.Errorf("%dHz: 端到端解码失败，解出 %d 帧", sampleRate, len(frames))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package modem
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// DefaultAmplitude 默认调制幅度（满幅的一半，为限幅器和音量调节留出余量）
+const DefaultAmplitude = 0.5
+
+// Modulator Bell 202 AFSK 1200波特调制器
+// 完成NRZI编码并生成相位连续的音调
+type Modulator struct {
+	sampleRate int
+	amplitude  float64
+	phase      float64
+	mark       bool    // 当前NRZI电平 (true=传号)
+	remainder  float64 // 码元采样数的小数部分累积
+}
+
+// NewModulator 创建新的调制器
+func NewModulator(sampleRate int) *Modulator {
+	return &Modulator{
+		sampleRate: sampleRate,
+		amplitude:  DefaultAmplitude,
+		mark:       true,
+	}
+}
+
+// SetAmplitude 设置调制幅度 (0.0-1.0)
+func (m *Modulator) SetAmplitude(amplitude float64) {
+	m.amplitude = amplitude
+}
+
+// GetSampleRate 获取调制器采样率
+func (m *Modulator) GetSampleRate() int {
+	return m.sampleRate
+}
+
+// ModulateBits 将比特序列调制为16位小端单声道PCM
+// 比特0翻转音调，比特1保持音调 (NRZI)
+func (m *Modulator) ModulateBits(bits []byte) []byte {
+	samplesPerBit := float64(m.sampleRate) / BaudRate
+	pcm := make([]byte, 0, int(float64(len(bits))*samplesPerBit+1)*2)
+
+	for _, bit := range bits {
+		if bit == 0 {
+			m.mark = !m.mark
+		}
+
+		freq := SpaceFreq
+		if m.mark {
+			freq = MarkFreq
+		}
+		step := 2 * math.Pi * freq / float64(m.sampleRate)
+
+		m.remainder += samplesPerBit
+		for m.remainder >= 1 {
+			sample := int16(m.amplitude * 32767 * math.Sin(m.phase))
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(sample))
+
+			m.phase += step
+			if m.phase >= 2*math.Pi {
+				m.phase -= 2 * math.Pi
+			}
+			m.remainder--
+		}
+	}
+
+	return pcm
+}
+
+// ModulateFrame 将AX.25帧（不含FCS）编码为HDLC并调制为PCM
+func (m *Modulator) ModulateFrame(data []byte, preambleFlags, tailFlags int) []byte {
+	return m.ModulateBits(EncodeHDLC(data, preambleFlags, tailFlags))
+}
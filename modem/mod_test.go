@@ -0,0 +1,90 @@
+package modem
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncodeHDLC(t *testing.T) {
+	data := testFrameData()
+	bits := EncodeHDLC(data, 3, 2)
+
+	if !bytes.Equal(bits[:24], flagBits(3)) {
+		t.Errorf("前导标志错误")
+	}
+	if !bytes.Equal(bits[len(bits)-16:], flagBits(2)) {
+		t.Errorf("尾部标志错误")
+	}
+
+	// 帧内容中不能出现连续6个1
+	ones := 0
+	for _, bit := range bits[24 : len(bits)-16] {
+		if bit == 1 {
+			ones++
+			if ones > 5 {
+				t.Fatalf("比特填充错误")
+			}
+		} else {
+			ones = 0
+		}
+	}
+
+	var frames []Frame
+	d := NewDeframer(0)
+	d.SetCallback(func(f Frame) { frames = append(frames, f) })
+	feedBits(d, bits)
+	if len(frames) != 1 || !bytes.Equal(frames[0].Data, data) {
+		t.Errorf("编码后无法解帧")
+	}
+}
+
+func TestFlagsForDuration(t *testing.T) {
+	if got := FlagsForDuration(300 * time.Millisecond); got != 45 {
+		t.Errorf("FlagsForDuration(300ms) = %d, want 45", got)
+	}
+	if got := FlagsForDuration(0); got != 0 {
+		t.Errorf("FlagsForDuration(0) = %d, want 0", got)
+	}
+}
+
+func TestFrameDuration(t *testing.T) {
+	// 全1的数据每5位填充一位，为最坏情况
+	for _, data := range [][]byte{testFrameData(), bytes.Repeat([]byte{0xFF}, 256)} {
+		bits := len(EncodeHDLC(data, 45, 0))
+		actual := time.Duration(float64(bits) / BaudRate * float64(time.Second))
+		if got := FrameDuration(len(data), 45); got < actual || got > actual+actual/5 {
+			t.Errorf("FrameDuration(%d) = %v, 实际 %v", len(data), got, actual)
+		}
+	}
+}
+
+func TestModulatorRoundTrip(t *testing.T) {
+	data := testFrameData()
+
+	for _, sampleRate := range []int{8000, 22050, 44100, 48000} {
+		mod := NewModulator(sampleRate)
+		pcm := mod.ModulateFrame(data, 30, 3)
+
+		// 连续发送两帧，验证相位连续时解调器仍能解出两帧
+		pcm = append(pcm, mod.ModulateFrame(data, 5, 3)...)
+
+		var frames []Frame
+		d := NewDeframer(0)
+		d.SetCallback(func(f Frame) { frames = append(frames, f) })
+
+		demod := NewDemodulator(sampleRate)
+		demod.SetBitCallback(d.ProcessBit)
+		demod.ProcessInt16(pcm)
+
+		if len(frames) != 2 {
+			t.Errorf("%dHz: 期望解出2帧，实际为 %d", sampleRate, len(frames))
+			continue
+		}
+		for _, f := range frames {
+			if !bytes.Equal(f.Data, data) {
+				t.Errorf("%dHz: 帧内容不一致", sampleRate)
+			}
+		}
+	}
+}
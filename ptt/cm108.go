@@ -0,0 +1,28 @@
+package ptt
+
+import "fmt"
+
+// DefaultCM108GPIO CM108类声卡接口板常用GPIO3控制PTT
+const DefaultCM108GPIO = 3
+
+// cm108Report 生成设置GPIO电平的HID输出报告
+// 格式: 报告编号0, HID_OR0, GPIO数据 (OR1), GPIO方向 (OR2, 1为输出), HID_OR3
+func cm108Report(gpio int, high bool) []byte {
+	mask := byte(1) << (gpio - 1)
+	var data byte
+	if high {
+		data = mask
+	}
+	return []byte{0, 0, data, mask, 0}
+}
+
+// checkCM108GPIO 检查GPIO编号，为0时返回默认值
+func checkCM108GPIO(gpio int) (int, error) {
+	if gpio == 0 {
+		return DefaultCM108GPIO, nil
+	}
+	if gpio < 1 || gpio > 8 {
+		return 0, fmt.Errorf("CM108 GPIO编号必须在1到8之间: %d", gpio)
+	}
+	return gpio, nil
+}
@@ -0,0 +1,240 @@
+//go:build linux
+
+package ptt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sysfsRoot sysfs挂载点，测试时可替换
+var sysfsRoot = "/sys"
+
+// alsaCardName ALSA设备名称中的声卡，如 hw:1,0、plughw:CARD=Device,DEV=0
+var alsaCardName = regexp.MustCompile(`(?i)^(?:plug)?hw:(?:card=)?([^,]+)`)
+
+// CM108 CM108/CM119 USB声卡GPIO PTT，通过hidraw写HID输出报告设置GPIO电平
+type CM108 struct {
+	mu     sync.Mutex
+	file   *os.File
+	gpio   int
+	invert bool
+	keyed  bool
+}
+
+// OpenCM108 打开CM108 PTT，device为hidraw设备，如 /dev/hidraw0
+func OpenCM108(device string, gpio int, invert bool) (*CM108, error) {
+	gpio, err := checkCM108GPIO(gpio)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(device, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开CM108 hidraw设备失败: %w", err)
+	}
+
+	c := &CM108{file: file, gpio: gpio, invert: invert}
+	if err := c.set(false); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Key 按下PTT
+func (c *CM108) Key() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(true)
+}
+
+// Unkey 松开PTT
+func (c *CM108) Unkey() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(false)
+}
+
+// IsKeyed PTT是否已按下
+func (c *CM108) IsKeyed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.keyed
+}
+
+// Close 松开PTT并关闭hidraw设备
+func (c *CM108) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := c.set(false)
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	c.file = nil
+	return err
+}
+
+// set 设置GPIO电平，调用时需持有c.mu
+func (c *CM108) set(keyed bool) error {
+	if c.file == nil {
+		return fmt.Errorf("CM108 hidraw设备已关闭")
+	}
+	if _, err := c.file.Write(cm108Report(c.gpio, keyed != c.invert)); err != nil {
+		return fmt.Errorf("写CM108 GPIO失败: %w", err)
+	}
+	c.keyed = keyed
+	return nil
+}
+
+// usbCard 属于USB设备的声卡
+type usbCard struct {
+	number string // 声卡编号
+	id     string // 声卡ID，如 Device
+	usbDev string // USB设备在sysfs中的路径
+}
+
+// FindCM108 查找与音频设备属于同一USB设备的hidraw节点
+// audioDevice可以是ALSA名称 (hw:1,0)、声卡ID、PulseAudio名称或声卡产品名；
+// 为空或default时，只有一块带hidraw的USB声卡才能确定
+func FindCM108(audioDevice string) (string, error) {
+	cards, err := usbSoundCards()
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	for _, card := range cards {
+		hidraw := hidrawFor(card.usbDev)
+		if hidraw == "" {
+			continue
+		}
+		if isDefaultDevice(audioDevice) {
+			candidates = append(candidates, hidraw)
+			continue
+		}
+		if matchCard(audioDevice, card) {
+			return hidraw, nil
+		}
+	}
+
+	if isDefaultDevice(audioDevice) {
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+		return "", fmt.Errorf("找到 %d 块带hidraw的USB声卡，请配置音频设备或 ptt_device", len(candidates))
+	}
+	return "", fmt.Errorf("未找到音频设备 %q 对应的CM108 hidraw设备", audioDevice)
+}
+
+// isDefaultDevice 是否使用默认音频设备
+func isDefaultDevice(name string) bool {
+	return name == "" || strings.EqualFold(name, "default")
+}
+
+// usbSoundCards 列出sysfs中属于USB设备的声卡
+func usbSoundCards() ([]usbCard, error) {
+	paths, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "sound", "card*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var cards []usbCard
+	for _, path := range paths {
+		number := strings.TrimPrefix(filepath.Base(path), "card")
+		if number == "" || strings.Trim(number, "0123456789") != "" {
+			continue
+		}
+		usbDev := usbDevice(filepath.Join(path, "device"))
+		if usbDev == "" {
+			continue
+		}
+		id, _ := os.ReadFile(filepath.Join(path, "id"))
+		cards = append(cards, usbCard{
+			number: number,
+			id:     strings.TrimSpace(string(id)),
+			usbDev: usbDev,
+		})
+	}
+	return cards, nil
+}
+
+// usbDevice 获取sysfs设备所属的USB设备路径，声卡的device链接指向USB接口，其父目录为USB设备
+func usbDevice(link string) string {
+	path, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return ""
+	}
+	for _, dir := range []string{path, filepath.Dir(path)} {
+		if _, err := os.Stat(filepath.Join(dir, "idVendor")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// hidrawFor 查找USB设备下的hidraw节点，返回设备文件路径
+func hidrawFor(usbDev string) string {
+	paths, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "hidraw", "hidraw*"))
+	if err != nil {
+		return ""
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		dev, err := filepath.EvalSymlinks(filepath.Join(path, "device"))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(dev, usbDev+string(filepath.Separator)) {
+			return filepath.Join("/dev", filepath.Base(path))
+		}
+	}
+	return ""
+}
+
+// matchCard 音频设备名称是否指向该声卡
+func matchCard(name string, card usbCard) bool {
+	if m := alsaCardName.FindStringSubmatch(name); m != nil {
+		return m[1] == card.number || strings.EqualFold(m[1], card.id)
+	}
+
+	lower := strings.ToLower(name)
+	if card.id != "" && strings.EqualFold(name, card.id) {
+		return true
+	}
+
+	// PulseAudio名称如 alsa_input.usb-C-Media_Electronics_Inc._USB_Audio_Device-00.analog-mono，
+	// malgo名称如 USB Audio Device, USB Audio，包含USB设备的厂商和产品名
+	product := readAttr(card.usbDev, "product")
+	if product == "" {
+		return false
+	}
+	if strings.Contains(lower, strings.ToLower(product)) {
+		return true
+	}
+	vendorProduct := product
+	if manufacturer := readAttr(card.usbDev, "manufacturer"); manufacturer != "" {
+		vendorProduct = manufacturer + " " + product
+	}
+	return strings.Contains(lower, "usb-"+strings.ToLower(strings.ReplaceAll(vendorProduct, " ", "_")))
+}
+
+// readAttr 读取sysfs属性
+func readAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
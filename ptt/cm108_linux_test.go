@@ -0,0 +1,163 @@
+//go:build linux
+
+package ptt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeSysfs 创建模拟的sysfs目录树:
+// card0为板载声卡，card1和card2为USB声卡，hidraw2属于另一个非声卡USB设备
+func makeSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	mkdir := func(path string) {
+		if err := os.MkdirAll(filepath.Join(root, path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(path, content string) {
+		mkdir(filepath.Dir(path))
+		if err := os.WriteFile(filepath.Join(root, path), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(path, target string) {
+		mkdir(filepath.Dir(path))
+		if err := os.Symlink(filepath.Join(root, target), filepath.Join(root, path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usb := "devices/pci0000:00/usb1"
+	write(usb+"/1-1/idVendor", "0d8c")
+	write(usb+"/1-1/manufacturer", "C-Media Electronics Inc.")
+	write(usb+"/1-1/product", "USB Audio Device")
+	mkdir(usb + "/1-1/1-1:1.0/sound/card1")
+	mkdir(usb + "/1-1/1-1:1.3/0003:0D8C:000C.0001/hidraw/hidraw0")
+
+	write(usb+"/1-2/idVendor", "0d8c")
+	write(usb+"/1-2/manufacturer", "C-Media Electronics Inc.")
+	write(usb+"/1-2/product", "USB PnP Sound Device")
+	mkdir(usb + "/1-2/1-2:1.0/sound/card2")
+	mkdir(usb + "/1-2/1-2:1.3/0003:0D8C:0012.0002/hidraw/hidraw1")
+
+	write(usb+"/1-3/idVendor", "046d")
+	mkdir(usb + "/1-3/1-3:1.0/0003:046D:C52B.0003/hidraw/hidraw2")
+
+	mkdir("devices/pci0000:00/0000:00:1f.3/sound/card0")
+
+	write("class/sound/card0/id", "PCH")
+	link("class/sound/card0/device", "devices/pci0000:00/0000:00:1f.3")
+	write("class/sound/card1/id", "Device")
+	link("class/sound/card1/device", usb+"/1-1/1-1:1.0")
+	write("class/sound/card2/id", "Device_1")
+	link("class/sound/card2/device", usb+"/1-2/1-2:1.0")
+
+	link("class/hidraw/hidraw0/device", usb+"/1-1/1-1:1.3/0003:0D8C:000C.0001")
+	link("class/hidraw/hidraw1/device", usb+"/1-2/1-2:1.3/0003:0D8C:0012.0002")
+	link("class/hidraw/hidraw2/device", usb+"/1-3/1-3:1.0/0003:046D:C52B.0003")
+
+	return root
+}
+
+func TestFindCM108(t *testing.T) {
+	saved := sysfsRoot
+	sysfsRoot = makeSysfs(t)
+	defer func() { sysfsRoot = saved }()
+
+	tests := []struct {
+		name    string
+		device  string
+		want    string
+		wantErr bool
+	}{
+		{"ALSA编号", "hw:1,0", "/dev/hidraw0", false},
+		{"ALSA声卡ID", "plughw:CARD=Device_1,DEV=0", "/dev/hidraw1", false},
+		{"声卡ID", "Device", "/dev/hidraw0", false},
+		{"PulseAudio名称", "alsa_input.usb-C-Media_Electronics_Inc._USB_PnP_Sound_Device-00.analog-mono", "/dev/hidraw1", false},
+		{"产品名", "USB Audio Device, USB Audio", "/dev/hidraw0", false},
+		{"板载声卡", "hw:0", "", true},
+		{"不存在的设备", "HDMI", "", true},
+		{"多块USB声卡时使用默认设备", "default", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindCM108(tt.device)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindCM108(%q) error = %v, wantErr %v", tt.device, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FindCM108(%q) = %q, want %q", tt.device, got, tt.want)
+			}
+		})
+	}
+
+	// 只有一块带hidraw的USB声卡时，默认设备可以确定
+	if err := os.RemoveAll(filepath.Join(sysfsRoot, "class", "sound", "card2")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FindCM108(""); err != nil || got != "/dev/hidraw0" {
+		t.Errorf("FindCM108(\"\") = %q, %v, want /dev/hidraw0", got, err)
+	}
+}
+
+func TestCM108(t *testing.T) {
+	tests := []struct {
+		name   string
+		gpio   int
+		invert bool
+		keyed  []byte
+		idle   []byte
+	}{
+		{"默认GPIO3", 0, false, []byte{0, 0, 0x04, 0x04, 0}, []byte{0, 0, 0, 0x04, 0}},
+		{"GPIO1", 1, false, []byte{0, 0, 0x01, 0x01, 0}, []byte{0, 0, 0, 0x01, 0}},
+		{"反转", 4, true, []byte{0, 0, 0, 0x08, 0}, []byte{0, 0, 0x08, 0x08, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 用普通文件代替hidraw设备，记录写入的HID报告
+			path := filepath.Join(t.TempDir(), "hidraw0")
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := OpenCM108(path, tt.gpio, tt.invert)
+			if err != nil {
+				t.Fatalf("打开CM108 PTT失败: %v", err)
+			}
+			if err := c.Key(); err != nil {
+				t.Fatalf("按下PTT失败: %v", err)
+			}
+			if !c.IsKeyed() {
+				t.Error("按下后IsKeyed()应为true")
+			}
+			if err := c.Unkey(); err != nil {
+				t.Fatalf("松开PTT失败: %v", err)
+			}
+			if err := c.Close(); err != nil {
+				t.Fatalf("关闭失败: %v", err)
+			}
+
+			// 打开、按下、松开、关闭各写一个报告
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := bytes.Join([][]byte{tt.idle, tt.keyed, tt.idle, tt.idle}, nil)
+			if !bytes.Equal(got, want) {
+				t.Errorf("HID报告 = % x, want % x", got, want)
+			}
+		})
+	}
+
+	if _, err := OpenCM108(filepath.Join(t.TempDir(), "hidraw0"), 9, false); err == nil {
+		t.Error("无效的GPIO应打开失败")
+	}
+}
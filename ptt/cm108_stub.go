@@ -0,0 +1,38 @@
+//go:build !linux
+
+package ptt
+
+import "fmt"
+
+// CM108 CM108/CM119 USB声卡GPIO PTT，仅在Linux上可用
+type CM108 struct{}
+
+// OpenCM108 打开CM108 PTT
+func OpenCM108(device string, gpio int, invert bool) (*CM108, error) {
+	return nil, fmt.Errorf("CM108 PTT仅在Linux系统上可用")
+}
+
+// FindCM108 查找与音频设备属于同一USB设备的hidraw节点
+func FindCM108(audioDevice string) (string, error) {
+	return "", fmt.Errorf("CM108 PTT仅在Linux系统上可用")
+}
+
+// Key 按下PTT
+func (c *CM108) Key() error {
+	return fmt.Errorf("CM108 PTT仅在Linux系统上可用")
+}
+
+// Unkey 松开PTT
+func (c *CM108) Unkey() error {
+	return nil
+}
+
+// IsKeyed PTT是否已按下
+func (c *CM108) IsKeyed() bool {
+	return false
+}
+
+// Close 关闭hidraw设备
+func (c *CM108) Close() error {
+	return nil
+}
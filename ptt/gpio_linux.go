@@ -0,0 +1,177 @@
+//go:build linux
+
+package ptt
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// GPIO字符设备v2 ABI (linux/gpio.h)
+const (
+	gpioV2LinesMax       = 64
+	gpioV2LineAttrsMax   = 10
+	gpioMaxNameSize      = 32
+	gpioV2LineFlagActLow = 1 << 1
+	gpioV2LineFlagOutput = 1 << 3
+	gpioV2AttrOutValues  = 2
+)
+
+// gpioV2LineAttribute struct gpio_v2_line_attribute
+type gpioV2LineAttribute struct {
+	ID      uint32
+	Padding uint32
+	Value   uint64 // flags、values或debounce_period_us
+}
+
+// gpioV2LineConfigAttribute struct gpio_v2_line_config_attribute
+type gpioV2LineConfigAttribute struct {
+	Attr gpioV2LineAttribute
+	Mask uint64
+}
+
+// gpioV2LineConfig struct gpio_v2_line_config
+type gpioV2LineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [gpioV2LineAttrsMax]gpioV2LineConfigAttribute
+}
+
+// gpioV2LineRequest struct gpio_v2_line_request
+type gpioV2LineRequest struct {
+	Offsets         [gpioV2LinesMax]uint32
+	Consumer        [gpioMaxNameSize]byte
+	Config          gpioV2LineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	Padding         [5]uint32
+	Fd              int32
+}
+
+// gpioV2LineValues struct gpio_v2_line_values
+type gpioV2LineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+// iowr 计算_IOWR ioctl请求号
+func iowr(nr, size uintptr) uintptr {
+	return 3<<30 | size<<16 | 0xB4<<8 | nr
+}
+
+var (
+	gpioV2GetLineIoctl       = iowr(0x07, unsafe.Sizeof(gpioV2LineRequest{}))
+	gpioV2LineSetValuesIoctl = iowr(0x0F, unsafe.Sizeof(gpioV2LineValues{}))
+)
+
+// gpioIoctl 执行GPIO ioctl，测试时可替换
+var gpioIoctl = func(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// gpioConsumer 申请GPIO线时登记的使用者名称
+const gpioConsumer = "aprs_agent-ptt"
+
+// GPIO GPIO字符设备PTT，通过 /dev/gpiochipN 申请一条输出线
+// 低电平有效由内核处理，Key总是将线设为有效状态
+type GPIO struct {
+	mu    sync.Mutex
+	line  *os.File
+	keyed bool
+}
+
+// OpenGPIO 申请GPIO线作为PTT输出，初始为无效状态
+func OpenGPIO(chip string, offset int, activeLow bool) (*GPIO, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("无效的GPIO线偏移: %d", offset)
+	}
+
+	file, err := os.OpenFile(chip, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开GPIO设备失败: %w", err)
+	}
+	defer file.Close()
+
+	var req gpioV2LineRequest
+	req.Offsets[0] = uint32(offset)
+	req.NumLines = 1
+	copy(req.Consumer[:], gpioConsumer)
+	req.Config.Flags = gpioV2LineFlagOutput
+	if activeLow {
+		req.Config.Flags |= gpioV2LineFlagActLow
+	}
+	// 输出初始值: 无效
+	req.Config.NumAttrs = 1
+	req.Config.Attrs[0] = gpioV2LineConfigAttribute{
+		Attr: gpioV2LineAttribute{ID: gpioV2AttrOutValues, Value: 0},
+		Mask: 1,
+	}
+
+	if err := gpioIoctl(file.Fd(), gpioV2GetLineIoctl, unsafe.Pointer(&req)); err != nil {
+		return nil, fmt.Errorf("申请GPIO线 %s:%d 失败: %w", chip, offset, err)
+	}
+
+	return &GPIO{line: os.NewFile(uintptr(req.Fd), fmt.Sprintf("%s:%d", chip, offset))}, nil
+}
+
+// Key 按下PTT
+func (g *GPIO) Key() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.set(true)
+}
+
+// Unkey 松开PTT
+func (g *GPIO) Unkey() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.set(false)
+}
+
+// IsKeyed PTT是否已按下
+func (g *GPIO) IsKeyed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.keyed
+}
+
+// Close 松开PTT并释放GPIO线
+func (g *GPIO) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.line == nil {
+		return nil
+	}
+	err := g.set(false)
+	if closeErr := g.line.Close(); err == nil {
+		err = closeErr
+	}
+	g.line = nil
+	return err
+}
+
+// set 设置GPIO线的逻辑值，调用时需持有g.mu
+func (g *GPIO) set(keyed bool) error {
+	if g.line == nil {
+		return fmt.Errorf("GPIO线已释放")
+	}
+
+	values := gpioV2LineValues{Mask: 1}
+	if keyed {
+		values.Bits = 1
+	}
+	if err := gpioIoctl(g.line.Fd(), gpioV2LineSetValuesIoctl, unsafe.Pointer(&values)); err != nil {
+		return fmt.Errorf("设置GPIO线失败: %w", err)
+	}
+	g.keyed = keyed
+	return nil
+}
@@ -0,0 +1,237 @@
+//go:build linux
+
+package ptt
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestGPIOABI(t *testing.T) {
+	// 与 linux/gpio.h 的结构体大小和ioctl请求号一致
+	if size := unsafe.Sizeof(gpioV2LineRequest{}); size != 592 {
+		t.Errorf("gpio_v2_line_request大小 = %d, want 592", size)
+	}
+	if size := unsafe.Sizeof(gpioV2LineConfig{}); size != 272 {
+		t.Errorf("gpio_v2_line_config大小 = %d, want 272", size)
+	}
+	if gpioV2GetLineIoctl != 0xC250B407 {
+		t.Errorf("GPIO_V2_GET_LINE_IOCTL = %#x, want 0xc250b407", gpioV2GetLineIoctl)
+	}
+	if gpioV2LineSetValuesIoctl != 0xC010B40F {
+		t.Errorf("GPIO_V2_LINE_SET_VALUES_IOCTL = %#x, want 0xc010b40f", gpioV2LineSetValuesIoctl)
+	}
+}
+
+// fakeGPIOChip 模拟GPIO字符设备的ioctl，记录申请的线和设置的值
+type fakeGPIOChip struct {
+	t      *testing.T
+	mu     sync.Mutex
+	req    gpioV2LineRequest
+	values []uint64
+}
+
+func (f *fakeGPIOChip) ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch req {
+	case gpioV2GetLineIoctl:
+		r := (*gpioV2LineRequest)(arg)
+		f.req = *r
+		// 用管道代替内核返回的GPIO线文件描述符
+		rd, wr, err := os.Pipe()
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		rd.Close()
+		fd, err := unix.Dup(int(wr.Fd()))
+		wr.Close()
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		r.Fd = int32(fd)
+	case gpioV2LineSetValuesIoctl:
+		v := (*gpioV2LineValues)(arg)
+		if v.Mask != 1 {
+			f.t.Errorf("设置值的掩码 = %#x, want 1", v.Mask)
+		}
+		f.values = append(f.values, v.Bits)
+	default:
+		return unix.ENOTTY
+	}
+	return nil
+}
+
+func TestGPIO(t *testing.T) {
+	fake := &fakeGPIOChip{t: t}
+	saved := gpioIoctl
+	gpioIoctl = fake.ioctl
+	defer func() { gpioIoctl = saved }()
+
+	chip := filepath.Join(t.TempDir(), "gpiochip0")
+	if err := os.WriteFile(chip, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := OpenGPIO(chip, 17, true)
+	if err != nil {
+		t.Fatalf("申请GPIO线失败: %v", err)
+	}
+
+	req := fake.req
+	if req.NumLines != 1 || req.Offsets[0] != 17 {
+		t.Errorf("申请的线 = %d 条, 偏移 %d, want 1 条, 偏移 17", req.NumLines, req.Offsets[0])
+	}
+	if req.Config.Flags != gpioV2LineFlagOutput|gpioV2LineFlagActLow {
+		t.Errorf("线配置标志 = %#x, want 输出|低电平有效", req.Config.Flags)
+	}
+	if attr := req.Config.Attrs[0]; req.Config.NumAttrs != 1 || attr.Attr.ID != gpioV2AttrOutValues || attr.Attr.Value != 0 || attr.Mask != 1 {
+		t.Errorf("输出初始值属性 = %+v, want 无效", attr)
+	}
+	if consumer := strings.TrimRight(string(req.Consumer[:]), "\x00"); consumer != gpioConsumer {
+		t.Errorf("使用者 = %q, want %q", consumer, gpioConsumer)
+	}
+
+	if err := g.Key(); err != nil {
+		t.Fatalf("按下PTT失败: %v", err)
+	}
+	if !g.IsKeyed() {
+		t.Error("按下后IsKeyed()应为true")
+	}
+	if err := g.Unkey(); err != nil {
+		t.Fatalf("松开PTT失败: %v", err)
+	}
+	g.Key()
+	if err := g.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	if err := g.Key(); err == nil {
+		t.Error("释放后按下PTT应失败")
+	}
+
+	want := []uint64{1, 0, 1, 0}
+	if len(fake.values) != len(want) {
+		t.Fatalf("设置的值 = %v, want %v", fake.values, want)
+	}
+	for i := range want {
+		if fake.values[i] != want[i] {
+			t.Fatalf("设置的值 = %v, want %v", fake.values, want)
+		}
+	}
+
+	if _, err := OpenGPIO(chip, -1, false); err == nil {
+		t.Error("无效的线偏移应申请失败")
+	}
+}
+
+// gpioSim 通过configfs创建的gpio-sim模拟GPIO芯片
+// 需要内核启用CONFIG_GPIO_SIM并以root运行，否则跳过测试
+type gpioSim struct {
+	t    *testing.T
+	chip string // /dev/gpiochipN
+	dir  string // sysfs中模拟芯片的目录，包含各线的sim_gpioN
+}
+
+// gpioSimConfigfs gpio-sim的configfs目录
+const gpioSimConfigfs = "/sys/kernel/config/gpio-sim"
+
+// newGPIOSim 创建有lines条线的模拟GPIO芯片，测试结束时删除
+func newGPIOSim(t *testing.T, lines int) *gpioSim {
+	t.Helper()
+	if _, err := os.Stat(gpioSimConfigfs); err != nil {
+		t.Skipf("gpio-sim不可用: %v", err)
+	}
+
+	dir := filepath.Join(gpioSimConfigfs, "aprs_agent_test")
+	bank := filepath.Join(dir, "bank0")
+	write := func(path, value string) error {
+		return os.WriteFile(path, []byte(value), 0o644)
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Skipf("创建gpio-sim设备失败: %v", err)
+	}
+	t.Cleanup(func() {
+		write(filepath.Join(dir, "live"), "0")
+		os.Remove(bank)
+		os.Remove(dir)
+	})
+	if err := os.Mkdir(bank, 0o755); err != nil {
+		t.Fatalf("创建gpio-sim bank失败: %v", err)
+	}
+	if err := write(filepath.Join(bank, "num_lines"), strconv.Itoa(lines)); err != nil {
+		t.Fatalf("设置gpio-sim线数失败: %v", err)
+	}
+	if err := write(filepath.Join(dir, "live"), "1"); err != nil {
+		t.Fatalf("启用gpio-sim失败: %v", err)
+	}
+
+	chipName, err := os.ReadFile(filepath.Join(bank, "chip_name"))
+	if err != nil {
+		t.Fatalf("读取gpio-sim芯片名称失败: %v", err)
+	}
+	devName, err := os.ReadFile(filepath.Join(dir, "dev_name"))
+	if err != nil {
+		t.Fatalf("读取gpio-sim设备名称失败: %v", err)
+	}
+	chip := strings.TrimSpace(string(chipName))
+	return &gpioSim{
+		t:    t,
+		chip: filepath.Join("/dev", chip),
+		dir:  filepath.Join("/sys/devices/platform", strings.TrimSpace(string(devName)), chip),
+	}
+}
+
+// value 读取模拟线的物理电平
+func (s *gpioSim) value(offset int) string {
+	s.t.Helper()
+	data, err := os.ReadFile(filepath.Join(s.dir, "sim_gpio"+strconv.Itoa(offset), "value"))
+	if err != nil {
+		s.t.Fatalf("读取模拟线电平失败: %v", err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestGPIOSim(t *testing.T) {
+	sim := newGPIOSim(t, 4)
+
+	tests := []struct {
+		name      string
+		offset    int
+		activeLow bool
+		keyed     string
+		idle      string
+	}{
+		{"高电平有效", 1, false, "1", "0"},
+		{"低电平有效", 2, true, "0", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Open(Config{Method: MethodGPIO, Device: sim.chip, GPIO: tt.offset, Invert: tt.activeLow})
+			if err != nil {
+				t.Fatalf("打开GPIO PTT失败: %v", err)
+			}
+			if got := sim.value(tt.offset); got != tt.idle {
+				t.Errorf("初始电平 = %s, want %s", got, tt.idle)
+			}
+			if err := p.Key(); err != nil {
+				t.Fatalf("按下PTT失败: %v", err)
+			}
+			if got := sim.value(tt.offset); got != tt.keyed {
+				t.Errorf("按下后电平 = %s, want %s", got, tt.keyed)
+			}
+			if err := p.Close(); err != nil {
+				t.Fatalf("关闭失败: %v", err)
+			}
+		})
+	}
+}
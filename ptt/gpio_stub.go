@@ -0,0 +1,33 @@
+//go:build !linux
+
+package ptt
+
+import "fmt"
+
+// GPIO GPIO字符设备PTT，仅在Linux上可用
+type GPIO struct{}
+
+// OpenGPIO 申请GPIO线作为PTT输出
+func OpenGPIO(chip string, offset int, activeLow bool) (*GPIO, error) {
+	return nil, fmt.Errorf("GPIO PTT仅在Linux系统上可用")
+}
+
+// Key 按下PTT
+func (g *GPIO) Key() error {
+	return fmt.Errorf("GPIO PTT仅在Linux系统上可用")
+}
+
+// Unkey 松开PTT
+func (g *GPIO) Unkey() error {
+	return nil
+}
+
+// IsKeyed PTT是否已按下
+func (g *GPIO) IsKeyed() bool {
+	return false
+}
+
+// Close 释放GPIO线
+func (g *GPIO) Close() error {
+	return nil
+}
@@ -0,0 +1,71 @@
+package ptt
+
+import "time"
+
+// Limits 发射保护参数
+type Limits struct {
+	MaxTxTime  time.Duration // 单次连续发射的最长时间，超过时强制松开PTT，为0时不限制
+	DutyCycle  int           // 滚动窗口内发射时间占比的上限 (百分比)，为0或不小于100时不限制
+	DutyWindow time.Duration // 占空比的统计窗口
+}
+
+// dutyBudget 窗口内允许的发射时间，不限制时返回0
+func (l Limits) dutyBudget() time.Duration {
+	if l.DutyCycle <= 0 || l.DutyCycle >= 100 || l.DutyWindow <= 0 {
+		return 0
+	}
+	return l.DutyWindow * time.Duration(l.DutyCycle) / 100
+}
+
+// GuardStats 发射保护统计信息
+type GuardStats struct {
+	WatchdogTrips uint64        // 发射超时被强制松开PTT的次数
+	Airtime       time.Duration // 统计窗口内的发射时间
+	DutyCycle     float64       // 统计窗口内的发射时间占比 (百分比)
+}
+
+// span 一次发射的起止时间
+type span struct {
+	start, end time.Time
+}
+
+// airtimeLog 滚动窗口内的发射时间记录
+type airtimeLog struct {
+	spans []span // 已结束的发射，按时间顺序
+}
+
+// add 记录一次已结束的发射
+func (a *airtimeLog) add(start, end time.Time) {
+	if end.After(start) {
+		a.spans = append(a.spans, span{start, end})
+	}
+}
+
+// total 计算 [now-window, now] 内的发射时间，并丢弃窗口之前的记录
+// keyedAt非零时表示正在发射，计入从keyedAt到now的时间
+func (a *airtimeLog) total(now time.Time, window time.Duration, keyedAt time.Time) time.Duration {
+	from := now.Add(-window)
+
+	drop := 0
+	for drop < len(a.spans) && !a.spans[drop].end.After(from) {
+		drop++
+	}
+	a.spans = a.spans[drop:]
+
+	var total time.Duration
+	for _, s := range a.spans {
+		start := s.start
+		if start.Before(from) {
+			start = from
+		}
+		total += s.end.Sub(start)
+	}
+	if !keyedAt.IsZero() {
+		start := keyedAt
+		if start.Before(from) {
+			start = from
+		}
+		total += now.Sub(start)
+	}
+	return total
+}
@@ -0,0 +1,191 @@
+package ptt
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// pollInterval 等待发射队列播放完毕的轮询间隔
+const pollInterval = 10 * time.Millisecond
+
+// Keyer 按发射队列控制PTT
+// 音频入队前按下PTT，队列播放完毕并经过TXTAIL后松开；期间又有音频入队时保持按下
+// 同时记录发射时间：连续发射超过最长时间时强制松开PTT，并统计滚动窗口内的占空比
+type Keyer struct {
+	ptt     PTT        // 为nil时不控制PTT (VOX)，只记录发射时间
+	pending func() int // 等待播放的音频块数，如本通道的 AudioOutput.GetQueueSize
+
+	mu       sync.Mutex
+	keyed    bool
+	keyedAt  time.Time
+	watching bool
+	tail     time.Duration
+
+	limits  Limits
+	onTrip  func() // 发射超时时调用，如本通道的 AudioOutput.ClearQueue
+	airtime airtimeLog
+	trips   uint64
+}
+
+// NewKeyer 创建PTT控制器，p为nil时不控制PTT
+func NewKeyer(p PTT, pending func() int) *Keyer {
+	return &Keyer{ptt: p, pending: pending}
+}
+
+// SetLimits 设置发射保护参数
+// 连续发射超过limits.MaxTxTime时松开PTT并调用onTrip清空待播放的音频
+func (k *Keyer) SetLimits(limits Limits, onTrip func()) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.limits = limits
+	k.onTrip = onTrip
+}
+
+// Send 按下PTT后调用play将音频入队，不等待播放
+// 队列播放完毕并经过tail后在后台松开PTT；按下和入队之间不会被松开
+func (k *Keyer) Send(play func() error, tail time.Duration) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !k.keyed {
+		if k.ptt != nil {
+			if err := k.ptt.Key(); err != nil {
+				return fmt.Errorf("按下PTT失败: %w", err)
+			}
+		}
+		k.keyed = true
+		k.keyedAt = time.Now()
+	}
+
+	err := play()
+
+	k.tail = tail
+	if !k.watching {
+		k.watching = true
+		go k.watch()
+	}
+	return err
+}
+
+// IsKeyed PTT是否已按下
+func (k *Keyer) IsKeyed() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.keyed
+}
+
+// DutyCycleExceeded 滚动窗口内的发射时间是否已达到占空比上限
+// 超过时应暂缓发射新的帧，直到早先的发射移出窗口
+func (k *Keyer) DutyCycleExceeded() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	budget := k.limits.dutyBudget()
+	return budget > 0 && k.airtimeLocked(time.Now()) >= budget
+}
+
+// GetStats 获取发射保护统计信息
+func (k *Keyer) GetStats() GuardStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	stats := GuardStats{WatchdogTrips: k.trips}
+	if k.limits.DutyWindow > 0 {
+		stats.Airtime = k.airtimeLocked(time.Now())
+		stats.DutyCycle = float64(stats.Airtime) / float64(k.limits.DutyWindow) * 100
+	}
+	return stats
+}
+
+// Close 松开PTT并释放设备
+func (k *Keyer) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keyed {
+		k.recordLocked()
+	}
+	if k.ptt == nil {
+		return nil
+	}
+	return k.ptt.Close()
+}
+
+// airtimeLocked 统计窗口内的发射时间，调用时需持有k.mu
+func (k *Keyer) airtimeLocked(now time.Time) time.Duration {
+	var keyedAt time.Time
+	if k.keyed {
+		keyedAt = k.keyedAt
+	}
+	return k.airtime.total(now, k.limits.DutyWindow, keyedAt)
+}
+
+// unkeyLocked 松开PTT并记录发射时间，调用时需持有k.mu
+func (k *Keyer) unkeyLocked() {
+	if !k.keyed {
+		return
+	}
+	if k.ptt != nil {
+		if err := k.ptt.Unkey(); err != nil {
+			log.Printf("[PTT] 松开PTT失败: %v", err)
+		}
+	}
+	k.recordLocked()
+}
+
+// recordLocked 结束本次发射并记录发射时间，丢弃窗口之前的记录，调用时需持有k.mu
+func (k *Keyer) recordLocked() {
+	now := time.Now()
+	k.keyed = false
+	k.airtime.add(k.keyedAt, now)
+	k.airtime.total(now, k.limits.DutyWindow, time.Time{})
+}
+
+// checkWatchdog 连续发射超过最长时间时松开PTT并清空待播放的音频
+func (k *Keyer) checkWatchdog() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !k.keyed || k.limits.MaxTxTime <= 0 {
+		return
+	}
+	elapsed := time.Since(k.keyedAt)
+	if elapsed < k.limits.MaxTxTime {
+		return
+	}
+
+	k.trips++
+	log.Printf("[PTT] 连续发射 %.1f 秒超过上限 %.1f 秒，强制松开PTT并清空音频队列 (第 %d 次)",
+		elapsed.Seconds(), k.limits.MaxTxTime.Seconds(), k.trips)
+	// 在锁内清空队列，避免与Send并发入队的音频在松开后继续播放
+	if k.onTrip != nil {
+		k.onTrip()
+	}
+	k.unkeyLocked()
+}
+
+// watch 等待队列播放完毕后松开PTT
+func (k *Keyer) watch() {
+	for {
+		for k.pending() > 0 {
+			k.checkWatchdog()
+			time.Sleep(pollInterval)
+		}
+
+		k.mu.Lock()
+		tail := k.tail
+		k.mu.Unlock()
+		time.Sleep(tail)
+
+		k.mu.Lock()
+		if k.pending() > 0 {
+			// 等待期间又有音频入队
+			k.mu.Unlock()
+			continue
+		}
+		k.unkeyLocked()
+		k.watching = false
+		k.mu.Unlock()
+		return
+	}
+}
@@ -0,0 +1,96 @@
+// Package ptt 发射控制 (Push-To-Talk)
+// 在调制音频播放前按下电台的PTT，播放完毕后松开
+package ptt
+
+import (
+	"fmt"
+	"strings"
+
+	"aprs_agent/rig"
+)
+
+// PTT 电台发射控制
+type PTT interface {
+	Key() error   // 按下PTT，电台开始发射
+	Unkey() error // 松开PTT，电台回到接收
+	Close() error // 松开PTT并释放设备
+}
+
+// PTT方式
+const (
+	MethodNone   = "none"    // 不控制PTT，使用电台VOX
+	MethodSerial = "serial"  // 串口RTS/DTR
+	MethodCM108  = "cm108"   // CM108/CM119 USB声卡的GPIO
+	MethodGPIO   = "gpio"    // GPIO字符设备
+	MethodRig    = "rigctld" // Hamlib rigctld，通过CAT命令控制
+)
+
+// DefaultGPIOChip GPIO PTT的默认设备
+const DefaultGPIOChip = "/dev/gpiochip0"
+
+// 串口控制线
+const (
+	LineRTS = "rts"
+	LineDTR = "dtr"
+)
+
+// Config PTT配置
+type Config struct {
+	Method string // PTT方式
+	Device string // PTT设备，如 /dev/ttyUSB0，rigctld为 host:port
+	Line   string // 串口控制线: rts (默认), dtr
+	Invert bool   // 反转电平，控制线为低时发射；GPIO字符设备为低电平有效
+	GPIO   int    // CM108的GPIO编号 (1-8，为0时使用DefaultCM108GPIO)，或GPIO字符设备的线偏移
+
+	// AudioDevice 通道使用的音频设备名称，CM108未配置设备时据此查找同一USB设备的hidraw节点
+	AudioDevice string
+}
+
+// Open 按配置打开PTT，方式为none时返回nil
+func Open(cfg Config) (PTT, error) {
+	switch strings.ToLower(cfg.Method) {
+	case "", MethodNone:
+		return nil, nil
+	case MethodSerial:
+		if cfg.Device == "" {
+			return nil, fmt.Errorf("串口PTT未配置设备")
+		}
+		s, err := OpenSerial(cfg.Device, cfg.Line, cfg.Invert)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case MethodCM108:
+		device := cfg.Device
+		if device == "" {
+			found, err := FindCM108(cfg.AudioDevice)
+			if err != nil {
+				return nil, err
+			}
+			device = found
+		}
+		c, err := OpenCM108(device, cfg.GPIO, cfg.Invert)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case MethodGPIO:
+		chip := cfg.Device
+		if chip == "" {
+			chip = DefaultGPIOChip
+		}
+		g, err := OpenGPIO(chip, cfg.GPIO, cfg.Invert)
+		if err != nil {
+			return nil, err
+		}
+		return g, nil
+	case MethodRig:
+		r, err := rig.Dial(cfg.Device, 0)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("不支持的PTT方式: %s", cfg.Method)
+	}
+}
@@ -0,0 +1,278 @@
+package ptt
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePTT 记录按下和松开操作的PTT
+type fakePTT struct {
+	mu     sync.Mutex
+	events []string
+	keyErr error
+}
+
+func (p *fakePTT) Key() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keyErr != nil {
+		return p.keyErr
+	}
+	p.events = append(p.events, "key")
+	return nil
+}
+
+func (p *fakePTT) Unkey() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, "unkey")
+	return nil
+}
+
+func (p *fakePTT) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, "close")
+	return nil
+}
+
+func (p *fakePTT) getEvents() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.events...)
+}
+
+// fakeQueue 模拟音频输出队列
+type fakeQueue struct {
+	mu   sync.Mutex
+	size int
+}
+
+func (q *fakeQueue) push() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.size++
+	return nil
+}
+
+func (q *fakeQueue) drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.size = 0
+}
+
+func (q *fakeQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestKeyer(t *testing.T) {
+	p := &fakePTT{}
+	q := &fakeQueue{}
+	k := NewKeyer(p, q.len)
+
+	// 连续两帧只按下一次
+	if err := k.Send(q.push, 20*time.Millisecond); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if err := k.Send(q.push, 20*time.Millisecond); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if !k.IsKeyed() {
+		t.Fatal("音频入队后PTT应处于按下状态")
+	}
+
+	// 队列未播放完时保持按下
+	time.Sleep(50 * time.Millisecond)
+	if !k.IsKeyed() {
+		t.Fatal("队列未播放完时不应松开PTT")
+	}
+
+	// 队列播放完毕并经过TXTAIL后松开
+	q.drain()
+	waitFor(t, func() bool { return !k.IsKeyed() })
+	if got := p.getEvents(); len(got) != 2 || got[0] != "key" || got[1] != "unkey" {
+		t.Errorf("PTT操作 = %v, want [key unkey]", got)
+	}
+
+	// 松开后再次发送重新按下
+	if err := k.Send(q.push, 0); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	q.drain()
+	waitFor(t, func() bool { return !k.IsKeyed() })
+
+	if err := k.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	want := []string{"key", "unkey", "key", "unkey", "close"}
+	got := p.getEvents()
+	if len(got) != len(want) {
+		t.Fatalf("PTT操作 = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("PTT操作 = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestKeyerErrors(t *testing.T) {
+	// 按下失败时不播放
+	p := &fakePTT{keyErr: errors.New("设备错误")}
+	q := &fakeQueue{}
+	k := NewKeyer(p, q.len)
+	if err := k.Send(q.push, 0); err == nil {
+		t.Error("按下PTT失败时应返回错误")
+	}
+	if q.len() != 0 {
+		t.Error("按下PTT失败时不应播放音频")
+	}
+
+	// 播放失败时返回错误并松开PTT
+	p = &fakePTT{}
+	k = NewKeyer(p, q.len)
+	playErr := errors.New("音频队列已满")
+	if err := k.Send(func() error { return playErr }, 0); !errors.Is(err, playErr) {
+		t.Errorf("Send() error = %v, want %v", err, playErr)
+	}
+	waitFor(t, func() bool { return !k.IsKeyed() })
+}
+
+func TestKeyerWatchdog(t *testing.T) {
+	p := &fakePTT{}
+	q := &fakeQueue{}
+	k := NewKeyer(p, q.len)
+	k.SetLimits(Limits{MaxTxTime: 50 * time.Millisecond}, q.drain)
+
+	// 音频一直未播放完，超过最长发射时间后清空队列并松开PTT
+	if err := k.Send(q.push, 0); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	waitFor(t, func() bool { return !k.IsKeyed() })
+	if q.len() != 0 {
+		t.Error("发射超时后应清空音频队列")
+	}
+	if got := p.getEvents(); len(got) != 2 || got[1] != "unkey" {
+		t.Errorf("PTT操作 = %v, want [key unkey]", got)
+	}
+	if stats := k.GetStats(); stats.WatchdogTrips != 1 {
+		t.Errorf("WatchdogTrips = %d, want 1", stats.WatchdogTrips)
+	}
+
+	// 正常长度的发射不触发
+	if err := k.Send(q.push, 0); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	q.drain()
+	waitFor(t, func() bool { return !k.IsKeyed() })
+	if stats := k.GetStats(); stats.WatchdogTrips != 1 {
+		t.Errorf("WatchdogTrips = %d, want 1", stats.WatchdogTrips)
+	}
+}
+
+func TestKeyerDutyCycle(t *testing.T) {
+	// 不控制PTT (VOX) 时同样统计发射时间
+	q := &fakeQueue{}
+	k := NewKeyer(nil, q.len)
+	k.SetLimits(Limits{DutyCycle: 25, DutyWindow: 400 * time.Millisecond}, nil)
+
+	if k.DutyCycleExceeded() {
+		t.Fatal("未发射时不应超过占空比")
+	}
+
+	// 发射120ms，超过窗口400ms的25%
+	if err := k.Send(q.push, 0); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	time.Sleep(120 * time.Millisecond)
+	q.drain()
+	waitFor(t, func() bool { return !k.IsKeyed() })
+	if !k.DutyCycleExceeded() {
+		t.Errorf("发射时间超过上限后应暂缓发射: %+v", k.GetStats())
+	}
+	if stats := k.GetStats(); stats.DutyCycle < 25 {
+		t.Errorf("DutyCycle = %.1f%%, want >= 25%%", stats.DutyCycle)
+	}
+
+	// 发射移出窗口后恢复
+	waitFor(t, func() bool { return !k.DutyCycleExceeded() })
+	if err := k.Close(); err != nil {
+		t.Errorf("关闭失败: %v", err)
+	}
+}
+
+func TestAirtimeLog(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return base.Add(time.Duration(s) * time.Second) }
+
+	var a airtimeLog
+	a.add(at(0), at(10))
+	a.add(at(20), at(25))
+	a.add(at(30), at(30)) // 长度为0的发射不记录
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		keyedAt time.Time
+		want    time.Duration
+	}{
+		{"窗口包含全部发射", at(40), time.Time{}, 15 * time.Second},
+		{"窗口截断第一次发射", at(65), time.Time{}, 10 * time.Second},
+		{"计入正在进行的发射", at(65), at(60), 15 * time.Second},
+		{"第一次发射移出窗口", at(75), time.Time{}, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.total(tt.now, time.Minute, tt.keyedAt); got != tt.want {
+				t.Errorf("total() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if len(a.spans) != 1 {
+		t.Errorf("窗口之前的记录应被丢弃，剩余 %d 条", len(a.spans))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantNil bool
+		wantErr bool
+	}{
+		{"未配置", Config{}, true, false},
+		{"VOX", Config{Method: "NONE"}, true, false},
+		{"串口未配置设备", Config{Method: MethodSerial}, true, true},
+		{"串口设备不存在", Config{Method: MethodSerial, Device: "/nonexistent/ttyUSB0"}, true, true},
+		{"rigctld连接失败", Config{Method: MethodRig, Device: "127.0.0.1:1"}, true, true},
+		{"不支持的方式", Config{Method: "magic"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Open(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (p == nil) != tt.wantNil {
+				t.Errorf("Open() = %v, wantNil %v", p, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+//go:build linux
+
+package ptt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setModemBits 置位或清除串口的调制解调器控制线，测试时可替换
+// pty没有控制线，不支持TIOCMBIS/TIOCMBIC
+var setModemBits = func(fd int, bits int, on bool) error {
+	req := uint(unix.TIOCMBIC)
+	if on {
+		req = unix.TIOCMBIS
+	}
+	return unix.IoctlSetPointerInt(fd, req, bits)
+}
+
+// Serial 串口PTT，通过RTS或DTR控制线按下电台PTT
+type Serial struct {
+	mu     sync.Mutex
+	file   *os.File
+	bit    int
+	invert bool
+	keyed  bool
+}
+
+// OpenSerial 打开串口PTT，line为rts或dtr (为空时使用rts)
+// 打开串口时驱动通常会拉高RTS和DTR，打开后立即将控制线设为不发射的状态
+func OpenSerial(device string, line string, invert bool) (*Serial, error) {
+	var bit int
+	switch strings.ToLower(line) {
+	case "", LineRTS:
+		bit = unix.TIOCM_RTS
+	case LineDTR:
+		bit = unix.TIOCM_DTR
+	default:
+		return nil, fmt.Errorf("无效的串口控制线: %s", line)
+	}
+
+	file, err := os.OpenFile(device, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开PTT串口失败: %w", err)
+	}
+
+	s := &Serial{file: file, bit: bit, invert: invert}
+	if err := s.set(false); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Key 按下PTT
+func (s *Serial) Key() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(true)
+}
+
+// Unkey 松开PTT
+func (s *Serial) Unkey() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(false)
+}
+
+// IsKeyed PTT是否已按下
+func (s *Serial) IsKeyed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keyed
+}
+
+// Close 松开PTT并关闭串口
+func (s *Serial) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.set(false)
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	return err
+}
+
+// set 设置控制线，调用时需持有s.mu
+func (s *Serial) set(keyed bool) error {
+	if s.file == nil {
+		return fmt.Errorf("PTT串口已关闭")
+	}
+
+	conn, err := s.file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ioctlErr error
+	err = conn.Control(func(fd uintptr) {
+		ioctlErr = setModemBits(int(fd), s.bit, keyed != s.invert)
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		return fmt.Errorf("设置串口控制线失败: %w", err)
+	}
+
+	s.keyed = keyed
+	return nil
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package ptt
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTestPty 创建pty对，返回从设备路径
+func openTestPty(t *testing.T) string {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("无法打开/dev/ptmx: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	if err := unix.IoctlSetPointerInt(int(master.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatalf("解锁pty失败: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(master.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		t.Fatalf("获取pty编号失败: %v", err)
+	}
+	return fmt.Sprintf("/dev/pts/%d", n)
+}
+
+// fakeModemBits 记录串口控制线状态，pty本身没有控制线
+type fakeModemBits struct {
+	mu    sync.Mutex
+	lines map[int]bool
+}
+
+func (f *fakeModemBits) set(fd int, bits int, on bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lines[bits] = on
+	return nil
+}
+
+func (f *fakeModemBits) get(bits int) (bool, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	on, ok := f.lines[bits]
+	return on, ok
+}
+
+func TestSerial(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		invert bool
+		bit    int
+	}{
+		{"RTS", "", false, unix.TIOCM_RTS},
+		{"DTR", "DTR", false, unix.TIOCM_DTR},
+		{"反转RTS", "rts", true, unix.TIOCM_RTS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeModemBits{lines: make(map[int]bool)}
+			saved := setModemBits
+			setModemBits = fake.set
+			defer func() { setModemBits = saved }()
+
+			s, err := OpenSerial(openTestPty(t), tt.line, tt.invert)
+			if err != nil {
+				t.Fatalf("打开串口PTT失败: %v", err)
+			}
+
+			check := func(keyed bool) {
+				t.Helper()
+				on, ok := fake.get(tt.bit)
+				if !ok || on != (keyed != tt.invert) {
+					t.Errorf("控制线状态 = %v (已设置 %v), 期望PTT按下 = %v", on, ok, keyed)
+				}
+				if s.IsKeyed() != keyed {
+					t.Errorf("IsKeyed() = %v, want %v", s.IsKeyed(), keyed)
+				}
+			}
+
+			// 打开后处于不发射状态
+			check(false)
+			if err := s.Key(); err != nil {
+				t.Fatalf("按下PTT失败: %v", err)
+			}
+			check(true)
+			if err := s.Unkey(); err != nil {
+				t.Fatalf("松开PTT失败: %v", err)
+			}
+			check(false)
+
+			// 关闭时松开PTT
+			s.Key()
+			if err := s.Close(); err != nil {
+				t.Fatalf("关闭失败: %v", err)
+			}
+			check(false)
+			if err := s.Key(); err == nil {
+				t.Error("关闭后按下PTT应失败")
+			}
+		})
+	}
+
+	if _, err := OpenSerial(openTestPty(t), "cts", false); err == nil {
+		t.Error("无效的控制线应打开失败")
+	}
+}
+
+func TestSerialIoctl(t *testing.T) {
+	// pty没有控制线，设置控制线的ioctl错误应返回给调用者
+	if _, err := OpenSerial(openTestPty(t), LineRTS, false); err == nil {
+		t.Error("pty不支持控制线，打开串口PTT应失败")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package ptt
+
+import "fmt"
+
+// Serial 串口PTT，仅在Linux上可用
+type Serial struct{}
+
+// OpenSerial 打开串口PTT
+func OpenSerial(device string, line string, invert bool) (*Serial, error) {
+	return nil, fmt.Errorf("串口PTT仅在Linux系统上可用")
+}
+
+// Key 按下PTT
+func (s *Serial) Key() error {
+	return fmt.Errorf("串口PTT仅在Linux系统上可用")
+}
+
+// Unkey 松开PTT
+func (s *Serial) Unkey() error {
+	return nil
+}
+
+// IsKeyed PTT是否已按下
+func (s *Serial) IsKeyed() bool {
+	return false
+}
+
+// Close 关闭串口
+func (s *Serial) Close() error {
+	return nil
+}
@@ -0,0 +1,282 @@
+package rig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRigctld 模拟rigctld，按协议应答命令并记录电台状态
+type fakeRigctld struct {
+	t        *testing.T
+	ln       net.Listener
+	mu       sync.Mutex
+	ptt      bool
+	freq     int64
+	mode     string
+	strength int
+	noMeter  bool     // 电台不支持S表
+	commands []string // 收到的命令
+	conns    []net.Conn
+}
+
+func newFakeRigctld(t *testing.T) *fakeRigctld {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	f := &fakeRigctld{t: t, ln: ln, freq: 144390000, mode: "FM", strength: -20}
+	t.Cleanup(f.close)
+	go f.serve()
+	return f
+}
+
+func (f *fakeRigctld) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeRigctld) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.mu.Unlock()
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRigctld) handle(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		cmd := strings.TrimSpace(scanner.Text())
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		var reply string
+		switch {
+		case cmd == "T 1":
+			f.ptt = true
+			reply = "RPRT 0\n"
+		case cmd == "T 0":
+			f.ptt = false
+			reply = "RPRT 0\n"
+		case cmd == "f":
+			reply = fmt.Sprintf("%d\n", f.freq)
+		case strings.HasPrefix(cmd, "F "):
+			fmt.Sscanf(cmd[2:], "%d", &f.freq)
+			reply = "RPRT 0\n"
+		case cmd == "m":
+			reply = f.mode + "\n15000\n"
+		case cmd == "l STRENGTH":
+			if f.noMeter {
+				reply = "RPRT -11\n"
+			} else {
+				reply = fmt.Sprintf("%d\n", f.strength)
+			}
+		default:
+			reply = "RPRT -4\n"
+		}
+		f.mu.Unlock()
+		conn.Write([]byte(reply))
+	}
+}
+
+// dropConnections 断开所有客户端连接
+func (f *fakeRigctld) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeRigctld) close() {
+	f.ln.Close()
+	f.dropConnections()
+}
+
+func (f *fakeRigctld) isKeyed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ptt
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeRigctld(t)
+	c, err := Dial(server.addr(), -1)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Key(); err != nil {
+		t.Fatalf("按下PTT失败: %v", err)
+	}
+	if !server.isKeyed() {
+		t.Error("按下后电台应处于发射状态")
+	}
+	if err := c.Unkey(); err != nil {
+		t.Fatalf("松开PTT失败: %v", err)
+	}
+	if server.isKeyed() {
+		t.Error("松开后电台应处于接收状态")
+	}
+
+	if freq, err := c.Frequency(); err != nil || freq != 144390000 {
+		t.Errorf("Frequency() = %d, %v, want 144390000", freq, err)
+	}
+	if err := c.SetFrequency(144640000); err != nil {
+		t.Fatalf("设置频率失败: %v", err)
+	}
+	if freq, err := c.Frequency(); err != nil || freq != 144640000 {
+		t.Errorf("设置后Frequency() = %d, %v, want 144640000", freq, err)
+	}
+
+	if mode, passband, err := c.Mode(); err != nil || mode != "FM" || passband != 15000 {
+		t.Errorf("Mode() = %q, %d, %v, want FM, 15000", mode, passband, err)
+	}
+	if strength, err := c.Strength(); err != nil || strength != -20 {
+		t.Errorf("Strength() = %d, %v, want -20", strength, err)
+	}
+
+	// 电台返回的错误码不影响连接
+	server.mu.Lock()
+	server.noMeter = true
+	server.mu.Unlock()
+	_, err = c.Strength()
+	var rprt *rprtError
+	if !errors.As(err, &rprt) || rprt.code != -11 {
+		t.Errorf("Strength() error = %v, want RPRT -11", err)
+	}
+	if _, err := c.Frequency(); err != nil {
+		t.Errorf("错误码之后的命令失败: %v", err)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	server := newFakeRigctld(t)
+	c, err := Dial(server.addr(), -1)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	// 连接断开后命令失败，后台自动重连
+	server.dropConnections()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := c.Key()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("重连后按下PTT失败: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !server.isKeyed() {
+		t.Error("重连后电台应处于发射状态")
+	}
+
+	// 关闭时松开PTT
+	if err := c.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	if server.isKeyed() {
+		t.Error("关闭后电台应处于接收状态")
+	}
+	if err := c.Key(); err == nil {
+		t.Error("关闭后按下PTT应失败")
+	}
+}
+
+func TestClientDisconnected(t *testing.T) {
+	server := newFakeRigctld(t)
+	c, err := Dial(server.addr(), -1)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	// rigctld退出后命令立即失败，不等待重连
+	server.close()
+	c.Key()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := c.Key(); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("断开后Key() error = %v, want ErrNotConnected", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("断开后的命令耗时 %v，应立即返回", elapsed)
+	}
+
+	// 重连期间关闭不阻塞
+	start = time.Now()
+	c.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("关闭耗时 %v", elapsed)
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	server := newFakeRigctld(t)
+	c, err := Dial(server.addr(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer c.Close()
+
+	// 连接时立即读取一次状态
+	status := c.Status()
+	if status.Frequency != 144390000 || status.Mode != "FM" || !status.HasStrength || status.Strength != -20 || status.Updated.IsZero() {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	// 轮询更新状态
+	server.mu.Lock()
+	server.strength = -6
+	server.noMeter = false
+	server.mu.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Status().Strength != -6 {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待S表更新超时: %+v", c.Status())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 不支持S表时标记为无效
+	server.mu.Lock()
+	server.noMeter = true
+	server.mu.Unlock()
+	for c.Status().HasStrength {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待S表失效超时: %+v", c.Status())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDialError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	if _, err := Dial(addr, -1); err == nil {
+		t.Error("连接未监听的端口应失败")
+	}
+}
@@ -0,0 +1,364 @@
+// Package rig 通过Hamlib rigctld控制电台
+// 使用rigctld的TCP文本协议按下PTT、读写频率和模式、读取S表
+package rig
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddress rigctld的默认地址
+const DefaultAddress = "localhost:4532"
+
+// 连接参数默认值
+const (
+	DefaultPollInterval = time.Second // 读取频率、模式和S表的间隔
+	dialTimeout         = 5 * time.Second
+	commandTimeout      = 2 * time.Second
+	reconnectMinDelay   = 500 * time.Millisecond // 重连失败后的首次重试间隔，之后每次加倍
+	reconnectMaxDelay   = 30 * time.Second
+)
+
+// ErrNotConnected 与rigctld的连接已断开，后台正在重连
+var ErrNotConnected = errors.New("rigctld未连接")
+
+// Status 最近一次读取的电台状态
+type Status struct {
+	Frequency   int64     // 频率 (Hz)
+	Mode        string    // 模式，如 FM
+	Strength    int       // S表读数，相对S9的dB (S9为0，S0约为-54)
+	HasStrength bool      // 电台是否支持读取S表
+	Updated     time.Time // 读取时间，为零表示尚未读取成功
+}
+
+// Client rigctld客户端
+// 命令和应答按行交替进行；连接断开后由后台协程按指数退避重连，
+// 断开期间的命令立即返回ErrNotConnected，不会阻塞PTT
+type Client struct {
+	addr string
+
+	mu     sync.Mutex // 保护连接，命令之间互斥
+	conn   net.Conn
+	reader *bufio.Reader
+	closed bool
+
+	statusMu sync.RWMutex
+	status   Status
+	pollErr  bool // 上次轮询是否失败，用于避免重复记录日志
+
+	reconnect chan struct{} // 连接断开时通知重连协程
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// Dial 连接rigctld并开始轮询电台状态，addr为空时使用DefaultAddress
+// interval为轮询间隔，为0时使用DefaultPollInterval，小于0时不轮询
+func Dial(addr string, interval time.Duration) (*Client, error) {
+	if addr == "" {
+		addr = DefaultAddress
+	}
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{addr: addr, reconnect: make(chan struct{}, 1), ctx: ctx, cancel: cancel}
+	conn, err := c.dial()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	c.wg.Add(1)
+	go c.reconnectLoop()
+	if interval > 0 {
+		c.poll()
+		c.wg.Add(1)
+		go c.pollLoop(interval)
+	}
+	return c, nil
+}
+
+// Key 按下PTT (T 1)
+func (c *Client) Key() error {
+	_, err := c.command("T 1", 0)
+	return err
+}
+
+// Unkey 松开PTT (T 0)
+func (c *Client) Unkey() error {
+	_, err := c.command("T 0", 0)
+	return err
+}
+
+// Frequency 读取频率 (f)，单位Hz
+func (c *Client) Frequency() (int64, error) {
+	lines, err := c.command("f", 1)
+	if err != nil {
+		return 0, err
+	}
+	freq, err := strconv.ParseFloat(lines[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的频率: %q", lines[0])
+	}
+	return int64(freq), nil
+}
+
+// SetFrequency 设置频率 (F)，单位Hz
+func (c *Client) SetFrequency(freq int64) error {
+	_, err := c.command(fmt.Sprintf("F %d", freq), 0)
+	return err
+}
+
+// Mode 读取模式和通带宽度 (m)
+func (c *Client) Mode() (string, int, error) {
+	lines, err := c.command("m", 2)
+	if err != nil {
+		return "", 0, err
+	}
+	passband, err := strconv.Atoi(lines[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("无效的通带宽度: %q", lines[1])
+	}
+	return lines[0], passband, nil
+}
+
+// Strength 读取S表 (l STRENGTH)，相对S9的dB
+func (c *Client) Strength() (int, error) {
+	lines, err := c.command("l STRENGTH", 1)
+	if err != nil {
+		return 0, err
+	}
+	strength, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return 0, fmt.Errorf("无效的S表读数: %q", lines[0])
+	}
+	return strength, nil
+}
+
+// Status 获取最近一次轮询的电台状态
+func (c *Client) Status() Status {
+	c.statusMu.RLock()
+	defer c.statusMu.RUnlock()
+	return c.status
+}
+
+// Close 松开PTT，停止轮询并断开连接
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.Unkey()
+
+		c.cancel()
+		c.wg.Wait()
+
+		c.mu.Lock()
+		c.closed = true
+		c.disconnectLocked()
+		c.mu.Unlock()
+	})
+	return err
+}
+
+// command 发送一条命令并读取应答
+// 设置类命令 (want为0) 的应答为 "RPRT n"；读取类命令返回want行数据，出错时只有 "RPRT n"
+func (c *Client) command(cmd string, want int) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, fmt.Errorf("rigctld连接已关闭")
+	}
+	if c.conn == nil {
+		return nil, fmt.Errorf("rigctld命令 %q 失败: %w", cmd, ErrNotConnected)
+	}
+
+	lines, err := c.exchangeLocked(cmd, want)
+	if err != nil {
+		var rprt *rprtError
+		if !errors.As(err, &rprt) {
+			// 连接出错，断开后由重连协程重连
+			c.disconnectLocked()
+		}
+		return nil, fmt.Errorf("rigctld命令 %q 失败: %w", cmd, err)
+	}
+	return lines, nil
+}
+
+// exchangeLocked 发送命令并读取应答，调用时需持有c.mu
+func (c *Client) exchangeLocked(cmd string, want int) ([]string, error) {
+	c.conn.SetDeadline(time.Now().Add(commandTimeout))
+	if _, err := c.conn.Write([]byte(cmd + "\n")); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for len(lines) < want || want == 0 {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+
+		if code, ok := parseRPRT(line); ok {
+			if code != 0 {
+				return nil, &rprtError{code: code}
+			}
+			if want == 0 {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("应答缺少数据")
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// dial 连接rigctld，不持有c.mu，关闭客户端时取消
+func (c *Client) dial() (net.Conn, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(c.ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接rigctld %s 失败: %w", c.addr, err)
+	}
+	return conn, nil
+}
+
+// disconnectLocked 断开连接并通知重连协程，调用时需持有c.mu
+func (c *Client) disconnectLocked() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.reader = nil
+	}
+	select {
+	case c.reconnect <- struct{}{}:
+	default:
+	}
+}
+
+// reconnectLoop 连接断开后在后台重连rigctld，失败时按指数退避重试
+func (c *Client) reconnectLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.reconnect:
+		}
+
+		delay := reconnectMinDelay
+		for failed := false; ; failed = true {
+			conn, err := c.dial()
+			if err == nil {
+				c.mu.Lock()
+				if c.closed || c.conn != nil {
+					conn.Close()
+				} else {
+					c.conn = conn
+					c.reader = bufio.NewReader(conn)
+				}
+				c.mu.Unlock()
+				log.Printf("[rigctld] 已重新连接: %s", c.addr)
+				break
+			}
+			if c.ctx.Err() != nil {
+				return
+			}
+			if !failed {
+				log.Printf("[rigctld] 重连失败，%v后重试: %v", delay, err)
+			}
+
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+		}
+	}
+}
+
+// pollLoop 定期读取电台状态
+func (c *Client) pollLoop(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.poll()
+		}
+	}
+}
+
+// poll 读取频率、模式和S表，读取模式失败时保持原值
+func (c *Client) poll() {
+	freq, err := c.Frequency()
+	if err != nil {
+		c.statusMu.Lock()
+		if !c.pollErr {
+			log.Printf("[rigctld] 读取电台状态失败: %v", err)
+		}
+		c.pollErr = true
+		c.statusMu.Unlock()
+		return
+	}
+
+	mode, _, modeErr := c.Mode()
+	strength, strengthErr := c.Strength()
+
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	if c.pollErr {
+		log.Printf("[rigctld] 已恢复读取电台状态: %s", c.addr)
+	}
+	c.pollErr = false
+	c.status.Frequency = freq
+	if modeErr == nil {
+		c.status.Mode = mode
+	}
+	c.status.Strength = strength
+	c.status.HasStrength = strengthErr == nil
+	c.status.Updated = time.Now()
+}
+
+// rprtError rigctld返回的错误码
+type rprtError struct {
+	code int
+}
+
+func (e *rprtError) Error() string {
+	return fmt.Sprintf("RPRT %d", e.code)
+}
+
+// parseRPRT 解析 "RPRT n" 应答
+func parseRPRT(line string) (int, bool) {
+	if !strings.HasPrefix(line, "RPRT ") {
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(line[5:]))
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}